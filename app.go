@@ -4,40 +4,242 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/aquarelle-tech/darkmatter/crawlers"
+	"github.com/aquarelle-tech/darkmatter/database"
 	"github.com/aquarelle-tech/darkmatter/mapreduce"
 	"github.com/aquarelle-tech/darkmatter/service"
 	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
-// List of available crawlers
-var directory = []types.PriceEvidenceCrawler{
-	crawlers.NewBinanceCrawler(),
-	crawlers.NewLiquidCrawler(),
-	crawlers.NewBitfinexCrawler(),
-}
+// The names of the keys of the available crawlers
+var crawlerNames = []string{"binance", "liquid", "bitfinex"}
 
 var publishedPrices = make(chan types.FullSignedBlock)
 
+// How long the open connections have to finish when the node stops
+const shutdownTimeout = 10 * time.Second
+
+// The maintenance of the database, run instead of the node
+var repair = flag.Bool("repair", false, "repair the indexes of the database and exit")
+var dryRun = flag.Bool("dry-run", false, "with -repair, only report the problems")
+var migrateBadgerV1 = flag.Bool("migrate-badger-v1", false, "migrate a database written by Badger v1 to Badger v2 and exit")
+
+// The hash function of the chain, the same in all their nodes
+var hashName = flag.String("hash", types.HashSHA256, "the hash function of the blocks: sha256, blake2b or sha3")
+
+// The proof of work of the chain, off by default
+var difficulty = flag.Uint("difficulty", 0, "the minimum proof of work of the blocks, in leading zero bits of their hashes")
+
+// The secondary store that mirrors the blocks of the node, off by default
+var replicaBackend = flag.String("replica-backend", "", "the backend of a secondary store that mirrors the blocks, e.g. postgres")
+var replicaLocation = flag.String("replica", "", "with -replica-backend, the location of the secondary store")
+
 func main() {
+	flag.Parse()
+	if err := setHashProvider(*hashName); err != nil {
+		log.Fatal(err)
+	}
+	types.SetMinDifficulty(uint32(*difficulty))
+	if *repair {
+		os.Exit(repairDatabase(*dryRun))
+	}
+	if *migrateBadgerV1 {
+		os.Exit(migrateDatabase())
+	}
+
+	chain, err := mapreduce.NewPublicBlockDatabase()
+	if err != nil {
+		log.Fatal("Can´t open the database ", err)
+	}
+	if *hashName != types.HashSHA256 {
+		chain.SetGenesis(types.GenesisConfig{
+			Chain:  mapreduce.MainBlockChainName,
+			Params: map[string]string{"hash": *hashName},
+		})
+	}
+
+	stopReplication, err := startReplication(chain)
+	if err != nil {
+		log.Fatal("Can´t open the secondary store ", err)
+	}
 
 	quotedCurrency := "USD"
 
 	// Prepare and run the subroutines for the oracle service
-	server := service.NewOracleServer(publishedPrices)
+	server := service.NewOracleServer(chain, publishedPrices)
 	server.Initialize()
 
 	// Prepare and start the subroutines to manage the request of sources
-	processor := mapreduce.NewMapReduceProcessor(directory, quotedCurrency, publishedPrices)
+	directory, err := newCrawlers()
+	if err != nil {
+		log.Fatal("Can´t load the keys of the crawlers ", err)
+	}
+	processor := mapreduce.NewMapReduceProcessor(chain, directory, quotedCurrency, publishedPrices)
 	processor.Initialize()
 
+	// The health of the database, for the load balancers and the monitoring
+	http.HandleFunc("/health", serveHealth(chain))
+
 	// handler := cors.Default().Handler(mux)
-	err := http.ListenAndServe(":8080", nil)
+	httpServer := &http.Server{Addr: ":8080"}
+	go func() {
+		err := httpServer.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal("ListenAndServe: ", err)
+		}
+	}()
+
+	// Wait for the signal to stop, and then close the database cleanly
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Println("The HTTP server didn´t stop cleanly", err)
+	}
+	stopReplication()
+	if err := chain.Close(); err != nil {
+		log.Println("The database didn´t close cleanly", err)
+	}
+}
+
+// Set the hash provider of the blocks. The chains with another provider than the default one have it in the
+// parameters of their genesis block, so they can´t be mixed with the others
+func setHashProvider(name string) error {
+	provider, err := types.HashProviderByName(name)
 	if err != nil {
-		log.Fatal("ListenAndServe: ", err)
+		return err
+	}
+
+	types.SetHashProvider(provider)
+	return nil
+}
+
+// Mirror the blocks of the chain in the secondary store of the flags as they are written. Returns the function that
+// stops the replication and closes the secondary store, which does nothing if no secondary store was set
+func startReplication(chain *database.BlockChain) (func(), error) {
+	if *replicaBackend == "" {
+		return func() {}, nil
 	}
 
+	secondary, err := database.OpenKVStore(*replicaBackend, *replicaLocation)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := database.NewReplicationMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		database.CloseStore(secondary)
+		return nil, err
+	}
+
+	// Each written block runs a pass, and if the store can´t be subscribed to, only the interval is left
+	config := database.ReplicationConfig{}
+	sub, err := chain.Subscribe("")
+	if err != nil {
+		log.Println("The blocks are replicated on each interval, the database can´t be subscribed to", err)
+	} else {
+		config.Notify = sub.Blocks
+	}
+
+	replicator := chain.NewReplicator(secondary, config, metrics)
+	replicator.Start()
+	log.Println("Replicating the blocks to the", *replicaBackend, "store")
+
+	return func() {
+		replicator.Stop()
+		if sub != nil {
+			sub.Close()
+		}
+		if err := database.CloseStore(secondary); err != nil {
+			log.Println("The secondary store didn´t close cleanly", err)
+		}
+	}, nil
+}
+
+// Report if the database can be used
+func serveHealth(chain *database.BlockChain) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := chain.Health(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("OK"))
+	}
+}
+
+// Create the list of available crawlers, each one with their own key pair, so their results are attributed to them
+// and not to the node
+func newCrawlers() ([]types.PriceEvidenceCrawler, error) {
+	identities := make(map[string]*types.NodeIdentity, len(crawlerNames))
+	for _, name := range crawlerNames {
+		identity, err := mapreduce.LoadCollectorIdentity(name)
+		if err != nil {
+			return nil, err
+		}
+		identities[name] = identity
+	}
+
+	return []types.PriceEvidenceCrawler{
+		crawlers.NewBinanceCrawler(identities["binance"]),
+		crawlers.NewLiquidCrawler(identities["liquid"]),
+		crawlers.NewBitfinexCrawler(identities["bitfinex"]),
+	}, nil
+}
+
+// Repair the database and report the issues. Only the store is opened, without the metrics and the identity of the
+// node. Returns the exit code: 1 if the repair failed or left issues unfixed
+func repairDatabase(dryRun bool) int {
+	store, err := database.Open(mapreduce.BlockchainFileLocation, database.StoreConfig{})
+	if err != nil {
+		log.Println("Can´t open the database", err)
+		return 1
+	}
+	defer store.Close()
+
+	report, err := database.RepairStore(store, dryRun)
+	if err != nil {
+		log.Println("The repair of the database failed", err)
+		return 1
+	}
+
+	for _, issue := range report.Issues {
+		log.Printf("%s at the height %d (%s): %s. Repaired: %t", issue.Kind, issue.Height, issue.Hash, issue.Detail, issue.Repaired)
+	}
+	log.Printf("Blocks scanned: %d, issues: %d, repaired: %d", report.Scanned, len(report.Issues), report.Repaired)
+
+	if report.Repaired < len(report.Issues) {
+		return 1
+	}
+	return 0
+}
+
+// Migrate the database from Badger v1, keeping the original directory. Returns the exit code: 1 if the migration
+// failed
+func migrateDatabase() int {
+	migrated, err := database.MigrateBadgerV1(mapreduce.BlockchainFileLocation, database.StoreConfig{})
+	if err != nil {
+		log.Println("The migration of the database failed", err)
+		return 1
+	}
+
+	if migrated {
+		log.Println("The database was migrated to Badger v2")
+	} else {
+		log.Println("The database was not written by Badger v1, nothing to migrate")
+	}
+	return 0
 }
@@ -4,7 +4,9 @@
 package crawlers
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"log"
 	"strconv"
 	"time"
 
@@ -22,9 +24,10 @@ type BinanceCrawler struct {
 	Ticker      string
 }
 
-// Creates a new crawler
-func NewBinanceCrawler() BinanceCrawler {
+// Creates a new crawler, signing their results with the key of the identity
+func NewBinanceCrawler(identity *types.NodeIdentity) BinanceCrawler {
 	crawler := NewCrawler(BINANCE_APIURL)
+	crawler.Identity = identity
 
 	return BinanceCrawler{
 		DataCrawler: crawler,
@@ -40,6 +43,11 @@ func (c BinanceCrawler) GetTicker() string {
 	return c.Ticker
 }
 
+// Return the public key of the crawler, to verify their results
+func (c BinanceCrawler) GetPublicKey() ed25519.PublicKey {
+	return c.DataCrawler.PublicKey()
+}
+
 // Serializes a json to a TickerInfo24 type
 func (c BinanceCrawler) ToQuotePriceInfo(jsonData []byte) types.QuotePriceInfo {
 
@@ -74,7 +82,7 @@ func (c BinanceCrawler) SetTicker(quotedCurrency string) {
 }
 
 // Helper function to convert the json from Binance´s API to a QuotePriceInfo instance
-func (c BinanceCrawler) Crawl(quotedCurrency string, done chan types.QuotePriceInfo) {
+func (c BinanceCrawler) Crawl(quotedCurrency string, done chan types.Result) {
 
 	c.SetTicker(quotedCurrency)
 	jsonData, err := c.DataCrawler.Get()
@@ -85,5 +93,11 @@ func (c BinanceCrawler) Crawl(quotedCurrency string, done chan types.QuotePriceI
 	priceInfo := c.ToQuotePriceInfo(jsonData)
 	priceInfo.Timestamp = time.Now().Unix()
 	priceInfo.DataURL = BINANCE_APIURL
-	done <- priceInfo
+
+	result, err := c.DataCrawler.NewResult(c.GetName(), c.GetTicker(), priceInfo)
+	if err != nil {
+		log.Println("Can´t sign the result of", c.GetName(), err)
+		return
+	}
+	done <- result
 }
@@ -4,7 +4,9 @@
 package crawlers
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"log"
 	"reflect"
 	"time"
 
@@ -22,9 +24,10 @@ type BitfinexCrawler struct {
 	Ticker      string
 }
 
-// Creates a new crawler
-func NewBitfinexCrawler() BitfinexCrawler {
+// Creates a new crawler, signing their results with the key of the identity
+func NewBitfinexCrawler(identity *types.NodeIdentity) BitfinexCrawler {
 	crawler := NewCrawler(BITFINEX_APIURL)
+	crawler.Identity = identity
 
 	return BitfinexCrawler{
 		DataCrawler: crawler,
@@ -40,6 +43,11 @@ func (c BitfinexCrawler) GetTicker() string {
 	return c.Ticker
 }
 
+// Return the public key of the crawler, to verify their results
+func (c BitfinexCrawler) GetPublicKey() ed25519.PublicKey {
+	return c.DataCrawler.PublicKey()
+}
+
 // Serializes a json to a TickerInfo24 type
 func (c BitfinexCrawler) ToQuotePriceInfo(jsonData []byte) types.QuotePriceInfo {
 
@@ -74,7 +82,7 @@ func (c BitfinexCrawler) SetTicker(quotedCurrency string) {
 }
 
 // Helper function to convert the json from Bitfinex´s API to a QuotePriceInfo instance
-func (c BitfinexCrawler) Crawl(quotedCurrency string, done chan types.QuotePriceInfo) {
+func (c BitfinexCrawler) Crawl(quotedCurrency string, done chan types.Result) {
 
 	c.SetTicker(quotedCurrency)
 	jsonData, err := c.DataCrawler.Get()
@@ -86,5 +94,11 @@ func (c BitfinexCrawler) Crawl(quotedCurrency string, done chan types.QuotePrice
 	priceInfo := c.ToQuotePriceInfo(jsonData)
 	priceInfo.Timestamp = time.Now().Unix()
 	priceInfo.DataURL = BITFINEX_APIURL
-	done <- priceInfo
+
+	result, err := c.DataCrawler.NewResult(c.GetName(), c.GetTicker(), priceInfo)
+	if err != nil {
+		log.Println("Can´t sign the result of", c.GetName(), err)
+		return
+	}
+	done <- result
 }
@@ -4,13 +4,24 @@
 package crawlers
 
 import (
+	"crypto/ed25519"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
 )
 
+// MaxResponseSize is the maximum number of bytes read from a source, the rest of the response is dropped
+const MaxResponseSize = 1 << 20
+
 type Crawler struct {
 	Url     string
 	Headers map[string]string
+	// The key pair of the crawler, apart from the one of the node, so the results are attributed to the crawler
+	Identity *types.NodeIdentity
 }
 
 // Create a new Crawler
@@ -20,6 +31,34 @@ func NewCrawler(url string) Crawler {
 	}
 }
 
+// NewResult creates the result of the data collected by the crawler, hashed and signed with their key
+func (crawler Crawler) NewResult(name string, ticker string, data types.QuotePriceInfo) (types.Result, error) {
+	result := types.Result{
+		Data:        data,
+		Ticker:      ticker,
+		HasError:    false,
+		Timestamp:   time.Now().Unix(),
+		CrawlerName: name,
+	}
+	if crawler.Identity == nil {
+		return result, errors.New("crawlers: the crawler has no identity to sign their results")
+	}
+	if err := result.CreateHash(); err != nil {
+		return result, err
+	}
+
+	return result, result.Sign(crawler.Identity.PrivateKey)
+}
+
+// PublicKey returns the public key of the crawler, or nil if it has no identity
+func (crawler Crawler) PublicKey() ed25519.PublicKey {
+	if crawler.Identity == nil {
+		return nil
+	}
+
+	return crawler.Identity.PublicKey()
+}
+
 // Return the data. For now, it is just a GET
 func (crawler Crawler) Get() ([]byte, error) {
 
@@ -45,7 +84,9 @@ func (crawler Crawler) Get() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	} else {
-		data, _ := ioutil.ReadAll(response.Body)
+		defer response.Body.Close()
+
+		data, _ := ioutil.ReadAll(io.LimitReader(response.Body, MaxResponseSize))
 		return data, nil
 	}
 }
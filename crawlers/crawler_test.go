@@ -0,0 +1,43 @@
+package crawlers
+
+import (
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// The results are signed with the key of the crawler, so they verify against their public key
+func TestCrawlerNewResult(t *testing.T) {
+	identity, err := types.NewNodeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		identity *types.NodeIdentity
+		signed   bool
+	}{
+		{"with an identity", identity, true},
+		{"without identity", nil, false},
+	}
+	for _, test := range tests {
+		crawler := NewCrawler(BINANCE_APIURL)
+		crawler.Identity = test.identity
+
+		result, err := crawler.NewResult(BINANCE_MODULE_NAME, "BTCUSD", types.QuotePriceInfo{HighPrice: 10000})
+		if (err == nil) != test.signed {
+			t.Errorf("%s: the result was created with %v", test.name, err)
+			continue
+		}
+		if !test.signed {
+			continue
+		}
+		if err = result.VerifySignature(crawler.PublicKey()); err != nil {
+			t.Errorf("%s: the signature of the result is not the one of the crawler: %v", test.name, err)
+		}
+		if signer, err := result.Signer(); err != nil || signer != identity.Address() {
+			t.Errorf("%s: the result is signed by %s (%v), expected %s", test.name, signer, err, identity.Address())
+		}
+	}
+}
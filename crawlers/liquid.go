@@ -4,7 +4,9 @@
 package crawlers
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"log"
 	"strconv"
 	"time"
 
@@ -22,9 +24,10 @@ type LiquidCrawler struct {
 	Ticker      string
 }
 
-// Creates a new crawler
-func NewLiquidCrawler() LiquidCrawler {
+// Creates a new crawler, signing their results with the key of the identity
+func NewLiquidCrawler(identity *types.NodeIdentity) LiquidCrawler {
 	crawler := NewCrawler(LIQUID_APIURL)
+	crawler.Identity = identity
 	crawler.Headers = make(map[string]string)
 	crawler.Headers["X-Quoine-API-Version"] = "2"
 
@@ -42,6 +45,11 @@ func (c LiquidCrawler) GetTicker() string {
 	return c.Ticker
 }
 
+// Return the public key of the crawler, to verify their results
+func (c LiquidCrawler) GetPublicKey() ed25519.PublicKey {
+	return c.DataCrawler.PublicKey()
+}
+
 // Serializes a json to a TickerInfo24 type
 func (c LiquidCrawler) ToQuotePriceInfo(jsonData []byte) types.QuotePriceInfo {
 
@@ -65,7 +73,7 @@ func (c LiquidCrawler) ToQuotePriceInfo(jsonData []byte) types.QuotePriceInfo {
 }
 
 // Helper function to convert the json from Liquid´s API to a QuotePriceInfo instance
-func (c LiquidCrawler) Crawl(quotedCurrency string, done chan types.QuotePriceInfo) {
+func (c LiquidCrawler) Crawl(quotedCurrency string, done chan types.Result) {
 
 	jsonData, err := c.DataCrawler.Get()
 	if err != nil {
@@ -75,5 +83,11 @@ func (c LiquidCrawler) Crawl(quotedCurrency string, done chan types.QuotePriceIn
 	priceInfo := c.ToQuotePriceInfo(jsonData)
 	priceInfo.Timestamp = time.Now().Unix()
 	priceInfo.DataURL = LIQUID_APIURL
-	done <- priceInfo
+
+	result, err := c.DataCrawler.NewResult(c.GetName(), c.GetTicker(), priceInfo)
+	if err != nil {
+		log.Println("Can´t sign the result of", c.GetName(), err)
+		return
+	}
+	done <- result
 }
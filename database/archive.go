@@ -0,0 +1,210 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// ArchivedBlockKeyPrefix starts the keys of the values that point from the hash of an archived block to the
+	// object that holds it
+	ArchivedBlockKeyPrefix = "archived-block-"
+
+	// How long a request to the object storage can take, if not set in the config
+	defaultS3Timeout = 30 * time.Second
+)
+
+// ErrNotArchived is returned when the block pointed by the index is not in the archived object
+var ErrNotArchived = errors.New("database: the block is not in the archive")
+
+// ObjectStorage keeps the archived batches of blocks, as objects identified by a key
+type ObjectStorage interface {
+	PutObject(key string, data []byte) error
+	GetObject(key string) ([]byte, error)
+}
+
+// S3Config sets the connection to an S3-compatible storage (AWS S3, MinIO, Ceph...)
+type S3Config struct {
+	// Endpoint is the host and port of the service, without the scheme (e.g. s3.amazonaws.com)
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	// UseSSL connects with HTTPS
+	UseSSL bool
+	// Bucket is where the objects are written. It must exist
+	Bucket string
+	// Prefix is prepended to the keys of the objects, to share the bucket
+	Prefix string
+	// Timeout is how long a request can take
+	Timeout time.Duration
+}
+
+// S3Storage implements ObjectStorage over an S3-compatible bucket
+type S3Storage struct {
+	client *minio.Client
+	config S3Config
+}
+
+// NewS3Storage creates the client for the bucket. The zero values of the config are replaced by the defaults
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultS3Timeout
+	}
+
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Storage{client: client, config: config}, nil
+}
+
+// PutObject uploads an object
+func (s *S3Storage) PutObject(key string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	_, err := s.client.PutObject(ctx, s.config.Bucket, s.config.Prefix+key, bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+
+	return err
+}
+
+// GetObject downloads an object
+func (s *S3Storage) GetObject(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	object, err := s.client.GetObject(ctx, s.config.Bucket, s.config.Prefix+key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return ioutil.ReadAll(object)
+}
+
+// Archiver uploads the blocks to be pruned to an object storage, and reads them back once their body is removed
+// from the store. Each batch is an object, and the store keeps a value per block pointing to their object
+type Archiver struct {
+	store   types.KVStore
+	storage ObjectStorage
+}
+
+// NewArchiver creates the archiver for the blocks of the store. Archive is the archive function for the pruner
+func NewArchiver(store types.KVStore, storage ObjectStorage) *Archiver {
+	return &Archiver{store: store, storage: storage}
+}
+
+// The key of an object, by the heights of the first and the last block. The heights are padded, so the objects
+// sort by height
+func archiveObjectKey(blocks []types.FullSignedBlock) string {
+	return fmt.Sprintf("blocks-%020d-%020d", blocks[0].Height, blocks[len(blocks)-1].Height)
+}
+
+// Serialize a batch in the same way than the stored blocks, in MessagePack and compressed with zstd
+func encodeArchive(blocks []types.FullSignedBlock) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+
+	if err := encoder.Encode(blocks); err != nil {
+		return nil, err
+	}
+
+	return zstdEncoder.EncodeAll(buffer.Bytes(), nil), nil
+}
+
+// Deserialize an archived batch
+func decodeArchive(data []byte) ([]types.FullSignedBlock, error) {
+	data, err := zstdDecoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []types.FullSignedBlock
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if err = decoder.Decode(&blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// Archive uploads the blocks as a single object and then writes the pointers to it. If it fails, the pruner keeps
+// the blocks, and the next run uploads them again
+func (a *Archiver) Archive(blocks []types.FullSignedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	data, err := encodeArchive(blocks)
+	if err != nil {
+		return err
+	}
+
+	key := archiveObjectKey(blocks)
+	if err = a.storage.PutObject(key, data); err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		if err = a.store.StoreValue(ArchivedBlockKeyPrefix+block.Hash, []byte(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetArchivedBlock reads a block using their hash. If the body of the block was pruned, the full block is
+// downloaded from the archive, so the callers don´t need to know where it is
+func (a *Archiver) GetArchivedBlock(hash string) (*types.FullSignedBlock, error) {
+	block, err := a.store.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	if block.Evidence != nil {
+		return block, nil // Not pruned
+	}
+
+	key, err := a.store.GetValue(ArchivedBlockKeyPrefix + hash)
+	if errors.Is(err, types.ErrNotFound) {
+		return block, nil // Never archived, so nothing else to read
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := a.storage.GetObject(string(key))
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := decodeArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range blocks {
+		if blocks[i].Hash == hash {
+			return &blocks[i], nil
+		}
+	}
+
+	return nil, ErrNotArchived
+}
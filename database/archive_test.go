@@ -0,0 +1,170 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// An object storage in memory, that can fail the uploads
+type memoryObjects struct {
+	objects map[string][]byte
+	failPut error
+}
+
+func newMemoryObjects() *memoryObjects {
+	return &memoryObjects{objects: make(map[string][]byte)}
+}
+
+func (m *memoryObjects) PutObject(key string, data []byte) error {
+	if m.failPut != nil {
+		return m.failPut
+	}
+	m.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *memoryObjects) GetObject(key string) ([]byte, error) {
+	data, exists := m.objects[key]
+	if !exists {
+		return nil, errors.New("the object doesn´t exist")
+	}
+	return data, nil
+}
+
+func (m *memoryObjects) keys() []string {
+	keys := make([]string, 0, len(m.objects))
+	for key := range m.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Prune the chain keeping the newest five blocks, and read all of them back through the archiver. The pruned ones
+// come whole from the archive, the others from the store
+func TestArchivePrunedBlocks(t *testing.T) {
+	store, blocks := testPrunerStore(t)
+	objects := newMemoryObjects()
+	archiver := NewArchiver(store, objects)
+
+	pruned, err := NewPruner(store, RetentionPolicy{KeepBlocks: 5, BatchSize: 2}, archiver.Archive).Prune()
+	if err != nil || pruned != 5 {
+		t.Fatalf("the pruning returned %d (%v)", pruned, err)
+	}
+
+	expected := []string{
+		"blocks-00000000000000000000-00000000000000000001",
+		"blocks-00000000000000000002-00000000000000000003",
+		"blocks-00000000000000000004-00000000000000000004",
+	}
+	if keys := objects.keys(); len(keys) != len(expected) || keys[0] != expected[0] || keys[1] != expected[1] || keys[2] != expected[2] {
+		t.Errorf("the archived objects are %v, expected %v", keys, expected)
+	}
+
+	tests := []struct {
+		height   uint64
+		archived bool
+	}{
+		{0, true},
+		{3, true},
+		{4, true},  // The last pruned block
+		{5, false}, // The first kept block
+		{9, false},
+	}
+	for _, test := range tests {
+		hash := blocks[test.height].Hash
+		if _, err := store.GetValue(ArchivedBlockKeyPrefix + hash); (err == nil) != test.archived {
+			t.Errorf("the pointer of the height %d to the archive returned %v", test.height, err)
+		}
+
+		block, err := archiver.GetArchivedBlock(hash)
+		if err != nil {
+			t.Fatalf("GetArchivedBlock of the height %d: %v", test.height, err)
+		}
+		if block.Hash != hash || len(block.Evidence) != 1 || block.Evidence[0].CrawlerName != "binance" {
+			t.Errorf("GetArchivedBlock of the height %d returned %v", test.height, block)
+		}
+	}
+}
+
+// A failed upload keeps the bodies in the store and writes no pointers, so the next pass archives them again
+func TestArchiveUploadFailed(t *testing.T) {
+	store, blocks := testPrunerStore(t)
+	objects := newMemoryObjects()
+	objects.failPut = errors.New("the bucket is not available")
+	archiver := NewArchiver(store, objects)
+	pruner := NewPruner(store, RetentionPolicy{KeepBlocks: 5}, archiver.Archive)
+
+	if pruned, err := pruner.Prune(); !errors.Is(err, objects.failPut) || pruned != 0 {
+		t.Errorf("the pruning with a failed upload returned %d (%v)", pruned, err)
+	}
+	if _, err := store.GetValue(ArchivedBlockKeyPrefix + blocks[0].Hash); err == nil {
+		t.Error("a block that was not uploaded points to the archive")
+	}
+
+	objects.failPut = nil
+	if pruned, err := pruner.Prune(); err != nil || pruned != 5 {
+		t.Errorf("the pruning after the failed upload returned %d (%v)", pruned, err)
+	}
+	if block, err := archiver.GetArchivedBlock(blocks[0].Hash); err != nil || len(block.Evidence) != 1 {
+		t.Errorf("the block archived on the second pass is %v (%v)", block, err)
+	}
+}
+
+func TestGetArchivedBlockErrors(t *testing.T) {
+	store, blocks := testPrunerStore(t)
+	objects := newMemoryObjects()
+	archiver := NewArchiver(store, objects)
+
+	// A pruned block without a pointer was never archived, so the header is all there is
+	header := blocks[0]
+	header.Evidence = nil
+	other := blocks[1]
+	other.Evidence = nil
+	if err := store.StoreBlocks([]types.FullSignedBlock{header, other}); err != nil {
+		t.Fatal(err)
+	}
+	if block, err := archiver.GetArchivedBlock(header.Hash); err != nil || block.Hash != header.Hash || block.Evidence != nil {
+		t.Errorf("GetArchivedBlock of a block never archived returned %v (%v)", block, err)
+	}
+
+	// The pointer of the other block goes to an object without it
+	if err := archiver.Archive(blocks[2:3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreValue(ArchivedBlockKeyPrefix+other.Hash, []byte(archiveObjectKey(blocks[2:3]))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := archiver.GetArchivedBlock(other.Hash); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("GetArchivedBlock of a block missing in their object returned %v", err)
+	}
+
+	if _, err := archiver.GetArchivedBlock("unknown"); err == nil {
+		t.Error("GetArchivedBlock of an unknown hash didn´t fail")
+	}
+}
+
+func TestArchiveCodec(t *testing.T) {
+	blocks := testChain(t, 3, 1600000000)
+	blocks[1].Evidence = []types.Result{{CrawlerName: "binance", Ticker: "BTCUSD"}}
+
+	data, err := encodeArchive(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeArchive(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalHeights(heightsOf(decoded), []uint64{0, 1, 2}) || decoded[1].Hash != blocks[1].Hash || len(decoded[1].Evidence) != 1 {
+		t.Errorf("the decoded archive is %v", decoded)
+	}
+
+	if _, err = decodeArchive(data[:len(data)/2]); err == nil {
+		t.Error("decoding a truncated archive didn´t fail")
+	}
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+const (
+	// BadgerBackend is the name of the default backend, a Badger database in a directory
+	BadgerBackend = "badger"
+	// MemoryBackend is the name of the backend that keeps all the data in memory. Nothing is written to disk
+	MemoryBackend = "memory"
+)
+
+// BackendFactory creates a KVStore for a location. The meaning of the location depends on each backend
+type BackendFactory func(location string) (types.KVStore, error)
+
+var backendsLock sync.RWMutex
+var backends = make(map[string]BackendFactory)
+
+// RegisterBackend makes a storage backend available by name. Registering the same name twice is an error
+func RegisterBackend(name string, factory BackendFactory) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+
+	if _, exists := backends[name]; exists {
+		panic("database: backend registered twice: " + name)
+	}
+	backends[name] = factory
+}
+
+// Backends returns the names of the registered backends, sorted
+func Backends() []string {
+	backendsLock.RLock()
+	defer backendsLock.RUnlock()
+
+	var names []string
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// OpenKVStore creates a new store for key-value pairs using the named backend
+func OpenKVStore(backend string, location string) (types.KVStore, error) {
+	backendsLock.RLock()
+	factory, exists := backends[backend]
+	backendsLock.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("database: unknown backend %q", backend)
+	}
+
+	return factory(location)
+}
+
+// HealthChecker is implemented by the stores that can report if they are usable
+type HealthChecker interface {
+	// Health returns an error if the store can´t be used
+	Health() error
+}
+
+// CloseStore closes a store if their backend holds any resource, like the open files or the connections
+func CloseStore(store types.KVStore) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// StoreHealth checks the health of a store. The stores that can´t report it are healthy
+func StoreHealth(store types.KVStore) error {
+	if checker, ok := store.(HealthChecker); ok {
+		return checker.Health()
+	}
+
+	return nil
+}
+
+func init() {
+	RegisterBackend(BadgerBackend, func(location string) (types.KVStore, error) {
+		store := &Store{StorFileLocation: location}
+		if err := store.Migrate(); err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	})
+	RegisterBackend(MemoryBackend, func(location string) (types.KVStore, error) {
+		return NewMemoryStore(), nil
+	})
+}
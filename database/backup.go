@@ -0,0 +1,173 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// How many writes can be pending while a backup is restored
+const restoreMaxPendingWrites = 256
+
+// ErrBackupUnsupported is returned by BackupStore and RestoreStore for the backends that can´t be backed up
+var ErrBackupUnsupported = errors.New("database: the backend can´t be backed up")
+
+// Backuper is implemented by the stores that can be backed up and restored
+type Backuper interface {
+	// Backup writes the pairs changed after the version since and returns the version of the next backup
+	Backup(w io.Writer, since uint64) (uint64, error)
+	// Restore loads a backup written by Backup
+	Restore(r io.Reader) error
+}
+
+// BackupStore writes a backup of a store, or returns ErrBackupUnsupported if their backend can´t be backed up
+func BackupStore(store types.KVStore, w io.Writer, since uint64) (uint64, error) {
+	if backuper, ok := store.(Backuper); ok {
+		return backuper.Backup(w, since)
+	}
+
+	return since, ErrBackupUnsupported
+}
+
+// RestoreStore loads a backup in a store, or returns ErrBackupUnsupported if their backend can´t be restored
+func RestoreStore(store types.KVStore, r io.Reader) error {
+	if backuper, ok := store.(Backuper); ok {
+		return backuper.Restore(r)
+	}
+
+	return ErrBackupUnsupported
+}
+
+// Backup writes a consistent snapshot of all the pairs changed after the version since (all of them if zero) and
+// returns the version to use as since in the next backup, so the backups can be incremental
+func (s Store) Backup(w io.Writer, since uint64) (uint64, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.release(stor)
+
+	// Badger returns the last version written, and includes it in a backup since that version
+	last, err := stor.Backup(w, since)
+	if err != nil || last == 0 {
+		return since, err
+	}
+
+	return last + 1, nil
+}
+
+// Restore loads a backup written by Backup. The incremental backups must be restored in the same order they were
+// taken, after the full one
+func (s Store) Restore(r io.Reader) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer s.release(stor)
+
+	if err = s.checkQuota(stor); err != nil {
+		return err
+	}
+
+	return stor.Load(r, restoreMaxPendingWrites)
+}
+
+// BackupScheduler takes a backup of a store on each interval, into files of a directory. The first backup is a
+// full one and the next ones only hold the changes since the previous one
+type BackupScheduler struct {
+	store     Store
+	directory string
+	interval  time.Duration
+	since     uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewBackupScheduler creates the scheduler. The backup files are written in the directory
+func NewBackupScheduler(store Store, directory string, interval time.Duration) *BackupScheduler {
+	return &BackupScheduler{
+		store:     store,
+		directory: directory,
+		interval:  interval,
+	}
+}
+
+// BackupNow takes a backup into a new file and returns their name. The file name holds the time of the backup
+// and the version it starts from, so sorting the names gives the order to restore them
+func (b *BackupScheduler) BackupNow() (string, error) {
+	if err := os.MkdirAll(b.directory, 0700); err != nil {
+		return "", err
+	}
+
+	name := filepath.Join(b.directory, fmt.Sprintf("backup-%d-%020d.bak", time.Now().Unix(), b.since))
+	file, err := os.OpenFile(name+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	// The file only gets their final name once complete, so a partial backup is never restored
+	next, err := b.store.Backup(file, b.since)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(name+".tmp", name)
+	}
+	if err != nil {
+		os.Remove(name + ".tmp")
+		return "", err
+	}
+
+	b.since = next
+	return name, nil
+}
+
+// Start launches the background job that takes the backups
+func (b *BackupScheduler) Start() {
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if name, err := b.BackupNow(); err != nil {
+					log.Println("The scheduled backup failed", err)
+				} else {
+					log.Println("Created a new backup", name)
+				}
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background job, waiting for the current backup to finish
+func (b *BackupScheduler) Stop() {
+	close(b.stop)
+	<-b.done
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	store := Store{StorFileLocation: t.TempDir()}
+	blocks := testChain(t, 5, 1600000000)
+	if err := store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreValue("key", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	var backup bytes.Buffer
+	if _, err := store.Backup(&backup, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := Store{StorFileLocation: t.TempDir()}
+	if err := restored.Restore(&backup); err != nil {
+		t.Fatal(err)
+	}
+	found, err := restored.GetBlocksByHeightRange(0, 10)
+	if err != nil || !equalHeights(heightsOf(found), []uint64{0, 1, 2, 3, 4}) {
+		t.Errorf("the restored store has the heights %v (%v)", heightsOf(found), err)
+	}
+	if value, err := restored.GetValue("key"); err != nil || string(value) != "value" {
+		t.Errorf("the restored value is %q (%v)", value, err)
+	}
+}
+
+// The first backup is a full one and the next ones only hold the changes, restored in the order of their names
+func TestBackupScheduler(t *testing.T) {
+	store := Store{StorFileLocation: t.TempDir()}
+	directory := filepath.Join(t.TempDir(), "backups")
+	scheduler := NewBackupScheduler(store, directory, 0)
+	blocks := testChain(t, 6, 1600000000)
+
+	var sizes []int64
+	for _, part := range [][]int{{0, 4}, {4, 6}} {
+		if err := store.StoreBlocks(blocks[part[0]:part[1]]); err != nil {
+			t.Fatal(err)
+		}
+		name, err := scheduler.BackupNow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, info.Size())
+	}
+	if sizes[1] >= sizes[0] {
+		t.Errorf("the incremental backup has %d bytes, the full one %d", sizes[1], sizes[0])
+	}
+
+	names, err := filepath.Glob(filepath.Join(directory, "*.bak"))
+	if err != nil || len(names) != 2 {
+		t.Fatalf("the backups are %v (%v)", names, err)
+	}
+	sort.Strings(names)
+
+	restored := Store{StorFileLocation: t.TempDir()}
+	for _, name := range names {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = restored.Restore(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found, err := restored.GetBlocksByHeightRange(0, 10)
+	if err != nil || !equalHeights(heightsOf(found), []uint64{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("the store restored from the backups has the heights %v (%v)", heightsOf(found), err)
+	}
+}
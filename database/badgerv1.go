@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	badgerv1 "github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// The directories written during the migration of a directory of Badger v1, next to it
+const (
+	badgerV1Suffix        = ".v1"        // The original directory, kept after the migration
+	badgerMigrationSuffix = ".migrating" // The new directory, until it replaces the original one
+)
+
+// MigrateBadgerV1 rewrites a directory written by Badger v1 in the format of Badger v2, with the settings of the
+// config (e.g. to encrypt it). The keys, the values, their user metadata and their TTLs are read with Badger v1 and
+// written to a new directory with Badger v2, which then replaces the original one. The original directory is kept
+// with the suffix ".v1", so it can be restored if the migration is wrong. Returns false if the directory was not
+// written by Badger v1, so nothing was migrated
+func MigrateBadgerV1(locationDirectory string, config StoreConfig) (bool, error) {
+	if err := checkBadgerVersion(locationDirectory); !errors.Is(err, ErrBadgerV1) {
+		return false, err
+	}
+
+	original := locationDirectory + badgerV1Suffix
+	migrating := locationDirectory + badgerMigrationSuffix
+	for _, dir := range []string{original, migrating} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			return false, fmt.Errorf("database: can´t migrate %s, the directory %s already exists", locationDirectory, dir)
+		}
+	}
+
+	if err := copyBadgerV1(locationDirectory, migrating, config); err != nil {
+		os.RemoveAll(migrating)
+		return false, err
+	}
+
+	if err := os.Rename(locationDirectory, original); err != nil {
+		os.RemoveAll(migrating)
+		return false, err
+	}
+	if err := os.Rename(migrating, locationDirectory); err != nil {
+		return false, fmt.Errorf("database: the migrated directory is %s and the original one %s: %v", migrating, original, err)
+	}
+
+	return true, nil
+}
+
+// Copy the live entries of a directory of Badger v1 into a new directory of Badger v2
+func copyBadgerV1(from string, to string, config StoreConfig) error {
+	source, err := badgerv1.Open(badgerv1.DefaultOptions(from).WithLogger(nil))
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	opts, err := config.options(to)
+	if err != nil {
+		return err
+	}
+	target, err := badger.Open(opts)
+	if err != nil {
+		return err
+	}
+
+	wb := target.NewWriteBatch()
+	err = source.View(func(txn *badgerv1.Txn) error {
+		it := txn.NewIterator(badgerv1.DefaultIteratorOptions)
+		defer it.Close()
+
+		// The deleted and expired entries are skipped by the iterator
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+
+			entry := badger.NewEntry(item.KeyCopy(nil), value).WithMeta(item.UserMeta())
+			entry.ExpiresAt = item.ExpiresAt()
+			if err = wb.SetEntry(entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err == nil {
+		err = wb.Flush()
+	} else {
+		wb.Cancel()
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	badgerv1 "github.com/dgraph-io/badger"
+)
+
+// The legacy chain of writeLegacyStore, written by Badger v1, with a value that expires
+func writeBadgerV1Store(t *testing.T, dir string, blocks []types.FullSignedBlock) {
+	db, err := badgerv1.Open(badgerv1.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(txn *badgerv1.Txn) error {
+		for _, block := range blocks {
+			content, err := json.Marshal(block)
+			if err != nil {
+				return err
+			}
+			if err = txn.Set(stringIndexKey(block.Hash, HashKeyPrefix), content); err != nil {
+				return err
+			}
+			if err = txn.Set(uintIndexKey(block.Height, HeightKeyPrefix), []byte(block.Hash)); err != nil {
+				return err
+			}
+			if err = txn.Set(uintIndexKey(block.Timestamp, TimestampKeyPrefix), []byte(block.Hash)); err != nil {
+				return err
+			}
+		}
+		return txn.SetEntry(badgerv1.NewEntry(stringIndexKey("session", FixedKeyPrefix), []byte("value")).WithTTL(time.Hour))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateBadgerV1(t *testing.T) {
+	blocks := testChain(t, 10, 1600000000)
+
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T, dir string)
+		migrated bool
+		fails    bool
+		stored   bool // The chain is in the directory
+	}{
+		{"badger v1", func(t *testing.T, dir string) { writeBadgerV1Store(t, dir, blocks) }, true, false, true},
+		{"badger v2", func(t *testing.T, dir string) { writeLegacyStore(t, dir, blocks) }, false, false, true},
+		{"new directory", func(t *testing.T, dir string) {}, false, false, false},
+		{"interrupted migration", func(t *testing.T, dir string) {
+			writeBadgerV1Store(t, dir, blocks)
+			if err := os.Mkdir(dir+badgerMigrationSuffix, 0700); err != nil {
+				t.Fatal(err)
+			}
+		}, false, true, true},
+	}
+	for _, test := range tests {
+		dir := filepath.Join(t.TempDir(), "chain")
+		test.prepare(t, dir)
+
+		migrated, err := MigrateBadgerV1(dir, StoreConfig{})
+		if migrated != test.migrated || (err != nil) != test.fails {
+			t.Errorf("%s: the migration returned %t (%v), expected %t", test.name, migrated, err, test.migrated)
+			continue
+		}
+		if test.fails {
+			if _, err = Open(dir, StoreConfig{}); !errors.Is(err, ErrBadgerV1) {
+				t.Errorf("%s: the directory that was not migrated opened with %v", test.name, err)
+			}
+			continue
+		}
+
+		if test.migrated {
+			if _, err = os.Stat(dir + badgerV1Suffix); err != nil {
+				t.Errorf("%s: the original directory was not kept: %v", test.name, err)
+			}
+		}
+		store, err := Open(dir, StoreConfig{})
+		if err != nil {
+			t.Errorf("%s: the migrated directory can´t be opened: %v", test.name, err)
+			continue
+		}
+		if test.stored {
+			if head, err := store.GetHead(); err != nil || head.Hash != blocks[9].Hash {
+				t.Errorf("%s: the head is %v (%v), expected the block at the height 9", test.name, head, err)
+			}
+			if block, err := store.FindBlockByHeight(4); err != nil || !block.Equal(blocks[4]) {
+				t.Errorf("%s: the block at the height 4 is %v (%v)", test.name, block, err)
+			}
+		}
+		if test.migrated {
+			if value, err := store.GetValue("session"); err != nil || string(value) != "value" {
+				t.Errorf("%s: the value with a TTL is %q (%v)", test.name, value, err)
+			}
+		}
+		store.Close()
+	}
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// The size of the chain read by the benchmarks. The defaults of the Badger store were chosen with 1M blocks
+var benchmarkBlocks = flag.Int("bench-blocks", 10000, "how many blocks are stored for the read benchmarks")
+
+const (
+	benchmarkBatchSize = 1000 // How many blocks are written by each StoreBlocks
+	benchmarkScanSize  = 100  // How many blocks are read by each range scan
+	benchmarkGenesis   = 1577836800
+)
+
+// The tickers of the blocks written by the benchmarks
+var benchmarkTickers = []string{"BTCUSD", "ETHUSD", "LTCUSD", "XRPUSD"}
+
+// A block like the ones of the oracle, with the evidence of three crawlers
+func benchmarkBlock(height uint64, previousHash string) types.FullSignedBlock {
+	ticker := benchmarkTickers[height%uint64(len(benchmarkTickers))]
+	timestamp := uint64(benchmarkGenesis) + height
+
+	evidence := make([]types.Result, 3)
+	for i := range evidence {
+		evidence[i] = types.Result{
+			CrawlerName: fmt.Sprintf("crawler-%d", i),
+			Ticker:      ticker,
+			Timestamp:   int64(timestamp),
+			Data: types.QuotePriceInfo{
+				QuoteVolume: rand.Float64() * 1e9,
+				Volume:      rand.Float64() * 1e5,
+				HighPrice:   rand.Float64() * 1e4,
+				OpenPrice:   rand.Float64() * 1e4,
+				Timestamp:   int64(timestamp),
+				DataURL:     fmt.Sprintf("https://api.crawler-%d.com/ticker/%s", i, ticker),
+			},
+		}
+		evidence[i].CreateHash()
+	}
+
+	block := types.FullSignedBlock{
+		Height:        height,
+		Timestamp:     timestamp,
+		Ticker:        ticker,
+		AveragePrice:  rand.Float64() * 1e4,
+		AverageVolume: rand.Float64() * 1e5,
+		PreviousHash:  previousHash,
+		Address:       "benchmark",
+		Evidence:      evidence,
+	}
+	block.CreateHash()
+
+	return block
+}
+
+// Open an empty store with the default settings
+func benchmarkStore(b *testing.B) *Store {
+	store, err := Open(b.TempDir(), StoreConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+// Write a chain of blocks in batches, returning their hashes
+func writeBenchmarkChain(b *testing.B, store types.KVStore, blocks int) []string {
+	hashes := make([]string, 0, blocks)
+	previous := ""
+	for height := 0; height < blocks; height += benchmarkBatchSize {
+		batch := make([]types.FullSignedBlock, 0, benchmarkBatchSize)
+		for i := height; i < height+benchmarkBatchSize && i < blocks; i++ {
+			block := benchmarkBlock(uint64(i), previous)
+			batch = append(batch, block)
+			previous = block.Hash
+		}
+		if err := store.StoreBlocks(batch); err != nil {
+			b.Fatal(err)
+		}
+		for _, block := range batch {
+			hashes = append(hashes, block.Hash)
+		}
+	}
+
+	return hashes
+}
+
+// Each iteration writes a block, in batches
+func BenchmarkStoreWrites(b *testing.B) {
+	store := benchmarkStore(b)
+	b.ResetTimer()
+
+	writeBenchmarkChain(b, store, b.N)
+}
+
+// The reads are spread at random over the whole chain, so the caches of the store only help as much as they would
+// with real clients
+func BenchmarkPointReads(b *testing.B) {
+	store := benchmarkStore(b)
+	hashes := writeBenchmarkChain(b, store, *benchmarkBlocks)
+
+	benchmarks := []struct {
+		name string
+		read func() error
+	}{
+		{"height", func() error {
+			_, err := store.FindBlockByHeight(uint64(rand.Intn(len(hashes))))
+			return err
+		}},
+		{"hash", func() error {
+			_, err := store.GetBlock(hashes[rand.Intn(len(hashes))])
+			return err
+		}},
+	}
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := benchmark.read(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Each scan reads up to benchmarkScanSize blocks, starting at random over the chain
+func BenchmarkRangeScans(b *testing.B) {
+	store := benchmarkStore(b)
+	blocks := *benchmarkBlocks
+	writeBenchmarkChain(b, store, blocks)
+
+	scan := benchmarkScanSize
+	if scan > blocks {
+		scan = blocks
+	}
+	benchmarks := []struct {
+		name string
+		scan func(i int) error
+	}{
+		{"height", func(i int) error {
+			from := uint64(rand.Intn(blocks - scan + 1))
+			_, err := store.GetBlocksByHeightRange(from, from+uint64(scan)-1)
+			return err
+		}},
+		{"ticker", func(i int) error {
+			_, err := store.GetBlocksByTicker(benchmarkTickers[i%len(benchmarkTickers)], scan)
+			return err
+		}},
+		{"latest", func(i int) error {
+			_, err := store.GetLatestBlocks(uint64(benchmarkGenesis+rand.Intn(blocks)), 0, scan)
+			return err
+		}},
+	}
+	for _, benchmark := range benchmarks {
+		b.Run(benchmark.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := benchmark.scan(i); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@
 package database
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
@@ -27,22 +29,93 @@ const (
 
 // BlockChain is the main data model to handle the blocks
 type BlockChain struct {
-	Name string
+	Name      string
 	IsTestnet bool
 
 	latestBlock *types.FullSignedBlock
-	kvstore types.KVStore
+	kvstore     types.KVStore
+	identity    *types.NodeIdentity
+	genesis     types.GenesisConfig
+	encoding    string // The encoding of the evidence of the new blocks, empty to keep it uncompressed
+	difficulty  uint32 // The proof of work of the new blocks, 0 for none
 }
 
 // NewBlockChain initializes and creates a new manager of a blockchain
-func NewBlockChain (name string, locationDirectory string) *BlockChain {
-	return &BlockChain {
-		Name: name,
-		kvstore: NewKVStore (locationDirectory),
+func NewBlockChain(name string, locationDirectory string) *BlockChain {
+	return &BlockChain{
+		Name:    name,
+		kvstore: NewKVStore(locationDirectory),
+		genesis: types.GenesisConfig{Chain: name},
 	}
 }
-// NewFullSignedBlock creates a new signed block to store
+
+// NewBlockChainWithStore creates a new manager of a blockchain over an already opened store, from any backend
+func NewBlockChainWithStore(name string, kvstore types.KVStore) *BlockChain {
+	return &BlockChain{
+		Name:    name,
+		kvstore: kvstore,
+		genesis: types.GenesisConfig{Chain: name},
+	}
+}
+
+// SetGenesis sets the parameters of the chain, used to build their genesis block. By default, the chain is only
+// identified by their name. It must be set before the first block is created
+func (db *BlockChain) SetGenesis(config types.GenesisConfig) {
+	db.genesis = config
+}
+
+// SetEvidenceEncoding compresses the data of the evidence of the new blocks with the encoding, types.EncodingGzip
+// or types.EncodingZstd. An empty encoding keeps it uncompressed
+func (db *BlockChain) SetEvidenceEncoding(encoding string) {
+	db.encoding = encoding
+}
+
+// SetDifficulty sets the proof of work of the new blocks, the leading zero bits of their hashes. It can´t be under
+// the minimum of the chain, see types.SetMinDifficulty
+func (db *BlockChain) SetDifficulty(difficulty uint32) {
+	db.difficulty = difficulty
+}
+
+// SetIdentity sets the identity of the node, used to sign the new blocks and as their address
+func (db *BlockChain) SetIdentity(identity *types.NodeIdentity) {
+	db.identity = identity
+}
+
+// SetSigningKey sets the private key of the node, used to sign the new blocks. It is the same than setting the
+// identity of the key
+func (db *BlockChain) SetSigningKey(key ed25519.PrivateKey) {
+	db.SetIdentity(&types.NodeIdentity{PrivateKey: key})
+}
+
+// PublicKey returns the public key of the node, or nil if the chain has no identity
+func (db *BlockChain) PublicKey() ed25519.PublicKey {
+	if db.identity == nil {
+		return nil
+	}
+
+	return db.identity.PublicKey()
+}
+
+// NewFullSignedBlock creates a new block to store, with the address of the node and signed with their key, if the
+// chain has an identity. The errors are logged, and the block is returned even if it wasn´t stored
 func (db *BlockChain) NewFullSignedBlock(ticker string, avgPrice float64, avgVolumen float64, sources []types.Result, memo string) types.FullSignedBlock {
+	block, err := db.newBlock(ticker, avgPrice, avgVolumen, nil, sources, memo)
+	if err != nil {
+		log.Println("Can´t store the new block", block.Hash, err)
+	}
+
+	return block
+}
+
+// NewCandleBlock creates and stores a new block as NewFullSignedBlock, with the candle of the sources as their
+// aggregate price. Returns an error if the block can´t be mined, signed or stored, e.g. if it is not valid over the
+// stored parent, so only the stored blocks are published
+func (db *BlockChain) NewCandleBlock(candle types.PriceCandle, avgPrice float64, avgVolumen float64, sources []types.Result, memo string) (types.FullSignedBlock, error) {
+	return db.newBlock(candle.Ticker, avgPrice, avgVolumen, &candle, sources, memo)
+}
+
+// Create, sign and store a new block, with a candle if not nil
+func (db *BlockChain) newBlock(ticker string, avgPrice float64, avgVolumen float64, candle *types.PriceCandle, sources []types.Result, memo string) (types.FullSignedBlock, error) {
 
 	// Create a "protomessage" in order to be hashed with the hash inside
 	var latestHash string
@@ -51,6 +124,18 @@ func (db *BlockChain) NewFullSignedBlock(ticker string, avgPrice float64, avgVol
 	if db.latestBlock == nil { // try to get the stored block
 		db.ReadLatestBlock()
 	}
+	if db.latestBlock == nil { // A new chain, that starts with the genesis block
+		genesis, err := types.NewGenesisBlock(db.genesis)
+		if err == nil {
+			err = db.storeBlock(genesis)
+		}
+		if err != nil {
+			log.Println("Can´t store the genesis block", err)
+		} else {
+			db.latestBlock = &genesis
+			log.Println("Created the genesis block", genesis.Hash)
+		}
+	}
 
 	if db.latestBlock != nil {
 		latestHash = db.latestBlock.Hash   // Yes, there is a latest block, so there is a "latest" of everything
@@ -58,40 +143,145 @@ func (db *BlockChain) NewFullSignedBlock(ticker string, avgPrice float64, avgVol
 	}
 
 	block := types.FullSignedBlock{
+		Version:       types.CurrentBlockVersion,
 		Height:        height,
 		AveragePrice:  avgVolumen,
 		AverageVolume: avgPrice,
 		Ticker:        ticker,
-		Timestamp:     uint64(time.Now().Unix()),
+		Timestamp:     types.NextTimestamp(db.latestBlock, time.Now()),
 		PreviousHash:  latestHash, // Chain the current hash with the previous one
 		Evidence:      sources,
+		Candle:        candle,
 		Memo:          memo,
 	}
+	if db.identity != nil {
+		block.Address = db.identity.Address()
+	}
 	// Other settings
-	block.CreateHash()
+	if db.encoding != "" {
+		if err := block.CompressEvidence(db.encoding); err != nil {
+			log.Println("Can´t compress the evidence of the new block", err)
+		}
+	}
+	block.CreateEvidenceRoot()
+	if difficulty := db.difficulty; difficulty > 0 || types.GetMinDifficulty() > 0 {
+		if difficulty < types.GetMinDifficulty() {
+			difficulty = types.GetMinDifficulty()
+		}
+		if err := block.Mine(difficulty); err != nil {
+			return block, err
+		}
+	} else {
+		block.CreateHash()
+	}
 	if db.latestBlock != nil {
 		block.PreviousAddress = db.latestBlock.Address // Link with previous block
 	}
+	if db.identity != nil {
+		if err := block.Sign(db.identity.PrivateKey); err != nil {
+			return block, err
+		}
+	}
 
-	db.kvstore.StoreBlock(block)
-	// Latest block
+	// Latest block. A block that can´t be stored, e.g. over the size limits, is not linked by the next one
+	if err := db.storeBlock(block); err != nil {
+		return block, err
+	}
 	db.latestBlock = &block
+
+	log.Println("Created a new block", block)
+	return block, nil
+}
+
+// Store a block with the pointer to the latest block. The block, the head and the latest block are written
+// together, if the store can. The block is validated against the stored parent
+func (db *BlockChain) storeBlock(block types.FullSignedBlock) error {
 	bytes, err := json.Marshal(block)
 	if err != nil {
-		panic (err) //TODO: This error is important!! means that there was not able to create a new block! Needs more code to manage this event
+		return err
 	}
-	db.kvstore.StoreValue(LatestBlockKey, bytes)
 
-	log.Println("Created a new block", block)
-	return block
+	err = RunTxn(db.kvstore, func(tx StoreTxn) error {
+		if err := validateBlock(tx.FindBlockByHeight, block); err != nil {
+			return err
+		}
+		if err := tx.StoreBlock(block); err != nil {
+			return err
+		}
+		return tx.StoreValue(LatestBlockKey, bytes)
+	})
+	if errors.Is(err, ErrTxnUnsupported) {
+		if err = validateBlock(db.kvstore.FindBlockByHeight, block); err == nil {
+			if err = db.kvstore.StoreBlock(block); err == nil {
+				err = db.kvstore.StoreValue(LatestBlockKey, bytes)
+			}
+		}
+	}
+
+	return err
 }
 
+// StoreBlocks validates and stores many blocks at once, e.g. to sync the chain from another node. The blocks must be
+// in height order, each one linked to the previous one and the first one to the stored block at their previous
+// height. Nothing is stored if any block is invalid or over the size limits. The latest block moves to the last one
+func (db *BlockChain) StoreBlocks(blocks []types.FullSignedBlock) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	// The parents are the previous blocks of the batch, or the stored ones
+	batch := make(map[uint64]*types.FullSignedBlock, len(blocks))
+	find := func(height uint64) (*types.FullSignedBlock, error) {
+		if parent, ok := batch[height]; ok {
+			return parent, nil
+		}
+		return db.kvstore.FindBlockByHeight(height)
+	}
+	for i := range blocks {
+		if err := validateBlock(find, blocks[i]); err != nil {
+			return err
+		}
+		batch[blocks[i].Height] = &blocks[i]
+	}
+
+	if err := db.kvstore.StoreBlocks(blocks); err != nil {
+		return err
+	}
+
+	if db.latestBlock == nil {
+		db.ReadLatestBlock()
+	}
+	latest := blocks[len(blocks)-1]
+	if db.latestBlock == nil || latest.Height > db.latestBlock.Height {
+		db.latestBlock = &latest
+		db.StoreLatestBlock()
+	}
+
+	return nil
+}
+
+// Validate a block against their parent, read with find. The genesis block has no parent. The size limits are
+// checked first, so an oversized block is rejected without reading the store
+func validateBlock(find func(height uint64) (*types.FullSignedBlock, error), block types.FullSignedBlock) error {
+	if err := block.CheckSize(); err != nil {
+		return err
+	}
+
+	var parent *types.FullSignedBlock
+	if block.Height > 0 {
+		var err error
+		if parent, err = find(block.Height - 1); err != nil {
+			return err
+		}
+	}
+
+	return block.Validate(parent)
+}
 
 func (db *BlockChain) GetBlockByHash(hash string) (*types.FullSignedBlock, error) {
 	return nil, nil
 }
 
-
 // Return a block from a weight value
 func (db *BlockChain) GetBlockByWeight(weight int64) (*types.FullSignedBlock, error) {
 	return nil, nil
@@ -102,10 +292,44 @@ func (db *BlockChain) GetBlockByTimestamp(timestamp int64) (*types.FullSignedBlo
 	return nil, nil
 }
 
-// Return a block from a timestamp value
+// GetMany returns the previousCount blocks created at or before startingTimestamp, the newest first
 func (db *BlockChain) GetMany(startingTimestamp int64, previousCount int) ([]types.FullSignedBlock, error) {
 
-	return nil, nil
+	return db.kvstore.GetLatestBlocks(uint64(startingTimestamp), 0, previousCount)
+}
+
+// GetBlocksByTicker returns the latest limit blocks of a ticker (e.g. BTCUSD), the newest first
+func (db *BlockChain) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+
+	return db.kvstore.GetBlocksByTicker(ticker, limit)
+}
+
+// GetBlocksByAddress returns the latest limit blocks produced by the node with the address, the newest first
+func (db *BlockChain) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+
+	return db.kvstore.GetBlocksByAddress(address, limit)
+}
+
+// GetHeaders returns the headers of the blocks between the heights from and to, both included, for the light
+// clients and the sync, that get the bodies later
+func (db *BlockChain) GetHeaders(from uint64, to uint64) ([]types.BlockHeader, error) {
+	blocks, err := db.kvstore.GetBlocksByHeightRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]types.BlockHeader, 0, len(blocks))
+	for _, block := range blocks {
+		headers = append(headers, block.Header())
+	}
+
+	return headers, nil
+}
+
+// FindBlocksByHashPrefix resolves a short hash, returning up to limit blocks whose hash starts with the prefix
+func (db *BlockChain) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+
+	return db.kvstore.FindBlocksByHashPrefix(prefix, limit)
 }
 
 // Store the latest hash of the message
@@ -116,12 +340,82 @@ func (db *BlockChain) StoreLatestBlock() {
 		log.Println("Can´t store the latest block. Please check the KVStore urgently!!")
 		return // Don´t continue
 	}
-	db.kvstore.StoreValue (LatestBlockKey, bytes)
+	db.kvstore.StoreValue(LatestBlockKey, bytes)
+}
+
+// Close closes the store of the chain. The chain can´t be used after it
+func (db *BlockChain) Close() error {
+	return CloseStore(db.kvstore)
+}
+
+// Health returns an error if the store of the chain can´t be used
+func (db *BlockChain) Health() error {
+	return StoreHealth(db.kvstore)
+}
+
+// DiskUsage returns the approximate size in bytes of the store of the chain on disk
+func (db *BlockChain) DiskUsage() (int64, error) {
+	return StoreDiskUsage(db.kvstore)
+}
+
+// Txn runs fn in a transaction of the store of the chain, or returns ErrTxnUnsupported
+func (db *BlockChain) Txn(fn func(tx StoreTxn) error) error {
+	return RunTxn(db.kvstore, fn)
+}
+
+// Repair fixes the indexes of the store of the chain, or only reports their problems if dryRun is set
+func (db *BlockChain) Repair(dryRun bool) (*RepairReport, error) {
+	return RepairStore(db.kvstore, dryRun)
+}
+
+// Subscribe returns a subscription to the blocks written to the store of the chain, or ErrSubscribeUnsupported
+func (db *BlockChain) Subscribe(prefix string) (*Subscription, error) {
+	return SubscribeStore(db.kvstore, prefix)
+}
+
+// NewReplicator creates a replicator that mirrors the blocks of the store of the chain in the secondary store
+func (db *BlockChain) NewReplicator(secondary types.KVStore, config ReplicationConfig, metrics *ReplicationMetrics) *Replicator {
+	return NewReplicator(db.kvstore, secondary, config, metrics)
+}
+
+// GetHead returns the block at the tip of the chain, read from the head pointer of the store
+func (db *BlockChain) GetHead() (*types.FullSignedBlock, error) {
+	return db.kvstore.GetHead()
+}
+
+// GetLatestHeight returns the height of the tip of the chain
+func (db *BlockChain) GetLatestHeight() (uint64, error) {
+	return db.kvstore.GetLatestHeight()
+}
+
+// GetConfirmations returns the number of blocks from the block with the hash to the head, both included, so it
+// grows with the chain. The head has one confirmation. Returns types.ErrNotFound if the block is not stored
+func (db *BlockChain) GetConfirmations(hash string) (uint64, error) {
+	block, err := db.kvstore.GetBlock(hash)
+	if err != nil {
+		return 0, err
+	}
+
+	tip, err := db.kvstore.GetLatestHeight()
+	if err != nil {
+		return 0, err
+	}
+	if tip < block.Height {
+		return 1, nil // The head is being written
+	}
+
+	return tip - block.Height + 1, nil
 }
 
-// Get the latest stored block
+// Get the latest stored block. The head of the store is written with the block, so it is used first. The
+// value of LatestBlockKey is only read if the store has no head
 func (db *BlockChain) ReadLatestBlock() {
 
+	if head, err := db.kvstore.GetHead(); err == nil {
+		db.latestBlock = head
+		return
+	}
+
 	bytes, err := db.kvstore.GetValue(LatestBlockKey)
 	if err != nil {
 		log.Println("The repository for the latest block don´t exists. Is is the genesis block?")
@@ -0,0 +1,289 @@
+package database
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// The chains with a key sign their new blocks with the address of the key, the others leave them unsigned
+func TestBlockChainSignsBlocks(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  ed25519.PrivateKey
+		err  error
+	}{
+		{"with a key", key, nil},
+		{"without a key", nil, types.ErrUnsignedBlock},
+	}
+	for _, test := range tests {
+		chain := NewBlockChainWithStore("test", NewMemoryStore())
+		if test.key != nil {
+			chain.SetSigningKey(test.key)
+		}
+
+		for i := 0; i < 2; i++ {
+			block := chain.NewFullSignedBlock("BTCUSD", 10000, 1.5, nil, "")
+			if err := block.VerifySignature(key.Public().(ed25519.PublicKey)); !errors.Is(err, test.err) {
+				t.Errorf("%s: the block %d verifies with %v, expected %v", test.name, i, err, test.err)
+			}
+			if err := block.VerifyAddress(); !errors.Is(err, test.err) {
+				t.Errorf("%s: the address %q of the block %d verifies with %v, expected %v", test.name, block.Address, i, err, test.err)
+			}
+		}
+	}
+
+	if NewBlockChainWithStore("test", NewMemoryStore()).PublicKey() != nil {
+		t.Error("a chain without a key has a public key")
+	}
+}
+
+// A new chain starts with the genesis block of their parameters, and the first price block goes after it
+func TestBlockChainGenesis(t *testing.T) {
+	config := types.GenesisConfig{Chain: "BTCUSD", Params: map[string]string{"sources": "binance"}}
+	store := NewMemoryStore()
+	chain := NewBlockChainWithStore("test", store)
+	chain.SetGenesis(config)
+
+	block := chain.NewFullSignedBlock("BTCUSD", 10000, 1.5, nil, "")
+	genesis, err := store.FindBlockByHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = genesis.ValidateGenesis(config); err != nil {
+		t.Errorf("the stored genesis block is invalid: %v", err)
+	}
+	if block.Height != 1 || block.PreviousHash != genesis.Hash {
+		t.Errorf("the first block is at the height %d linked to %s, expected 1 and %s", block.Height, block.PreviousHash, genesis.Hash)
+	}
+	if head, err := store.GetHead(); err != nil || head.Hash != block.Hash {
+		t.Errorf("the head is %v (%v), expected the first block", head, err)
+	}
+}
+
+// The headers of a range validate as a chain without the bodies
+func TestBlockChainGetHeaders(t *testing.T) {
+	chain := NewBlockChainWithStore("test", NewMemoryStore())
+	for i := 0; i < 3; i++ {
+		chain.NewFullSignedBlock("BTCUSD", 10000+float64(i), 1.5, nil, "")
+	}
+
+	tests := []struct {
+		name    string
+		from    uint64
+		to      uint64
+		heights []uint64
+	}{
+		{"all", 0, 3, []uint64{0, 1, 2, 3}},
+		{"inside", 1, 2, []uint64{1, 2}},
+		{"over the head", 3, 10, []uint64{3}},
+	}
+	for _, test := range tests {
+		headers, err := chain.GetHeaders(test.from, test.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		heights := make([]uint64, 0, len(headers))
+		for i, header := range headers {
+			heights = append(heights, header.Height)
+			var parent *types.BlockHeader
+			if i > 0 {
+				parent = &headers[i-1]
+			}
+			if err = header.Validate(parent); err != nil {
+				t.Errorf("%s: the header at the height %d is invalid: %v", test.name, header.Height, err)
+			}
+		}
+		if !equalHeights(heights, test.heights) {
+			t.Errorf("%s returned the heights %v, expected %v", test.name, heights, test.heights)
+		}
+	}
+}
+
+// The confirmations of a block grow with the chain, and the head confirms itself
+func TestBlockChainConfirmations(t *testing.T) {
+	chain := NewBlockChainWithStore("test", NewMemoryStore())
+	var blocks []types.FullSignedBlock
+	for i := 0; i < 3; i++ {
+		blocks = append(blocks, chain.NewFullSignedBlock("BTCUSD", 10000+float64(i), 1.5, nil, ""))
+	}
+
+	tests := []struct {
+		name          string
+		hash          string
+		confirmations uint64
+		err           error
+	}{
+		{"first block", blocks[0].Hash, 3, nil},
+		{"tip", blocks[2].Hash, 1, nil},
+		{"unknown block", "unknown", 0, types.ErrNotFound},
+	}
+	for _, test := range tests {
+		confirmations, err := chain.GetConfirmations(test.hash)
+		if !errors.Is(err, test.err) || confirmations != test.confirmations {
+			t.Errorf("the %s has %d confirmations (%v), expected %d (%v)", test.name, confirmations, err, test.confirmations, test.err)
+		}
+	}
+
+}
+
+// A block over the size limits is not stored, and the next block links to the last stored one
+func TestBlockChainBlockTooLarge(t *testing.T) {
+	defer types.SetBlockLimits(types.GetBlockLimits())
+	limits := types.DefaultBlockLimits
+	limits.MaxMemoSize = 100 // Over the memo of the genesis block
+	types.SetBlockLimits(limits)
+
+	store := NewMemoryStore()
+	chain := NewBlockChainWithStore("test", store)
+	first := chain.NewFullSignedBlock("BTCUSD", 10000, 1.5, nil, "")
+	large := chain.NewFullSignedBlock("BTCUSD", 10001, 1.5, nil, strings.Repeat("m", 101))
+	next := chain.NewFullSignedBlock("BTCUSD", 10002, 1.5, nil, "")
+
+	if _, err := store.GetBlock(large.Hash); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("the block over the limits was stored (%v)", err)
+	}
+	if next.Height != first.Height+1 || next.PreviousHash != first.Hash {
+		t.Errorf("the next block is at the height %d linked to %s, expected %d and %s", next.Height, next.PreviousHash, first.Height+1, first.Hash)
+	}
+	if head, err := store.GetHead(); err != nil || head.Hash != next.Hash {
+		t.Errorf("the head is %v (%v), expected the next block", head, err)
+	}
+}
+
+// The blocks over the size limits are rejected by storeBlock and StoreBlocks, and a batch with one of them or a broken
+// link stores nothing
+func TestBlockChainStoreBlocks(t *testing.T) {
+	defer types.SetBlockLimits(types.GetBlockLimits())
+
+	source := NewBlockChainWithStore("test", NewMemoryStore())
+	blocks := []types.FullSignedBlock{source.NewFullSignedBlock("BTCUSD", 10000, 1.5, nil, "")}
+	for i := 0; i < 3; i++ {
+		blocks = append(blocks, source.NewFullSignedBlock("BTCUSD", float64(10001+i), 1.5, nil, ""))
+	}
+	large := source.NewFullSignedBlock("BTCUSD", 10005, 1.5, nil, strings.Repeat("m", 101))
+	genesis, err := source.kvstore.FindBlockByHeight(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks = append([]types.FullSignedBlock{*genesis}, blocks...)
+	broken := blocks[2]
+	broken.PreviousHash = blocks[0].Hash
+
+	limits := types.DefaultBlockLimits
+	limits.MaxMemoSize = 100
+	types.SetBlockLimits(limits)
+
+	tests := []struct {
+		name   string
+		blocks []types.FullSignedBlock
+		err    error
+		head   uint64
+	}{
+		{"a valid chain", blocks, nil, blocks[len(blocks)-1].Height},
+		{"a block over the limits", append(append([]types.FullSignedBlock{}, blocks...), large), types.ErrBlockTooLarge, 0},
+		{"a broken link", []types.FullSignedBlock{blocks[0], blocks[1], broken}, types.ErrInvalidBlock, 0},
+	}
+	for _, test := range tests {
+		store := NewMemoryStore()
+		chain := NewBlockChainWithStore("test", store)
+		err := chain.StoreBlocks(test.blocks)
+		if !errors.Is(err, test.err) {
+			t.Errorf("storing %s returned %v, expected %v", test.name, err, test.err)
+			continue
+		}
+		if test.err != nil {
+			if _, err = store.GetLatestHeight(); !errors.Is(err, types.ErrNotFound) {
+				t.Errorf("storing %s wrote blocks (%v)", test.name, err)
+			}
+			continue
+		}
+		if height, err := store.GetLatestHeight(); err != nil || height != test.head {
+			t.Errorf("storing %s left the head at %d (%v), expected %d", test.name, height, err, test.head)
+		}
+		if next := chain.NewFullSignedBlock("BTCUSD", 10010, 1.5, nil, ""); next.Height != test.head+1 {
+			t.Errorf("after storing %s the next block is at the height %d, expected %d", test.name, next.Height, test.head+1)
+		}
+	}
+
+	chain := NewBlockChainWithStore("test", NewMemoryStore())
+	if err = chain.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err = chain.storeBlock(large); !errors.Is(err, types.ErrBlockTooLarge) {
+		t.Errorf("storing a block over the limits returned %v", err)
+	}
+}
+
+// The evidence of the new blocks is compressed with the encoding of the chain, and the stored blocks are valid
+func TestBlockChainEvidenceEncoding(t *testing.T) {
+	result := types.Result{CrawlerName: "binance", Ticker: "BTCUSD", Data: types.QuotePriceInfo{HighPrice: 10000}}
+	if err := result.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+	}{
+		{"uncompressed", ""},
+		{"gzip", types.EncodingGzip},
+		{"zstd", types.EncodingZstd},
+	}
+	for _, test := range tests {
+		store := NewMemoryStore()
+		chain := NewBlockChainWithStore("test", store)
+		chain.SetEvidenceEncoding(test.encoding)
+
+		block := chain.NewFullSignedBlock("BTCUSD", 10000, 1.5, []types.Result{result}, "")
+		stored, err := store.GetBlock(block.Hash)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(stored.Evidence) != 1 || stored.Evidence[0].Encoding != test.encoding {
+			t.Errorf("%s: the stored evidence is %v", test.name, stored.Evidence)
+		}
+		if decompressed, err := stored.Evidence[0].Decompressed(); err != nil || decompressed.Data != result.Data {
+			t.Errorf("%s: the stored evidence decompresses to %v (%v)", test.name, decompressed, err)
+		}
+	}
+}
+
+// The candle blocks are stored with their candle
+func TestBlockChainCandleBlock(t *testing.T) {
+	candle := types.PriceCandle{Ticker: "BTCUSD", Start: 1600000000, Period: 60, Open: 10, High: 15, Low: 9, Close: 12, Volume: 4}
+	store := NewMemoryStore()
+	chain := NewBlockChainWithStore("test", store)
+
+	block, err := chain.NewCandleBlock(candle, 12, 4, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Ticker != "BTCUSD" || block.Candle == nil || *block.Candle != candle {
+		t.Errorf("the candle block is %v", block)
+	}
+	stored, err := store.GetBlock(block.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Equal(block) {
+		t.Errorf("the stored candle block is %v, expected %v", stored, block)
+	}
+
+	candle.Low = 20
+	invalid, err := chain.NewCandleBlock(candle, 12, 4, nil, "")
+	if err == nil {
+		t.Error("a block with an invalid candle was created")
+	}
+	if _, err = store.GetBlock(invalid.Hash); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("the block with an invalid candle was stored (%v)", err)
+	}
+}
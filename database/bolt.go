@@ -0,0 +1,378 @@
+package database
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// BoltBackend is the name of the backend that keeps all the data in a single bbolt file
+	BoltBackend = "bolt"
+
+	// How long to wait for the lock of the file, when another process has it open
+	boltOpenTimeout = 5 * time.Second
+)
+
+// All the keys live in one bucket, with the same prefixes than in the Badger store
+var boltBucket = []byte("darkmatter")
+
+// BoltStore implements the KVStore interface over a single bbolt file. Each write is a crash-safe transaction
+type BoltStore struct {
+	db        *bolt.DB
+	namespace namespace // Prepended to the keys of the chain. Empty for the default namespace
+}
+
+// Implements orderedReader over a bucket inside a transaction
+type boltReader struct {
+	bucket *bolt.Bucket
+}
+
+// NewBoltStore opens (or creates) the bbolt file in the location
+func NewBoltStore(fileLocation string) (*BoltStore, error) {
+	db, err := bolt.Open(fileLocation, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store := &BoltStore{db: db}
+	if err = runMigrations(store, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close releases the file
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+// Health returns an error if the file can´t be read
+func (b *BoltStore) Health() error {
+	return b.view(func(r orderedReader) error { return nil })
+}
+
+func (r boltReader) get(key []byte) ([]byte, error) {
+	value := r.bucket.Get(key)
+	if value == nil {
+		return nil, types.ErrNotFound
+	}
+
+	return append([]byte(nil), value...), nil
+}
+
+func (r boltReader) scan(prefix []byte, start []byte, reverse bool, fn func(key, value []byte) (bool, error)) error {
+	c := r.bucket.Cursor()
+
+	// The cursor seeks the first key greater or equal, so in reverse it may need to step back one key
+	k, v := c.Seek(start)
+	if reverse {
+		if k == nil {
+			k, v = c.Last()
+		} else if !bytes.Equal(k, start) {
+			k, v = c.Prev()
+		}
+	}
+
+	for k != nil && bytes.HasPrefix(k, prefix) {
+		next, err := fn(k, v)
+		if err != nil || !next {
+			return err
+		}
+
+		if reverse {
+			k, v = c.Prev()
+		} else {
+			k, v = c.Next()
+		}
+	}
+
+	return nil
+}
+
+// Namespace returns a store for the chain of a namespace, sharing the file with this one. The namespaces work in
+// the same way than in the Badger store
+func (b *BoltStore) Namespace(name string) (*BoltStore, error) {
+	ns, err := newNamespace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltStore{db: b.db, namespace: ns}, nil
+}
+
+// Run a read-only transaction over the keys of the namespace
+func (b *BoltStore) view(fn func(r orderedReader) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return fn(b.namespace.reader(boltReader{bucket: tx.Bucket(boltBucket)}))
+	})
+}
+
+// StoreValue stores an abritrary value, indexed by a string
+func (b *BoltStore) StoreValue(key string, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return b.namespace.setter(tx.Bucket(boltBucket).Put)(stringIndexKey(key, FixedKeyPrefix), value)
+	})
+}
+
+// GetValue returns a value indexed by an string
+func (b *BoltStore) GetValue(key string) ([]byte, error) {
+	var value []byte
+	err := b.view(func(r orderedReader) (err error) {
+		value, err = r.get(stringIndexKey(key, FixedKeyPrefix))
+		return err
+	})
+
+	return value, err
+}
+
+// StoreBlock stores a full block indexed by their timestamp and Height. Storing the same block again does
+// nothing, and a different block at the same height or with the same hash is a *ConflictError
+func (b *BoltStore) StoreBlock(block types.FullSignedBlock) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		r, set := b.namespace.reader(boltReader{bucket: bucket}), b.namespace.setter(bucket.Put)
+		if stored, err := orderedCheckBlock(r, block); err != nil || stored {
+			return err
+		}
+
+		if err := writeBlock(set, block, false); err != nil {
+			return err
+		}
+
+		return orderedUpdateHead(r, set, []types.FullSignedBlock{block})
+	})
+}
+
+// StoreBlocks stores many blocks in a single transaction
+func (b *BoltStore) StoreBlocks(blocks []types.FullSignedBlock) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		set := b.namespace.setter(bucket.Put)
+		for _, block := range blocks {
+			if err := writeBlock(set, block, false); err != nil {
+				return err
+			}
+		}
+
+		return orderedUpdateHead(b.namespace.reader(boltReader{bucket: bucket}), set, blocks)
+	})
+}
+
+// GetBlock reads a block using their hash
+func (b *BoltStore) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		block, err = orderedReadBlock(r, hash)
+		return err
+	})
+
+	return block, err
+}
+
+// FindBlockByTimestamp reads a block using their timestamp as index. If many blocks were created in the same
+// second, the newest one
+func (b *BoltStore) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		block, err = orderedReadBlockByTimestamp(r, timestamp)
+		return err
+	})
+
+	return block, err
+}
+
+// FindBlockByHeight reads a block using their height as index
+func (b *BoltStore) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		block, err = orderedReadBlockByIndex(r, Height, HeightKeyPrefix)
+		return err
+	})
+
+	return block, err
+}
+
+// GetLatestBlocks returns the blocks created at or before the timestamp, the newest block first, skipping the
+// first offset blocks and returning no more than limit blocks, unless limit is zero
+func (b *BoltStore) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		blocks, err = orderedLatestBlocks(r, timestamp, offset, limit)
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetBlocksByHeightRange returns the blocks between the heights from and to, both included
+func (b *BoltStore) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksInRange(r, from, to, 0, HeightKeyPrefix)
+		return err
+	})
+
+	return blocks, err
+}
+
+// FindBlocksBetween returns the blocks created between startTs and endTs, both included, the oldest first.
+// No more than limit blocks are returned, unless limit is zero
+func (b *BoltStore) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksBetween(r, startTs, endTs, limit)
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetHead returns the block at the tip of the chain, the one with the greatest height
+func (b *BoltStore) GetHead() (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		block, err = orderedHead(r)
+		return err
+	})
+
+	return block, err
+}
+
+// GetLatestHeight returns the height of the tip of the chain, without reading the block
+func (b *BoltStore) GetLatestHeight() (uint64, error) {
+	var height uint64
+	err := b.view(func(r orderedReader) (err error) {
+		height, _, err = orderedReadHead(r)
+		return err
+	})
+
+	return height, err
+}
+
+// GetBlocksByTicker returns the blocks of a ticker, the newest first. No more than limit blocks are returned,
+// unless limit is zero
+func (b *BoltStore) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksByString(r, ticker, limit, TickerKeyPrefix)
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetBlocksByAddress returns the blocks produced by the node with the address, the newest first. No more than
+// limit blocks are returned, unless limit is zero
+func (b *BoltStore) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksByString(r, address, limit, AddressKeyPrefix)
+		return err
+	})
+
+	return blocks, err
+}
+
+// SchemaVersion returns the version of the schema of the stored data, the same for all the namespaces
+func (b *BoltStore) SchemaVersion() (int, error) {
+	root := &BoltStore{db: b.db}
+
+	var version int
+	err := root.view(func(r orderedReader) (err error) {
+		version, err = orderedSchemaVersion(r)
+		return err
+	})
+
+	return version, err
+}
+
+func (b *BoltStore) schemaVersion() (int, error) {
+	return b.SchemaVersion()
+}
+
+// A write of a migration to the bolt bucket, a put or a delete if the value is nil
+type boltWrite struct {
+	key, value []byte
+}
+
+// The bucket can´t be changed while a cursor reads it, so the writes are done, in order, once the migration has
+// read all
+func (b *BoltStore) apply(m migration) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		var writes []boltWrite
+		err := m.migrate(boltReader{bucket: bucket}, func(key, value []byte) error {
+			writes = append(writes, boltWrite{key, value})
+			return nil
+		}, func(key []byte) error {
+			writes = append(writes, boltWrite{key, nil})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, write := range writes {
+			if write.value == nil {
+				err = bucket.Delete(write.key)
+			} else {
+				err = bucket.Put(write.key, write.value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return bucket.Put(schemaKey, encodeSchemaVersion(m.version))
+	})
+}
+
+// VerifyChain walks the blocks between the heights from and to, both included, and reports the broken links, the
+// invalid hashes and evidence, the gaps and the inconsistent indexes
+func (b *BoltStore) VerifyChain(from uint64, to uint64) (*ChainReport, error) {
+	var report *ChainReport
+	err := b.view(func(r orderedReader) (err error) {
+		report, err = orderedVerifyChain(r, from, to)
+		return err
+	})
+
+	return report, err
+}
+
+// FindBlocksByHashPrefix returns the blocks whose hash starts with the prefix, sorted by hash. No more than limit
+// blocks are returned, unless limit is zero
+func (b *BoltStore) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := b.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksByHashPrefix(r, prefix, limit)
+		return err
+	})
+
+	return blocks, err
+}
+
+func init() {
+	RegisterBackend(BoltBackend, func(location string) (types.KVStore, error) {
+		store, err := NewBoltStore(location)
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	})
+}
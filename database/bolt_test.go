@@ -0,0 +1,45 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStoreRoundTrip(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	testStoreRoundTrip(t, store)
+}
+
+// The blocks are read from the file once it is opened again
+func TestBoltStoreReopen(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "chain.db")
+	store, err := NewBoltStore(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := testChain(t, 3, 1600000000)
+	if err = store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if store, err = NewBoltStore(location); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	latest, err := store.GetLatestBlocks(blocks[2].Timestamp, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := heightsOf(latest); !equalHeights(got, []uint64{2, 1, 0}) {
+		t.Errorf("the heights %v were read after opening the file again, expected [2 1 0]", got)
+	}
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// The blocks compressed with zstd start with the magic number of the zstd frames, the ones written in JSON by the
+// previous versions with "{", and the MessagePack ones with a map header, so the reads tell them apart. The JSON
+// blocks are encoded again by the migration to the schema version 3, so they are only read once
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Both are safe to use from many goroutines at the same time
+var zstdEncoder, _ = zstd.NewWriter(nil)
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// Serialize a block to be stored with MessagePack, compressing it if asked. The fields keep the names of their json
+// tags, so the stored blocks can be read as the ones of the API
+func encodeBlock(block types.FullSignedBlock, compress bool) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	encoder.UseCompactInts(true)
+
+	if err := encoder.Encode(&block); err != nil {
+		return nil, err
+	}
+	if !compress {
+		return buffer.Bytes(), nil
+	}
+
+	return zstdEncoder.EncodeAll(buffer.Bytes(), nil), nil
+}
+
+// Deserialize a stored block, compressed or not, in JSON or MessagePack
+func decodeBlock(data []byte) (*types.FullSignedBlock, error) {
+	if bytes.HasPrefix(data, zstdMagic) {
+		var err error
+		if data, err = zstdDecoder.DecodeAll(data, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var block types.FullSignedBlock
+	if len(data) > 0 && data[0] == '{' {
+		if err := json.Unmarshal(data, &block); err != nil {
+			return nil, err
+		}
+		return &block, nil
+	}
+
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(&block); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
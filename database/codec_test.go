@@ -0,0 +1,134 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// A block with all the fields that are stored, the evidence included
+func testCodecBlock(t *testing.T) types.FullSignedBlock {
+	result := types.Result{
+		CrawlerName: "binance",
+		Ticker:      "BTCUSD",
+		Timestamp:   1600000000,
+		Data:        types.QuotePriceInfo{HighPrice: 10250.5, OpenPrice: 10100.25, Volume: 12.125},
+	}
+	result.CreateHash()
+
+	block := types.FullSignedBlock{
+		Height:       18446744073709551615, // The uint64 must not lose precision
+		Timestamp:    1600000000,
+		AveragePrice: 10250.5,
+		Ticker:       "BTCUSD",
+		Address:      "node",
+		Memo:         "ñ \"quoted\"",
+		Evidence:     []types.Result{result},
+	}
+	if err := block.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	block := testCodecBlock(t)
+	legacy, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		encode func() ([]byte, error)
+	}{
+		{"msgpack", func() ([]byte, error) { return encodeBlock(block, false) }},
+		{"msgpack compressed", func() ([]byte, error) { return encodeBlock(block, true) }},
+		{"json", func() ([]byte, error) { return legacy, nil }},
+		{"json compressed", func() ([]byte, error) { return zstdEncoder.EncodeAll(legacy, nil), nil }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := test.encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := decodeBlock(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*decoded, block) {
+				t.Errorf("the decoded block %v is not the encoded one %v", decoded, block)
+			}
+		})
+	}
+}
+
+func TestCodecInvalid(t *testing.T) {
+	encoded, err := encodeBlock(testCodecBlock(t), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated msgpack", encoded[:len(encoded)/2]},
+		{"truncated json", []byte(`{"hash":"dd`)},
+		{"zstd frame without content", append(append([]byte(nil), zstdMagic...), 0xff, 0xff)},
+		{"empty", nil},
+	}
+	for _, test := range tests {
+		if _, err := decodeBlock(test.data); err == nil {
+			t.Errorf("decoding a %s block didn´t fail", test.name)
+		}
+	}
+}
+
+// The MessagePack blocks are smaller than the JSON ones, and the compressed ones smaller still
+func TestCodecSizes(t *testing.T) {
+	block := testCodecBlock(t)
+	for i := 0; i < 20; i++ {
+		block.Evidence = append(block.Evidence, block.Evidence[0])
+	}
+
+	legacy, _ := json.Marshal(block)
+	plain, err := encodeBlock(block, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := encodeBlock(block, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plain) >= len(legacy) {
+		t.Errorf("the MessagePack block has %d bytes, the JSON one %d", len(plain), len(legacy))
+	}
+	if len(compressed) >= len(plain) {
+		t.Errorf("the compressed block has %d bytes, the uncompressed one %d", len(compressed), len(plain))
+	}
+}
+
+// A store holds blocks written with and without compression
+func TestCompressedStore(t *testing.T) {
+	blocks := testChain(t, 2, 1600000000)
+	dir := t.TempDir()
+	if err := NewKVStoreWithConfig(dir, StoreConfig{CompressBlocks: true}).StoreBlock(blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+	store := NewKVStoreWithConfig(dir, StoreConfig{})
+	if err := store.StoreBlock(blocks[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := store.GetBlocksByHeightRange(0, 1)
+	if err != nil || !equalHeights(heightsOf(found), []uint64{0, 1}) {
+		t.Errorf("the store with both forms returned %v (%v)", heightsOf(found), err)
+	}
+}
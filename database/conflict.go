@@ -0,0 +1,65 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// ConflictError is returned by StoreBlock when the store already holds a different block at the same height, or
+// a block with the same hash and different content. The stored block is not changed
+type ConflictError struct {
+	Height     uint64
+	Hash       string
+	StoredHash string // The hash of the block already stored at the height
+}
+
+func (e *ConflictError) Error() string {
+	if e.Hash == e.StoredHash {
+		return fmt.Sprintf("database: the block %s is already stored at the height %d with a different content", e.Hash, e.Height)
+	}
+
+	return fmt.Sprintf("database: the height %d already holds the block %s, can´t store the block %s", e.Height, e.StoredHash, e.Hash)
+}
+
+// Unwrap returns types.ErrDuplicate, for errors.Is
+func (e *ConflictError) Unwrap() error {
+	return types.ErrDuplicate
+}
+
+// Decide what to do with a new block, knowing the hash stored at their height (empty if there is none) and the
+// block stored with their hash (nil if there is none). Returns true if the same block is already stored, so the
+// write can be skipped, or a *ConflictError if the write would change another block or their indexes
+func checkStoredBlock(block types.FullSignedBlock, hashAtHeight string, stored *types.FullSignedBlock) (bool, error) {
+	if hashAtHeight != "" && hashAtHeight != block.Hash {
+		return false, &ConflictError{Height: block.Height, Hash: block.Hash, StoredHash: hashAtHeight}
+	}
+
+	if stored == nil {
+		return false, nil
+	}
+	if !stored.Equal(block) {
+		return false, &ConflictError{Height: block.Height, Hash: block.Hash, StoredHash: stored.Hash}
+	}
+
+	return true, nil
+}
+
+// Check a new block against the blocks of an ordered engine, in the same way than checkStoredBlock
+func orderedCheckBlock(r orderedReader, block types.FullSignedBlock) (bool, error) {
+	hashAtHeight, err := r.get(uintIndexKey(block.Height, HeightKeyPrefix))
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return false, err
+	}
+
+	stored, err := orderedReadBlock(r, block.Hash)
+	if errors.Is(err, types.ErrNotFound) {
+		stored, err = nil, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return checkStoredBlock(block, string(hashAtHeight), stored)
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+func TestCheckStoredBlock(t *testing.T) {
+	block := testChain(t, 1, 1600000000)[0]
+	changed := block
+	changed.Memo = "changed"
+	decoded := block
+	decoded.Evidence = []types.Result{} // As read back from some stores
+
+	tests := []struct {
+		name         string
+		hashAtHeight string
+		stored       *types.FullSignedBlock
+		exists       bool
+		conflict     *ConflictError
+	}{
+		{"new block", "", nil, false, nil},
+		{"same block", block.Hash, &block, true, nil},
+		{"height of another block", "other", nil, false, &ConflictError{Height: 0, Hash: block.Hash, StoredHash: "other"}},
+		{"changed content", block.Hash, &changed, false, &ConflictError{Height: 0, Hash: block.Hash, StoredHash: block.Hash}},
+		{"hash without the height index", "", &block, true, nil},
+		{"same block with empty evidence", block.Hash, &decoded, true, nil},
+	}
+	for _, test := range tests {
+		exists, err := checkStoredBlock(block, test.hashAtHeight, test.stored)
+		if exists != test.exists {
+			t.Errorf("%s: checkStoredBlock returned %t, expected %t", test.name, exists, test.exists)
+		}
+
+		var conflict *ConflictError
+		switch {
+		case test.conflict == nil && err != nil:
+			t.Errorf("%s: checkStoredBlock failed: %v", test.name, err)
+		case test.conflict != nil && (!errors.As(err, &conflict) || *conflict != *test.conflict):
+			t.Errorf("%s: checkStoredBlock returned %v, expected %v", test.name, err, test.conflict)
+		case test.conflict != nil && !errors.Is(err, types.ErrDuplicate):
+			t.Errorf("%s: the conflict %v is not a types.ErrDuplicate", test.name, err)
+		}
+	}
+}
+
+func TestConflictErrorMessage(t *testing.T) {
+	tests := []struct {
+		err     ConflictError
+		message string
+	}{
+		{ConflictError{Height: 2, Hash: "aa", StoredHash: "bb"}, "database: the height 2 already holds the block bb, can´t store the block aa"},
+		{ConflictError{Height: 2, Hash: "aa", StoredHash: "aa"}, "database: the block aa is already stored at the height 2 with a different content"},
+	}
+	for _, test := range tests {
+		if message := test.err.Error(); message != test.message {
+			t.Errorf("the conflict message is %q, expected %q", message, test.message)
+		}
+	}
+}
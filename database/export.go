@@ -0,0 +1,380 @@
+package database
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// ExportFormat is the format of the files with the exported blocks
+type ExportFormat string
+
+const (
+	// ExportJSONL writes each block in JSON, one block per line
+	ExportJSONL ExportFormat = "jsonl"
+	// ExportCSV writes a header and then one row per block. The evidence is written as JSON in the last column
+	ExportCSV ExportFormat = "csv"
+)
+
+// ErrExportUnsupported is returned by ExportStore and ImportStore for the backends that can´t export their blocks
+var ErrExportUnsupported = errors.New("database: the backend can´t export their blocks")
+
+// Exporter is implemented by the stores that can export their blocks and import them back
+type Exporter interface {
+	// Export writes all the blocks in the format, in height order
+	Export(w io.Writer, format ExportFormat) error
+	// Import reads the blocks written by Export and stores them. Returns how many blocks were imported
+	Import(r io.Reader) (int, error)
+}
+
+// ExportStore exports the blocks of a store, or returns ErrExportUnsupported if their backend can´t export them
+func ExportStore(store types.KVStore, w io.Writer, format ExportFormat) error {
+	if exporter, ok := store.(Exporter); ok {
+		return exporter.Export(w, format)
+	}
+
+	return ErrExportUnsupported
+}
+
+// ImportStore imports the exported blocks in a store, or returns ErrExportUnsupported if their backend can´t import
+// them
+func ImportStore(store types.KVStore, r io.Reader) (int, error) {
+	if exporter, ok := store.(Exporter); ok {
+		return exporter.Import(r)
+	}
+
+	return 0, ErrExportUnsupported
+}
+
+// The columns of the CSV format
+var csvHeader = []string{
+	"hash", "height", "timestamp", "ticker", "avgPrice", "avgVolumen",
+	"previousHash", "address", "previousAddress", "memo", "evidence",
+}
+
+// The columns added after the first exports, empty if they are missing
+var csvOptionalHeader = []string{"signature", "publicKey", "evidenceRoot", "version", "nonce", "difficulty", "candle"}
+
+// Writes the blocks one by one in a format
+type blockWriter interface {
+	write(block *types.FullSignedBlock) error
+	flush() error
+}
+
+type jsonlWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// Create the writer for the format
+func newBlockWriter(w io.Writer, format ExportFormat) (blockWriter, error) {
+	switch format {
+	case ExportJSONL:
+		buffer := bufio.NewWriter(w)
+		return &jsonlWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+	case ExportCSV:
+		writer := csv.NewWriter(w)
+		return &csvWriter{writer: writer}, writer.Write(append(append([]string(nil), csvHeader...), csvOptionalHeader...))
+	}
+
+	return nil, fmt.Errorf("database: unknown export format %q", format)
+}
+
+func (j *jsonlWriter) write(block *types.FullSignedBlock) error {
+	return j.encoder.Encode(block) // Encode ends each block with a new line
+}
+
+func (j *jsonlWriter) flush() error {
+	return j.buffer.Flush()
+}
+
+func (c *csvWriter) write(block *types.FullSignedBlock) error {
+	evidence, err := json.Marshal(block.Evidence)
+	if err != nil {
+		return err
+	}
+	var candle []byte
+	if block.Candle != nil {
+		if candle, err = json.Marshal(block.Candle); err != nil {
+			return err
+		}
+	}
+
+	return c.writer.Write([]string{
+		block.Hash,
+		strconv.FormatUint(block.Height, 10),
+		strconv.FormatUint(block.Timestamp, 10),
+		block.Ticker,
+		strconv.FormatFloat(block.AveragePrice, 'g', -1, 64),
+		strconv.FormatFloat(block.AverageVolume, 'g', -1, 64),
+		block.PreviousHash,
+		block.Address,
+		block.PreviousAddress,
+		block.Memo,
+		string(evidence),
+		block.Signature,
+		block.PublicKey,
+		block.EvidenceRoot,
+		strconv.FormatUint(uint64(block.Version), 10),
+		strconv.FormatUint(block.Nonce, 10),
+		strconv.FormatUint(uint64(block.Difficulty), 10),
+		string(candle),
+	})
+}
+
+func (c *csvWriter) flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// Export writes all the blocks in height order. The blocks are read one by one from a consistent snapshot of the
+// database, so the chain is never fully loaded in memory
+func (s Store) Export(w io.Writer, format ExportFormat) error {
+	writer, err := newBlockWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer s.release(stor)
+
+	err = stor.View(func(txn *badger.Txn) error {
+		r := s.reader(txn)
+		return r.scan([]byte{HeightKeyPrefix}, []byte{HeightKeyPrefix}, false, func(key, hash []byte) (bool, error) {
+			block, err := orderedReadBlock(r, string(hash))
+			if err != nil {
+				return false, err
+			}
+
+			return true, writer.write(block)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.flush()
+}
+
+// Import reads the blocks written by Export, in any of the formats, and stores them. The stream must hold a
+// contiguous segment of the chain in height order: the hash of each block is verified, and each one must link to
+// the previous one. The first block must be the genesis block or link to a block already stored.
+// When an invalid block is found, the valid blocks before it are stored, so the store always holds a linked prefix.
+// Returns how many blocks were imported
+func (s Store) Import(r io.Reader) (int, error) {
+	if err := s.writable(); err != nil {
+		return 0, err
+	}
+
+	reader, err := newBlockReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.release(stor)
+
+	if err = s.checkQuota(stor); err != nil {
+		return 0, err
+	}
+
+	wb := stor.NewWriteBatch()
+	defer wb.Cancel()
+	set := s.namespace.setter(wb.Set)
+
+	// An invalid block stops the import, but the valid blocks before it are still written
+	var previous, head *types.FullSignedBlock
+	imported := 0
+	for err == nil {
+		var block *types.FullSignedBlock
+		if block, err = reader.read(); err != nil {
+			break
+		}
+
+		if previous == nil && block.Height > 0 {
+			// The parent of the first block must be in the store
+			err = stor.View(func(txn *badger.Txn) error {
+				previous, err = orderedReadBlockByIndex(s.reader(txn), block.Height-1, HeightKeyPrefix)
+				return err
+			})
+			if err != nil {
+				err = fmt.Errorf("database: the parent of the block %d is not stored: %v", block.Height, err)
+				break
+			}
+		}
+
+		if err = block.Validate(previous); err == nil {
+			err = writeBlock(set, *block, s.config.CompressBlocks)
+		}
+		if err == nil {
+			previous = block
+			imported++
+			if head == nil || block.Height >= head.Height {
+				head = block
+			}
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+
+	if head != nil {
+		headErr := stor.View(func(txn *badger.Txn) error {
+			return orderedUpdateHead(s.reader(txn), set, []types.FullSignedBlock{*head})
+		})
+		if headErr != nil {
+			return 0, headErr
+		}
+	}
+
+	if flushErr := wb.Flush(); flushErr != nil {
+		return 0, flushErr
+	}
+
+	return imported, err
+}
+
+// Reads the blocks one by one from a format
+type blockReader interface {
+	read() (*types.FullSignedBlock, error)
+}
+
+type jsonlReader struct {
+	decoder *json.Decoder
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// Create the reader for the format of the stream. The JSON Lines start with "{", and the CSV with the header
+func newBlockReader(r io.Reader) (blockReader, error) {
+	buffer := bufio.NewReader(r)
+	first, err := buffer.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return &jsonlReader{decoder: json.NewDecoder(buffer)}, nil // Empty, nothing to read
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] == '{' {
+		return &jsonlReader{decoder: json.NewDecoder(buffer)}, nil
+	}
+
+	reader := csv.NewReader(buffer)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range csvHeader {
+		if _, exists := columns[name]; !exists {
+			return nil, fmt.Errorf("database: the column %q is missing", name)
+		}
+	}
+
+	return &csvReader{reader: reader, columns: columns}, nil
+}
+
+func (j *jsonlReader) read() (*types.FullSignedBlock, error) {
+	var block types.FullSignedBlock
+	if err := j.decoder.Decode(&block); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+func (c *csvReader) read() (*types.FullSignedBlock, error) {
+	row, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	column := func(name string) string {
+		if i, exists := c.columns[name]; exists {
+			return row[i]
+		}
+		return ""
+	}
+	block := types.FullSignedBlock{
+		Hash:            column("hash"),
+		Ticker:          column("ticker"),
+		PreviousHash:    column("previousHash"),
+		Address:         column("address"),
+		PreviousAddress: column("previousAddress"),
+		Memo:            column("memo"),
+		Signature:       column("signature"),
+		PublicKey:       column("publicKey"),
+		EvidenceRoot:    column("evidenceRoot"),
+	}
+
+	if block.Height, err = strconv.ParseUint(column("height"), 10, 64); err != nil {
+		return nil, err
+	}
+	if block.Timestamp, err = strconv.ParseUint(column("timestamp"), 10, 64); err != nil {
+		return nil, err
+	}
+	if block.AveragePrice, err = strconv.ParseFloat(column("avgPrice"), 64); err != nil {
+		return nil, err
+	}
+	if block.AverageVolume, err = strconv.ParseFloat(column("avgVolumen"), 64); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(column("evidence")), &block.Evidence); err != nil {
+		return nil, err
+	}
+	if version := column("version"); version != "" {
+		v, err := strconv.ParseUint(version, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		block.Version = uint32(v)
+	}
+	if nonce := column("nonce"); nonce != "" {
+		if block.Nonce, err = strconv.ParseUint(nonce, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	if difficulty := column("difficulty"); difficulty != "" {
+		d, err := strconv.ParseUint(difficulty, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		block.Difficulty = uint32(d)
+	}
+	if candle := column("candle"); candle != "" {
+		if err = json.Unmarshal([]byte(candle), &block.Candle); err != nil {
+			return nil, err
+		}
+	}
+
+	return &block, nil
+}
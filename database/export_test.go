@@ -0,0 +1,244 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// A Badger store with a chain of three blocks, stored out of order, the last one signed and with evidence
+func testExportStore(t *testing.T) (Store, []types.FullSignedBlock) {
+	blocks := testChain(t, 3, 1600000000)
+	blocks[2].Memo = "a memo, with \"quotes\""
+	evidence := types.Result{CrawlerName: "binance", Ticker: "BTCUSD", Data: types.QuotePriceInfo{HighPrice: 10000}}
+	if err := evidence.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+	blocks[2].Evidence = []types.Result{evidence}
+	if err := blocks[2].CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = blocks[2].Sign(key); err != nil {
+		t.Fatal(err)
+	}
+
+	store := Store{StorFileLocation: t.TempDir()}
+	for _, i := range []int{2, 0, 1} {
+		if err := store.StoreBlock(blocks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return store, blocks
+}
+
+func TestExportJSONL(t *testing.T) {
+	store, blocks := testExportStore(t)
+	var exported bytes.Buffer
+	if err := store.Export(&exported, ExportJSONL); err != nil {
+		t.Fatal(err)
+	}
+
+	var read []types.FullSignedBlock
+	scanner := bufio.NewScanner(&exported)
+	for scanner.Scan() {
+		var block types.FullSignedBlock
+		if err := json.Unmarshal(scanner.Bytes(), &block); err != nil {
+			t.Fatalf("the line %q is not a block: %v", scanner.Text(), err)
+		}
+		read = append(read, block)
+	}
+
+	if !reflect.DeepEqual(read, blocks) {
+		t.Errorf("the exported blocks are %v, expected %v in height order", read, blocks)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	store, blocks := testExportStore(t)
+	var exported bytes.Buffer
+	if err := store.Export(&exported, ExportCSV); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(&exported).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := append(append([]string(nil), csvHeader...), csvOptionalHeader...); len(rows) != len(blocks)+1 || !reflect.DeepEqual(rows[0], header) {
+		t.Fatalf("the CSV has %d rows, starting with %v", len(rows), rows[0])
+	}
+
+	tests := []struct {
+		column int
+		value  string
+	}{
+		{0, blocks[2].Hash},
+		{1, "2"},
+		{2, "1600000002"},
+		{4, "1002"},
+		{9, blocks[2].Memo},
+		{11, blocks[2].Signature},
+		{12, blocks[2].PublicKey},
+	}
+	for _, test := range tests {
+		if value := rows[3][test.column]; value != test.value {
+			t.Errorf("the column %s is %q, expected %q", rows[0][test.column], value, test.value)
+		}
+	}
+
+	var evidence []types.Result
+	if err = json.Unmarshal([]byte(rows[3][10]), &evidence); err != nil || !reflect.DeepEqual(evidence, blocks[2].Evidence) {
+		t.Errorf("the evidence column is %s (%v)", rows[3][10], err)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	store, _ := testExportStore(t)
+	if err := store.Export(&bytes.Buffer{}, ExportFormat("xml")); err == nil {
+		t.Error("the blocks were exported in an unknown format")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []ExportFormat{ExportJSONL, ExportCSV} {
+		store, blocks := testExportStore(t)
+		var exported bytes.Buffer
+		if err := store.Export(&exported, format); err != nil {
+			t.Fatal(err)
+		}
+
+		imported := Store{StorFileLocation: t.TempDir()}
+		count, err := imported.Import(&exported)
+		if err != nil || count != len(blocks) {
+			t.Fatalf("the %s import returned %d blocks (%v)", format, count, err)
+		}
+
+		found, err := imported.GetBlocksByHeightRange(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(found, blocks) {
+			t.Errorf("the blocks imported from %s are %v, expected %v", format, found, blocks)
+		}
+	}
+}
+
+// The CSV files exported before the signatures existed are still imported
+func TestImportCSVWithoutSignature(t *testing.T) {
+	store, blocks := testExportStore(t)
+	var exported bytes.Buffer
+	if err := store.Export(&exported, ExportCSV); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&exported).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacy bytes.Buffer
+	writer := csv.NewWriter(&legacy)
+	for _, row := range rows {
+		if err = writer.Write(row[:len(csvHeader)]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Flush()
+
+	imported := Store{StorFileLocation: t.TempDir()}
+	if count, err := imported.Import(&legacy); err != nil || count != len(blocks) {
+		t.Fatalf("the import returned %d blocks (%v)", count, err)
+	}
+	block, err := imported.FindBlockByHeight(2)
+	if err != nil || block.Hash != blocks[2].Hash || block.Signature != "" || block.PublicKey != "" {
+		t.Errorf("the block imported without signature is %v (%v)", block, err)
+	}
+}
+
+// Write the blocks as JSON Lines, the format read by Import
+func jsonLines(t *testing.T, blocks []types.FullSignedBlock) *bytes.Buffer {
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, block := range blocks {
+		if err := encoder.Encode(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &lines
+}
+
+func TestImportInvalid(t *testing.T) {
+	blocks := testChain(t, 4, 1600000000)
+	tampered := append([]types.FullSignedBlock(nil), blocks...)
+	tampered[2].AveragePrice++
+	unlinked := append([]types.FullSignedBlock(nil), blocks...)
+	unlinked[2].PreviousHash = blocks[0].Hash
+	if err := unlinked[2].CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+	genesis := blocks[0]
+	genesis.PreviousHash = blocks[1].Hash
+	if err := genesis.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		blocks []types.FullSignedBlock
+		stored []uint64
+	}{
+		{"a block with an invalid hash", tampered, []uint64{0, 1}},
+		{"a block not linked to the previous one", unlinked, []uint64{0, 1}},
+		{"a genesis block with a parent", []types.FullSignedBlock{genesis}, []uint64{}},
+		{"a first block without their parent", blocks[2:], []uint64{}},
+	}
+	for _, test := range tests {
+		store := Store{StorFileLocation: t.TempDir()}
+		count, err := store.Import(jsonLines(t, test.blocks))
+		if err == nil {
+			t.Errorf("the import of %s didn´t fail", test.name)
+		}
+		if count != len(test.stored) {
+			t.Errorf("the import of %s returned %d blocks, expected %d", test.name, count, len(test.stored))
+		}
+
+		found, err := store.GetBlocksByHeightRange(0, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := heightsOf(found); !equalHeights(got, test.stored) {
+			t.Errorf("the import of %s stored the heights %v, expected %v", test.name, got, test.stored)
+		}
+	}
+}
+
+// An import can continue a chain already stored
+func TestImportContinues(t *testing.T) {
+	blocks := testChain(t, 4, 1600000000)
+	store := Store{StorFileLocation: t.TempDir()}
+	if err := store.StoreBlocks(blocks[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := store.Import(jsonLines(t, blocks[2:])); err != nil || count != 2 {
+		t.Fatalf("the import after the stored blocks returned %d (%v)", count, err)
+	}
+	found, err := store.GetBlocksByHeightRange(0, 10)
+	if err != nil || !equalHeights(heightsOf(found), []uint64{0, 1, 2, 3}) {
+		t.Errorf("the store has the heights %v (%v)", heightsOf(found), err)
+	}
+
+	if _, err = store.Import(bytes.NewBufferString("hash,height\nxx,1\n")); err == nil {
+		t.Error("a CSV without all the columns was imported")
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+const (
+	// DefaultGCDiscardRatio is the fraction of a value log file that must be stale before the file is rewritten
+	DefaultGCDiscardRatio = 0.5
+	// DefaultGCInterval is the time between two runs of the garbage collector, if not set in the config
+	DefaultGCInterval = 10 * time.Minute
+)
+
+// ErrGCUnsupported is returned by CollectStore for the backends without a value log to collect
+var ErrGCUnsupported = errors.New("database: the backend can´t be garbage collected")
+
+// GarbageCollectable is implemented by the stores that can free the space of their stale data
+type GarbageCollectable interface {
+	// GC rewrites the files with too much stale data and returns how many were rewritten
+	GC() (int, error)
+}
+
+// CollectStore runs the garbage collection of a store, or returns ErrGCUnsupported if their backend has nothing to
+// collect
+func CollectStore(store types.KVStore) (int, error) {
+	if collectable, ok := store.(GarbageCollectable); ok {
+		return collectable.GC()
+	}
+
+	return 0, ErrGCUnsupported
+}
+
+// GCConfig sets when the value log of a Badger store is collected
+type GCConfig struct {
+	// Interval is the time between two runs of the background job
+	Interval time.Duration
+	// DiscardRatio is the fraction of stale data a file needs to be rewritten, between 0 and 1. Lower values free
+	// more space but rewrite files more often
+	DiscardRatio float64
+	// MaxRewrites is how many files can be rewritten in a run. There is no limit if zero
+	MaxRewrites int
+}
+
+// GC rewrites the value log files with too much stale data, with the default discard ratio, until no file is
+// worth it. Returns how many files were rewritten
+func (s Store) GC() (int, error) {
+	return s.collect(DefaultGCDiscardRatio, 0)
+}
+
+// Run the garbage collection of the value log. Each call to RunValueLogGC rewrites one file at most, so it is
+// repeated until Badger reports there is nothing to do
+func (s Store) collect(discardRatio float64, maxRewrites int) (int, error) {
+	if err := s.writable(); err != nil {
+		return 0, err
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.release(stor)
+
+	rewrites := 0
+	for maxRewrites == 0 || rewrites < maxRewrites {
+		err = stor.RunValueLogGC(discardRatio)
+		if errors.Is(err, badger.ErrNoRewrite) {
+			return rewrites, nil
+		}
+		if err != nil {
+			return rewrites, err
+		}
+		rewrites++
+	}
+
+	return rewrites, nil
+}
+
+// GarbageCollector runs the value log garbage collection of a store on each interval. Badger never removes the stale
+// values by itself, so without it the disk usage grows with each rewritten key
+type GarbageCollector struct {
+	store  Store
+	config GCConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewGarbageCollector creates the collector for the store. The zero values of the config are replaced by the defaults
+func NewGarbageCollector(store Store, config GCConfig) *GarbageCollector {
+	if config.Interval <= 0 {
+		config.Interval = DefaultGCInterval
+	}
+	if config.DiscardRatio <= 0 || config.DiscardRatio >= 1 {
+		config.DiscardRatio = DefaultGCDiscardRatio
+	}
+
+	return &GarbageCollector{
+		store:  store,
+		config: config,
+	}
+}
+
+// CollectNow runs the garbage collection once, and returns how many files were rewritten
+func (g *GarbageCollector) CollectNow() (int, error) {
+	return g.store.collect(g.config.DiscardRatio, g.config.MaxRewrites)
+}
+
+// Start launches the background job that collects the value log
+func (g *GarbageCollector) Start() {
+	g.stop = make(chan struct{})
+	g.done = make(chan struct{})
+
+	go func() {
+		defer close(g.done)
+
+		ticker := time.NewTicker(g.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				rewrites, err := g.CollectNow()
+				if err != nil {
+					log.Println("The garbage collection of the value log failed", err)
+				} else if rewrites > 0 {
+					log.Println("Rewritten value log files:", rewrites)
+				}
+			case <-g.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background job, waiting for the current run to finish
+func (g *GarbageCollector) Stop() {
+	close(g.stop)
+	<-g.done
+}
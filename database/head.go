@@ -0,0 +1,93 @@
+package database
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// The head pointer holds the height and the hash of the block at the tip of the chain
+var headKey = []byte{HeadKeyPrefix}
+
+// Serialize the head pointer for a block
+func encodeHead(block types.FullSignedBlock) []byte {
+	value := make([]byte, 8, 8+len(block.Hash))
+	binary.BigEndian.PutUint64(value, block.Height)
+
+	return append(value, block.Hash...)
+}
+
+// Deserialize the head pointer
+func decodeHead(value []byte) (uint64, string, error) {
+	if len(value) < 8 {
+		return 0, "", errors.New("database: the head pointer is corrupt")
+	}
+
+	return binary.BigEndian.Uint64(value), string(value[8:]), nil
+}
+
+// Returns the block of the batch that becomes the new head, or nil if the head doesn´t change. The height and the
+// error are the ones returned when the current head was read, so a types.ErrNotFound means there is no head yet
+func nextHead(height uint64, err error, blocks []types.FullSignedBlock) (*types.FullSignedBlock, error) {
+	hasHead := err == nil
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return nil, err
+	}
+
+	var head *types.FullSignedBlock
+	for i := range blocks {
+		if !hasHead || blocks[i].Height >= height {
+			head, height, hasHead = &blocks[i], blocks[i].Height, true
+		}
+	}
+
+	return head, nil
+}
+
+// Read the height and the hash of the head from an ordered engine. The stores written before the head pointer
+// existed don´t have it, so then the head is the last key of the height index
+func orderedReadHead(r orderedReader) (uint64, string, error) {
+	value, err := r.get(headKey)
+	if err == nil {
+		return decodeHead(value)
+	}
+	if !errors.Is(err, types.ErrNotFound) {
+		return 0, "", err
+	}
+
+	var height uint64
+	var hash string
+	err = r.scan([]byte{HeightKeyPrefix}, uintIndexKey(math.MaxUint64, HeightKeyPrefix), true, func(key, value []byte) (bool, error) {
+		height, hash = binary.BigEndian.Uint64(key[1:]), string(value)
+		return false, nil
+	})
+	if err == nil && hash == "" {
+		err = types.ErrNotFound // Empty store
+	}
+
+	return height, hash, err
+}
+
+// Move the head pointer of an ordered engine, if any of the blocks is at or above the current head. The set
+// function can be the one of a transaction or of a write batch
+func orderedUpdateHead(r orderedReader, set func(key, value []byte) error, blocks []types.FullSignedBlock) error {
+	height, _, err := orderedReadHead(r)
+	head, err := nextHead(height, err, blocks)
+	if err != nil || head == nil {
+		return err
+	}
+
+	return set(headKey, encodeHead(*head))
+}
+
+// Read the block at the head of the chain, from an ordered engine
+func orderedHead(r orderedReader) (*types.FullSignedBlock, error) {
+	_, hash, err := orderedReadHead(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderedReadBlock(r, hash)
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+func TestNextHead(t *testing.T) {
+	blocks := testChain(t, 4, 1600000000)
+
+	tests := []struct {
+		name   string
+		height uint64
+		err    error
+		blocks []types.FullSignedBlock
+		head   string
+	}{
+		{"no head yet", 0, types.ErrNotFound, blocks[:1], blocks[0].Hash},
+		{"a higher block", 1, nil, blocks[2:3], blocks[2].Hash},
+		{"the same height", 2, nil, blocks[2:3], blocks[2].Hash},
+		{"a lower block", 3, nil, blocks[1:2], ""},
+		{"the highest of a batch", 1, nil, []types.FullSignedBlock{blocks[3], blocks[2]}, blocks[3].Hash},
+		{"no blocks", 1, nil, nil, ""},
+	}
+	for _, test := range tests {
+		head, err := nextHead(test.height, test.err, test.blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash := ""; head != nil {
+			hash = head.Hash
+			if hash != test.head {
+				t.Errorf("the next head with %s is %s, expected %s", test.name, hash, test.head)
+			}
+		} else if test.head != "" {
+			t.Errorf("the head didn´t move with %s", test.name)
+		}
+	}
+
+	failed := errors.New("failed read")
+	if _, err := nextHead(0, failed, blocks); !errors.Is(err, failed) {
+		t.Errorf("the error reading the head returned %v", err)
+	}
+}
+
+// The stores written before the head pointer existed use the last key of the height index
+func TestHeadWithoutPointer(t *testing.T) {
+	store := Store{StorFileLocation: t.TempDir()}
+	blocks := testChain(t, 3, 1600000000)
+	if err := store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	stor, err := store.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stor.Update(func(txn *badger.Txn) error { return txn.Delete(headKey) })
+	stor.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if head, err := store.GetHead(); err != nil || head.Hash != blocks[2].Hash {
+		t.Errorf("the head without the pointer is %v (%v), expected the block at the height 2", head, err)
+	}
+}
+
+// Many writers at the same time leave the head at the highest block
+func TestHeadConcurrentWrites(t *testing.T) {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+	leveldb, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leveldb.Close()
+
+	stores := []struct {
+		name  string
+		store types.KVStore
+	}{
+		{"memory", NewMemoryStore()},
+		{"bolt", bolt},
+		{"leveldb", leveldb},
+	}
+	for _, test := range stores {
+		blocks := testChain(t, 8, 1600000000)
+		var wg sync.WaitGroup
+		for i := range blocks {
+			wg.Add(1)
+			go func(block types.FullSignedBlock) {
+				defer wg.Done()
+				if err := test.store.StoreBlock(block); err != nil {
+					t.Error(err)
+				}
+			}(blocks[i])
+		}
+		wg.Wait()
+
+		if height, err := test.store.GetLatestHeight(); err != nil || height != 7 {
+			t.Errorf("the %s store has the height %d (%v) after the concurrent writes, expected 7", test.name, height, err)
+		}
+	}
+}
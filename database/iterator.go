@@ -0,0 +1,107 @@
+package database
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger/v2"
+)
+
+// The size of a key to be greater than any other key stored
+const maxKeySize = 1024
+
+// IterOptions are the settings to iterate over the keys of a prefix
+type IterOptions struct {
+	// Reverse iterates from the greatest key to the lowest one
+	Reverse bool
+	// Start is the key (without the prefix) where the iteration begins. In reverse mode the iteration
+	// begins at the greatest key lower or equal than Start. The iteration begins at the edge of the prefix if empty
+	Start []byte
+	// PrefetchSize is how many values are read in advance. Values are not prefetched if zero
+	PrefetchSize int
+}
+
+// Iterator walks over the pairs stored under a prefix
+type Iterator interface {
+	// Next moves to the next pair. It must be called before reading the first one, and returns false at the end
+	Next() bool
+	// Key returns the key of the current pair, without the prefix
+	Key() []byte
+	// Value returns a copy of the value of the current pair
+	Value() ([]byte, error)
+	// Close releases the iterator and the database behind it
+	Close() error
+}
+
+// Implements the Iterator interface over a badger read-only transaction
+type badgerIterator struct {
+	store   Store
+	stor    *badger.DB
+	txn     *badger.Txn
+	it      *badger.Iterator
+	seek    []byte
+	prefix  int // The length of the namespace and the prefix, removed from the keys
+	started bool
+}
+
+// Iterate returns an iterator over all the pairs stored with the prefix in the namespace of the store. The iterator
+// holds the database open, so it must be closed once done
+func (s Store) Iterate(prefix byte, opts IterOptions) (Iterator, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	itOpts := badger.DefaultIteratorOptions
+	itOpts.Reverse = opts.Reverse
+	itOpts.Prefix = s.namespace.key([]byte{prefix})
+	itOpts.PrefetchValues = opts.PrefetchSize > 0
+	itOpts.PrefetchSize = opts.PrefetchSize
+
+	seek := s.namespace.key(append([]byte{prefix}, opts.Start...))
+	if opts.Reverse && len(opts.Start) == 0 {
+		// In reverse the seek stops at a key equal to the seek key, so the next prefix can´t be used: its first key may
+		// exist (as the head key after the heights). A long enough key of the prefix is greater than any other one
+		seek = s.namespace.key(append([]byte{prefix}, bytes.Repeat([]byte{0xFF}, maxKeySize)...))
+	}
+
+	txn := stor.NewTransaction(false)
+	return &badgerIterator{
+		store:  s,
+		stor:   stor,
+		txn:    txn,
+		it:     txn.NewIterator(itOpts),
+		seek:   seek,
+		prefix: len(itOpts.Prefix),
+	}, nil
+}
+
+// Next moves to the next pair
+func (i *badgerIterator) Next() bool {
+	if i.started {
+		i.it.Next()
+	} else {
+		i.it.Seek(i.seek)
+		i.started = true
+	}
+
+	return i.it.Valid()
+}
+
+// Key returns the key of the current pair, without the prefix
+func (i *badgerIterator) Key() []byte {
+	return i.it.Item().KeyCopy(nil)[i.prefix:]
+}
+
+// Value returns a copy of the value of the current pair
+func (i *badgerIterator) Value() ([]byte, error) {
+	return i.it.Item().ValueCopy(nil)
+}
+
+// Close releases the iterator, the transaction and the database
+func (i *badgerIterator) Close() error {
+	i.it.Close()
+	i.txn.Discard()
+
+	return i.store.release(i.stor)
+}
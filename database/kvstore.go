@@ -2,10 +2,13 @@ package database
 
 import (
 	"encoding/binary"
-	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 
 	"github.com/aquarelle-tech/darkmatter/types"
-	"github.com/dgraph-io/badger"
+	"github.com/dgraph-io/badger/v2"
 )
 
 const (
@@ -13,211 +16,611 @@ const (
 	HashKeyPrefix      = 0x1
 	TimestampKeyPrefix = 0x2
 	HeightKeyPrefix    = 0x3
+	HeadKeyPrefix      = 0x4  // The head pointer, a single key
+	TickerKeyPrefix    = 0x5  // The heights of the blocks of each ticker
+	AddressKeyPrefix   = 0x6  // The heights of the blocks of each producing node
+	SchemaKeyPrefix    = 0x7  // The schema version, a single key
+	NamespaceKeyPrefix = 0x8  // The keys of the named namespaces
 	FixedKeyPrefix     = 0xFF // Any other key
 )
 
+// The version of the MANIFEST written by Badger v2. The data directories of Badger v1 have an older one
+const badgerV2Manifest = 7
+
+// ErrBadgerV1 is returned when the directory was written by Badger v1, whose files can´t be read by Badger v2
+var ErrBadgerV1 = errors.New("database: the directory was written by Badger v1. Migrate it with MigrateBadgerV1 " +
+	"(darkmatter -migrate-badger-v1), or sync the chain into a new directory")
+
 // Implements the KVStore interface
 type Store struct {
-	StorFileLocation	string
+	StorFileLocation string
+
+	config    StoreConfig
+	db        *badger.DB // Held open by Open, else the database is opened on each call
+	namespace namespace  // Prepended to the keys of the chain. Empty for the default namespace
 }
 
-// Creates a new store for key-value pairs
-func NewKVStore (locationDirectory string) types.KVStore {
-	kvs := &Store {
-		StorFileLocation : locationDirectory,
+// Creates a new store for key-value pairs, using the default backend
+func NewKVStore(locationDirectory string) types.KVStore {
+	kvs, err := OpenKVStore(BadgerBackend, locationDirectory)
+	if err != nil {
+		panic(err)
 	}
 
 	return kvs
 }
 
-// Store a value in the database indexed by an uint64
-func storeUIntIndex (txn *badger.Txn, key uint64, value []byte, prefix byte) error {
+// NewKVStoreWithConfig creates a new Badger store for key-value pairs with the settings of the config
+func NewKVStoreWithConfig(locationDirectory string, config StoreConfig) types.KVStore {
+	store := &Store{
+		StorFileLocation: locationDirectory,
+		config:           config,
+	}
+	if err := store.Migrate(); err != nil {
+		panic(err)
+	}
+
+	return store
+}
+
+// Open opens the Badger database in the directory and keeps it open until Close, so the calls don´t open the
+// database each time. Unlike NewKVStore, the errors are returned
+func Open(locationDirectory string, config StoreConfig) (*Store, error) {
+	opts, err := config.options(locationDirectory)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkBadgerVersion(opts.Dir); err != nil {
+		return nil, err
+	}
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	index := make([]byte, 8)
-	binary.LittleEndian.PutUint64(index, key)
-	index = append ([]byte{prefix}, index...)
+	store := &Store{
+		StorFileLocation: locationDirectory,
+		config:           config,
+		db:               db,
+	}
+	if err = store.Migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
 
-	return txn.Set(index, value)
+	return store, nil
 }
 
-// Read a value from the database indexed by an uint64
-func readUIntIndex (txn *badger.Txn, key uint64,  prefix byte) ([]byte, error) {
+// Close closes the database held open by Open, waiting for the pending writes. The calls after Close fail with
+// badger.ErrDBClosed. It does nothing for the stores that open the database on each call
+func (s *Store) Close() error {
+	if s.db == nil {
+		return nil
+	}
 
-	index := make([]byte, 8)
-	binary.LittleEndian.PutUint64(index, key)
-	index = append ([]byte{prefix}, index...)
+	return s.db.Close()
+}
 
-	item, err := txn.Get(index)
+// Health returns an error if the database can´t be read
+func (s *Store) Health() error {
+	stor, err := s.open()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return item.ValueCopy(nil)
+	defer s.release(stor)
+
+	return stor.View(func(txn *badger.Txn) error {
+		_, err := s.reader(txn).get(headKey)
+		if errors.Is(err, types.ErrNotFound) {
+			return nil // Empty store
+		}
+		return err
+	})
 }
 
-// Store a value in the database indexed by an uint64
-func storeStringIndex (txn *badger.Txn, key string, value []byte, prefix byte) error {
+// Namespace returns a store for the chain of a namespace (e.g. a ticker), sharing the database with this one. The
+// blocks, the indexes, the head and the values of each namespace are independent. Names are not nested: the
+// namespace of a namespaced store is taken from the root of the database.
+// Backup, Restore and GC always work over the whole database, and the schema is migrated for the default namespace
+// only. Closing any of the stores closes the database shared by all of them
+func (s *Store) Namespace(name string) (*Store, error) {
+	ns, err := newNamespace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := *s
+	scoped.namespace = ns
+
+	return &scoped, nil
+}
 
-	index := append ([]byte{prefix}, []byte(key)...)
-	return txn.Set(index, value)
+// Scope a badger transaction to the namespace of the store
+func (s Store) reader(txn *badger.Txn) orderedReader {
+	return s.namespace.reader(badgerReader{txn: txn})
 }
 
-// Read a value from the database indexed by an uint64
-func readStringIndex (txn *badger.Txn, key string,  prefix byte) ([]byte, error) {
+// Open the Badger database with the settings of the store, or return the one held open
+func (s Store) open() (*badger.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
 
-	index := append ([]byte{prefix}, []byte(key)...)
-	item, err := txn.Get(index)
+	opts, err := s.config.options(s.StorFileLocation)
 	if err != nil {
 		return nil, err
 	}
+	if err = checkBadgerVersion(opts.Dir); err != nil {
+		return nil, err
+	}
 
-	return item.ValueCopy(nil)
+	return badger.Open(opts)
 }
 
-// Store a full block in the database. The block will be indexed by their timestamp and Height
-func (s Store) StoreBlock (block types.FullSignedBlock) error {
+// Return ErrBadgerV1 if the MANIFEST of the directory was written by Badger v1. A directory without MANIFEST is a
+// new store, and a MANIFEST that can´t be read is left to Badger
+func checkBadgerVersion(dir string) error {
+	if dir == "" {
+		return nil // In memory
+	}
 
-	// Open badger
-	stor, err := badger.Open(badger.DefaultOptions(s.StorFileLocation))
+	manifest, err := os.Open(filepath.Join(dir, badger.ManifestFilename))
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer manifest.Close()
 
-	defer stor.Close()
+	var magic [8]byte
+	if _, err = io.ReadFull(manifest, magic[:]); err != nil {
+		return nil
+	}
+	if string(magic[:4]) == "Bdgr" && binary.BigEndian.Uint32(magic[4:]) < badgerV2Manifest {
+		return ErrBadgerV1
+	}
 
-	// Serialize all the parts: block in json
-	bytes, err := json.Marshal(block)
+	return nil
+}
 
-	err = stor.Update(func(txn *badger.Txn) error {
+// Close the database opened by open, unless it is the one held open
+func (s Store) release(stor *badger.DB) error {
+	if stor == s.db {
+		return nil
+	}
 
-		var txErr error
-		// Store the hash as a key. This is the main register
-		if txErr = storeStringIndex(txn, block.Hash, bytes, HashKeyPrefix); txErr == nil {
-			// And now store the indexes. Using this indexes it is possible to retrieve the hash, and next the block
-			if txErr = storeUIntIndex(txn, block.Timestamp, []byte(block.Hash), TimestampKeyPrefix); txErr != nil { // By timestamp
-				return txErr
-			}
+	return stor.Close()
+}
 
-			if txErr = storeUIntIndex(txn, block.Height, []byte(block.Hash), HeightKeyPrefix); txErr != nil { // By block Height
-				return txErr
-			}
-		} 
+// Build the key for an uint64 index. The number is big endian encoded, so the keys
+// sort in the same order than the numbers and the indexes can be iterated in order. The keys written in little
+// endian by the first versions are rewritten by the migration to the schema version 1
+func uintIndexKey(key uint64, prefix byte) []byte {
 
-		 return txErr
-	})
+	index := make([]byte, 9)
+	index[0] = prefix
+	binary.BigEndian.PutUint64(index[1:], key)
 
-	return err
+	return index
 }
 
-// Read a block from the database using their hash
-func (s Store) GetBlock (hash string) (*types.FullSignedBlock, error) {
+// Build the key of a block in the timestamp index. The timestamp and the height are big endian encoded, so the
+// blocks sort by timestamp and then by height, and all the blocks created in the same second are indexed
+func timestampHeightKey(timestamp uint64, height uint64) []byte {
+
+	key := uintIndexKey(timestamp, TimestampKeyPrefix)
+	key = append(key, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], height)
+
+	return key
+}
+
+// Build the key for a string index
+func stringIndexKey(key string, prefix byte) []byte {
+
+	return append([]byte{prefix}, []byte(key)...)
+}
+
+// Build the prefix shared by all the keys of a value (a ticker or an address) in a string index. The value ends
+// with a zero byte, so the prefix of a value never matches the keys of a longer one (BTC and BTCUSD)
+func stringHeightPrefix(value string, prefix byte) []byte {
+
+	index := make([]byte, 0, len(value)+2)
+	index = append(index, prefix)
+	index = append(index, value...)
+
+	return append(index, 0)
+}
+
+// Build the key of a block in a string index. The height is big endian encoded, so the blocks of each value
+// sort by height
+func stringHeightKey(value string, height uint64, prefix byte) []byte {
+
+	key := stringHeightPrefix(value, prefix)
+	key = append(key, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], height)
+
+	return key
+}
+
+// Write a full block and their indexes. The set function can be the one of a transaction or of a write batch
+func writeBlock(set func(key, value []byte) error, block types.FullSignedBlock, compress bool) error {
+
+	// Serialize all the parts: block in MessagePack, compressed if asked
+	bytes, err := encodeBlock(block, compress)
+	if err != nil {
+		return err
+	}
+
+	// Store the hash as a key. This is the main register
+	if err = set(stringIndexKey(block.Hash, HashKeyPrefix), bytes); err != nil {
+		return err
+	}
+
+	// And now store the indexes. Using this indexes it is possible to retrieve the hash, and next the block
+	if err = set(timestampHeightKey(block.Timestamp, block.Height), []byte(block.Hash)); err != nil { // By timestamp
+		return err
+	}
+
+	if err = set(uintIndexKey(block.Height, HeightKeyPrefix), []byte(block.Hash)); err != nil { // By block Height
+		return err
+	}
+
+	if err = set(stringHeightKey(block.Ticker, block.Height, TickerKeyPrefix), []byte(block.Hash)); err != nil { // By ticker
+		return err
+	}
+
+	return set(stringHeightKey(block.Address, block.Height, AddressKeyPrefix), []byte(block.Hash)) // By producing node
+}
+
+// Store a full block in the database. The block will be indexed by their timestamp and Height. Storing the same
+// block again does nothing, and a different block at the same height or with the same hash is a *ConflictError
+func (s Store) StoreBlock(block types.FullSignedBlock) error {
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+
 	// Open badger
-	stor, err := badger.Open(badger.DefaultOptions(s.StorFileLocation))
+	stor, err := s.open()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	defer stor.Close()
+	defer s.release(stor)
 
-	var block types.FullSignedBlock
-	err = stor.Update(func(txn *badger.Txn) error {
-		bytes, err := readStringIndex (txn, hash, HashKeyPrefix)
-		if err != nil{
+	if err = s.checkQuota(stor); err != nil {
+		return err
+	}
+
+	return stor.Update(func(txn *badger.Txn) error {
+		r, set := s.reader(txn), s.namespace.setter(txn.Set)
+		if stored, err := orderedCheckBlock(r, block); err != nil || stored {
 			return err
 		}
-		err = json.Unmarshal(bytes, &block)
 
-		return err
-	})
+		if err := writeBlock(set, block, s.config.CompressBlocks); err != nil {
+			return err
+		}
 
-	return &block, err
+		return orderedUpdateHead(r, set, []types.FullSignedBlock{block})
+	})
 }
 
-// Read a block from the database using their timestamp as index
-func (s Store) FindBlockByTimestamp (timestamp uint64) (*types.FullSignedBlock, error) {
+// StoreBlocks stores many blocks at once using a write batch. It is intended for imports, syncs and replays,
+// where writing a transaction for each block is too slow. The blocks are indexed in the same way than in StoreBlock,
+// but the stored blocks are not checked: they are replaced, as the pruner does to remove their evidence
+func (s Store) StoreBlocks(blocks []types.FullSignedBlock) error {
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+
 	// Open badger
-	stor, err := badger.Open(badger.DefaultOptions(s.StorFileLocation))
+	stor, err := s.open()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	defer stor.Close()
+	defer s.release(stor)
 
-	var block types.FullSignedBlock
-	err = stor.Update(func(txn *badger.Txn) error {
-		bytes, err := readUIntIndex (txn, timestamp, TimestampKeyPrefix)
-		if err != nil{
+	if err = s.checkQuota(stor); err != nil {
+		return err
+	}
+
+	wb := stor.NewWriteBatch()
+	defer wb.Cancel()
+
+	set := s.namespace.setter(wb.Set)
+	for _, block := range blocks {
+		if err = writeBlock(set, block, s.config.CompressBlocks); err != nil {
 			return err
 		}
-		err = json.Unmarshal(bytes, &block)
+	}
+
+	// The batch can´t read, so the current head is read from a transaction
+	err = stor.View(func(txn *badger.Txn) error {
+		return orderedUpdateHead(s.reader(txn), set, blocks)
+	})
+	if err != nil {
+		return err
+	}
+
+	return wb.Flush()
+}
+
+// Read a block from the database using their hash
+func (s Store) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var block *types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		block, err = orderedReadBlock(s.reader(txn), hash)
 
 		return err
 	})
 
-	return &block, err
+	return block, err
 }
 
+// Read a block from the database using their timestamp as index. If many blocks were created in the same second,
+// the newest one
+func (s Store) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var block *types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		block, err = orderedReadBlockByTimestamp(s.reader(txn), timestamp)
+
+		return err
+	})
+
+	return block, err
+}
 
 // Read a block from the database using their timestamp as index
-func (s Store) FindBlockByHeight (Height uint64) (*types.FullSignedBlock, error) {
+func (s Store) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
 	// Open badger
-	stor, err := badger.Open(badger.DefaultOptions(s.StorFileLocation))
+	stor, err := s.open()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	defer stor.Close()
+	defer s.release(stor)
 
-	var block types.FullSignedBlock
-	err = stor.Update(func(txn *badger.Txn) error {
-		bytes, err := readUIntIndex (txn, Height, HeightKeyPrefix)
-		if err != nil{
-			return err
-		}
-		err = json.Unmarshal(bytes, &block)
+	var block *types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		block, err = orderedReadBlockByIndex(s.reader(txn), Height, HeightKeyPrefix)
 
 		return err
 	})
 
-	return &block, err
+	return block, err
 }
 
+// StoreValue stores an abritrary value in the database, indexed by a string
+func (s Store) StoreValue(key string, value []byte) error {
 
-// StoreValue stores an abritrary value in the database, indexed by a string 
-func (s Store) StoreValue (key string, value []byte) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
 
 	// Open badger
-	stor, err := badger.Open(badger.DefaultOptions(s.StorFileLocation))
+	stor, err := s.open()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	defer stor.Close()
+	defer s.release(stor)
+
+	if err = s.checkQuota(stor); err != nil {
+		return err
+	}
 
 	err = stor.Update(func(txn *badger.Txn) error {
-		return storeStringIndex (txn, key, value, FixedKeyPrefix);
+		return s.namespace.setter(txn.Set)(stringIndexKey(key, FixedKeyPrefix), value)
 	})
 
 	return err
 }
 
 // GetValue returns a value stored in the database indexed by an string
-func (s *Store) GetValue (key string) ([]byte, error) {
+func (s *Store) GetValue(key string) ([]byte, error) {
 
 	// Open badger
-	stor, err := badger.Open(badger.DefaultOptions(s.StorFileLocation))
+	stor, err := s.open()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	defer stor.Close()
+	defer s.release(stor)
 
 	var bytes []byte
-	err = stor.Update(func(txn *badger.Txn) error {
-		bytes, err = readStringIndex (txn, key, FixedKeyPrefix);
+	err = stor.View(func(txn *badger.Txn) error {
+		bytes, err = s.reader(txn).get(stringIndexKey(key, FixedKeyPrefix))
 
 		return err
 	})
 
 	return bytes, err
 }
+
+// GetLatestBlocks returns the blocks created at or before the timestamp, the newest block first. The
+// first offset blocks are skipped and no more than limit blocks are returned (all of them if limit is zero), so
+// the results can be paged
+func (s Store) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var blocks []types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		blocks, err = orderedLatestBlocks(s.reader(txn), timestamp, offset, limit)
+
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetBlocksByHeightRange returns the contiguous segment of the chain between the heights from and to, both included
+func (s Store) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var blocks []types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		blocks, err = orderedBlocksInRange(s.reader(txn), from, to, 0, HeightKeyPrefix)
+
+		return err
+	})
+
+	return blocks, err
+}
+
+// FindBlocksBetween returns the blocks created in the time window between startTs and endTs, both included,
+// the oldest first. No more than limit blocks are returned, unless limit is zero
+func (s Store) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var blocks []types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		blocks, err = orderedBlocksBetween(s.reader(txn), startTs, endTs, limit)
+
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetHead returns the block at the tip of the chain, the one with the greatest height
+func (s Store) GetHead() (*types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var block *types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		block, err = orderedHead(s.reader(txn))
+
+		return err
+	})
+
+	return block, err
+}
+
+// GetLatestHeight returns the height of the tip of the chain, without reading the block
+func (s Store) GetLatestHeight() (uint64, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.release(stor)
+
+	var height uint64
+	err = stor.View(func(txn *badger.Txn) error {
+		height, _, err = orderedReadHead(s.reader(txn))
+		return err
+	})
+
+	return height, err
+}
+
+// GetBlocksByTicker returns the blocks of a ticker (e.g. BTCUSD), the newest first. No more than limit blocks
+// are returned, unless limit is zero
+func (s Store) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var blocks []types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		blocks, err = orderedBlocksByString(s.reader(txn), ticker, limit, TickerKeyPrefix)
+
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetBlocksByAddress returns the blocks produced by the node with the address, the newest first. No more than
+// limit blocks are returned, unless limit is zero
+func (s Store) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var blocks []types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		blocks, err = orderedBlocksByString(s.reader(txn), address, limit, AddressKeyPrefix)
+
+		return err
+	})
+
+	return blocks, err
+}
+
+// FindBlocksByHashPrefix returns the blocks whose hash starts with the prefix, sorted by hash, so the short hashes
+// typed by the users can be resolved. No more than limit blocks are returned, unless limit is zero
+func (s Store) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var blocks []types.FullSignedBlock
+	err = stor.View(func(txn *badger.Txn) error {
+		blocks, err = orderedBlocksByHashPrefix(s.reader(txn), prefix, limit)
+
+		return err
+	})
+
+	return blocks, err
+}
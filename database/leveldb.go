@@ -0,0 +1,373 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// LevelDBBackend is the name of the backend over a goleveldb directory
+const LevelDBBackend = "leveldb"
+
+// LevelDBStore implements the KVStore interface over goleveldb. The keys keep the same layout and prefixes than in
+// the Badger store, so the data can be moved between both engines copying the pairs as they are
+type LevelDBStore struct {
+	db        *leveldb.DB
+	namespace namespace // Prepended to the keys of the chain. Empty for the default namespace
+
+	// The writes of blocks read the head before writing their batch, so they can´t run at the same time. The lock
+	// is shared by all the namespaces of the database
+	writeLock *sync.Mutex
+}
+
+// Implements orderedReader over a snapshot of the database
+type levelDBReader struct {
+	snap *leveldb.Snapshot
+}
+
+// NewLevelDBStore opens (or creates) the goleveldb database in the directory
+func NewLevelDBStore(locationDirectory string) (*LevelDBStore, error) {
+	db, err := leveldb.OpenFile(locationDirectory, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &LevelDBStore{db: db, writeLock: new(sync.Mutex)}
+	if err = runMigrations(store, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Close releases the database
+func (l *LevelDBStore) Close() error {
+	return l.db.Close()
+}
+
+// Health returns an error if the database can´t be read
+func (l *LevelDBStore) Health() error {
+	_, err := l.db.GetProperty("leveldb.stats")
+	return err
+}
+
+func (r levelDBReader) get(key []byte) ([]byte, error) {
+	value, err := r.snap.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, types.ErrNotFound
+	}
+
+	return value, err
+}
+
+func (r levelDBReader) scan(prefix []byte, start []byte, reverse bool, fn func(key, value []byte) (bool, error)) error {
+	it := r.snap.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	// Seek moves to the first key greater or equal, so in reverse it may need to step back one key
+	valid := it.Seek(start)
+	if reverse {
+		if !valid {
+			valid = it.Last()
+		} else if !bytes.Equal(it.Key(), start) {
+			valid = it.Prev()
+		}
+	}
+
+	for valid {
+		next, err := fn(it.Key(), it.Value())
+		if err != nil || !next {
+			return err
+		}
+
+		if reverse {
+			valid = it.Prev()
+		} else {
+			valid = it.Next()
+		}
+	}
+
+	return it.Error()
+}
+
+// Namespace returns a store for the chain of a namespace, sharing the database with this one. The namespaces work
+// in the same way than in the Badger store
+func (l *LevelDBStore) Namespace(name string) (*LevelDBStore, error) {
+	ns, err := newNamespace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LevelDBStore{db: l.db, namespace: ns, writeLock: l.writeLock}, nil
+}
+
+// Read the keys of the namespace from a consistent snapshot of the database
+func (l *LevelDBStore) view(fn func(r orderedReader) error) error {
+	snap, err := l.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	return fn(l.namespace.reader(levelDBReader{snap: snap}))
+}
+
+// Write all the pairs set by fn in a single atomic batch. The reader is a snapshot taken once the previous
+// writes have finished
+func (l *LevelDBStore) update(fn func(r orderedReader, set func(key, value []byte) error) error) error {
+	l.writeLock.Lock()
+	defer l.writeLock.Unlock()
+
+	batch := new(leveldb.Batch)
+	err := l.view(func(r orderedReader) error {
+		return fn(r, l.namespace.setter(func(key, value []byte) error {
+			batch.Put(key, value)
+			return nil
+		}))
+	})
+	if err != nil {
+		return err
+	}
+
+	return l.db.Write(batch, nil)
+}
+
+// StoreValue stores an abritrary value, indexed by a string
+func (l *LevelDBStore) StoreValue(key string, value []byte) error {
+	return l.db.Put(l.namespace.key(stringIndexKey(key, FixedKeyPrefix)), value, nil)
+}
+
+// GetValue returns a value indexed by an string
+func (l *LevelDBStore) GetValue(key string) ([]byte, error) {
+	var value []byte
+	err := l.view(func(r orderedReader) (err error) {
+		value, err = r.get(stringIndexKey(key, FixedKeyPrefix))
+		return err
+	})
+
+	return value, err
+}
+
+// StoreBlock stores a full block indexed by their timestamp and Height. Storing the same block again does
+// nothing, and a different block at the same height or with the same hash is a *ConflictError
+func (l *LevelDBStore) StoreBlock(block types.FullSignedBlock) error {
+	return l.update(func(r orderedReader, set func(key, value []byte) error) error {
+		if stored, err := orderedCheckBlock(r, block); err != nil || stored {
+			return err
+		}
+
+		if err := writeBlock(set, block, false); err != nil {
+			return err
+		}
+
+		return orderedUpdateHead(r, set, []types.FullSignedBlock{block})
+	})
+}
+
+// StoreBlocks stores many blocks in a single batch
+func (l *LevelDBStore) StoreBlocks(blocks []types.FullSignedBlock) error {
+	return l.update(func(r orderedReader, set func(key, value []byte) error) error {
+		for _, block := range blocks {
+			if err := writeBlock(set, block, false); err != nil {
+				return err
+			}
+		}
+
+		return orderedUpdateHead(r, set, blocks)
+	})
+}
+
+// GetBlock reads a block using their hash
+func (l *LevelDBStore) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		block, err = orderedReadBlock(r, hash)
+		return err
+	})
+
+	return block, err
+}
+
+// FindBlockByTimestamp reads a block using their timestamp as index. If many blocks were created in the same
+// second, the newest one
+func (l *LevelDBStore) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		block, err = orderedReadBlockByTimestamp(r, timestamp)
+		return err
+	})
+
+	return block, err
+}
+
+// FindBlockByHeight reads a block using their height as index
+func (l *LevelDBStore) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		block, err = orderedReadBlockByIndex(r, Height, HeightKeyPrefix)
+		return err
+	})
+
+	return block, err
+}
+
+// GetLatestBlocks returns the blocks created at or before the timestamp, the newest block first, skipping the
+// first offset blocks and returning no more than limit blocks, unless limit is zero
+func (l *LevelDBStore) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		blocks, err = orderedLatestBlocks(r, timestamp, offset, limit)
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetBlocksByHeightRange returns the blocks between the heights from and to, both included
+func (l *LevelDBStore) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksInRange(r, from, to, 0, HeightKeyPrefix)
+		return err
+	})
+
+	return blocks, err
+}
+
+// FindBlocksBetween returns the blocks created between startTs and endTs, both included, the oldest first.
+// No more than limit blocks are returned, unless limit is zero
+func (l *LevelDBStore) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksBetween(r, startTs, endTs, limit)
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetHead returns the block at the tip of the chain, the one with the greatest height
+func (l *LevelDBStore) GetHead() (*types.FullSignedBlock, error) {
+	var block *types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		block, err = orderedHead(r)
+		return err
+	})
+
+	return block, err
+}
+
+// GetLatestHeight returns the height of the tip of the chain, without reading the block
+func (l *LevelDBStore) GetLatestHeight() (uint64, error) {
+	var height uint64
+	err := l.view(func(r orderedReader) (err error) {
+		height, _, err = orderedReadHead(r)
+		return err
+	})
+
+	return height, err
+}
+
+// GetBlocksByTicker returns the blocks of a ticker, the newest first. No more than limit blocks are returned,
+// unless limit is zero
+func (l *LevelDBStore) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksByString(r, ticker, limit, TickerKeyPrefix)
+		return err
+	})
+
+	return blocks, err
+}
+
+// GetBlocksByAddress returns the blocks produced by the node with the address, the newest first. No more than
+// limit blocks are returned, unless limit is zero
+func (l *LevelDBStore) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksByString(r, address, limit, AddressKeyPrefix)
+		return err
+	})
+
+	return blocks, err
+}
+
+// SchemaVersion returns the version of the schema of the stored data, the same for all the namespaces
+func (l *LevelDBStore) SchemaVersion() (int, error) {
+	root := &LevelDBStore{db: l.db, writeLock: l.writeLock}
+
+	var version int
+	err := root.view(func(r orderedReader) (err error) {
+		version, err = orderedSchemaVersion(r)
+		return err
+	})
+
+	return version, err
+}
+
+func (l *LevelDBStore) schemaVersion() (int, error) {
+	return l.SchemaVersion()
+}
+
+// The migration and their version are written in a single batch
+func (l *LevelDBStore) apply(m migration) error {
+	l.writeLock.Lock()
+	defer l.writeLock.Unlock()
+
+	batch := new(leveldb.Batch)
+	err := l.view(func(r orderedReader) error {
+		return m.migrate(r, func(key, value []byte) error {
+			batch.Put(key, value)
+			return nil
+		}, func(key []byte) error {
+			batch.Delete(key)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	batch.Put(schemaKey, encodeSchemaVersion(m.version))
+
+	return l.db.Write(batch, nil)
+}
+
+// VerifyChain walks the blocks between the heights from and to, both included, and reports the broken links, the
+// invalid hashes, the gaps and the inconsistent indexes
+func (l *LevelDBStore) VerifyChain(from uint64, to uint64) (*ChainReport, error) {
+	var report *ChainReport
+	err := l.view(func(r orderedReader) (err error) {
+		report, err = orderedVerifyChain(r, from, to)
+		return err
+	})
+
+	return report, err
+}
+
+// FindBlocksByHashPrefix returns the blocks whose hash starts with the prefix, sorted by hash. No more than limit
+// blocks are returned, unless limit is zero
+func (l *LevelDBStore) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	err := l.view(func(r orderedReader) (err error) {
+		blocks, err = orderedBlocksByHashPrefix(r, prefix, limit)
+		return err
+	})
+
+	return blocks, err
+}
+
+func init() {
+	RegisterBackend(LevelDBBackend, func(location string) (types.KVStore, error) {
+		store, err := NewLevelDBStore(location)
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	})
+}
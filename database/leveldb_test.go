@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestLevelDBStoreRoundTrip(t *testing.T) {
+	store, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	testStoreRoundTrip(t, store)
+}
+
+// The blocks are read from the directory once it is opened again, through the registry
+func TestLevelDBStoreReopen(t *testing.T) {
+	location := t.TempDir()
+	store, err := NewLevelDBStore(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := testChain(t, 3, 1600000000)
+	if err = store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenKVStore(LevelDBBackend, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.(*LevelDBStore).Close()
+
+	found, err := reopened.GetBlocksByHeightRange(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := heightsOf(found); !equalHeights(got, []uint64{0, 1, 2}) {
+		t.Errorf("the heights %v were read after opening the directory again, expected [0 1 2]", got)
+	}
+}
@@ -0,0 +1,303 @@
+package database
+
+import (
+	"container/list"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// DefaultLRUCacheSize is how many blocks the LRU cache keeps, if the size is not set
+const DefaultLRUCacheSize = 1024
+
+// LRUCache implements the KVStore interface keeping in memory the blocks most recently read or written, to answer
+// GetBlock and FindBlockByHeight without reading and decoding the block again. It is a write-through cache: the writes
+// go first to the backing store and the cache only keeps the blocks that were stored. All the other calls go to the
+// backing store
+type LRUCache struct {
+	backing types.KVStore
+	size    int
+
+	lock    sync.Mutex
+	order   *list.List               // The newest first. Each element holds a *types.FullSignedBlock
+	hashes  map[string]*list.Element // The elements by the hash of their block
+	heights map[uint64]string        // The hash of the cached block at each height
+}
+
+// NewLRUCache puts a cache of size blocks in front of the backing store. DefaultLRUCacheSize if size is zero
+func NewLRUCache(backing types.KVStore, size int) *LRUCache {
+	if size <= 0 {
+		size = DefaultLRUCacheSize
+	}
+
+	return &LRUCache{
+		backing: backing,
+		size:    size,
+		order:   list.New(),
+		hashes:  make(map[string]*list.Element),
+		heights: make(map[uint64]string),
+	}
+}
+
+// Close closes the backing store
+func (c *LRUCache) Close() error {
+	return CloseStore(c.backing)
+}
+
+// Health checks the health of the backing store
+func (c *LRUCache) Health() error {
+	return StoreHealth(c.backing)
+}
+
+// DiskUsage returns the disk usage of the backing store
+func (c *LRUCache) DiskUsage() (int64, error) {
+	return StoreDiskUsage(c.backing)
+}
+
+// Repair repairs the backing store. The indexes may change, so the cached blocks are dropped
+func (c *LRUCache) Repair(dryRun bool) (*RepairReport, error) {
+	report, err := RepairStore(c.backing, dryRun)
+	if !dryRun {
+		c.clear()
+	}
+
+	return report, err
+}
+
+// Subscribe subscribes to the blocks written to the backing store
+func (c *LRUCache) Subscribe(prefix string) (*Subscription, error) {
+	return SubscribeStore(c.backing, prefix)
+}
+
+// Backup writes a backup of the backing store
+func (c *LRUCache) Backup(w io.Writer, since uint64) (uint64, error) {
+	return BackupStore(c.backing, w, since)
+}
+
+// Restore loads a backup in the backing store. The restored pairs replace the stored ones, so the cached blocks are
+// dropped
+func (c *LRUCache) Restore(r io.Reader) error {
+	err := RestoreStore(c.backing, r)
+	c.clear()
+
+	return err
+}
+
+// Export exports the blocks of the backing store
+func (c *LRUCache) Export(w io.Writer, format ExportFormat) error {
+	return ExportStore(c.backing, w, format)
+}
+
+// Import imports the exported blocks in the backing store, and drops the cached blocks
+func (c *LRUCache) Import(r io.Reader) (int, error) {
+	imported, err := ImportStore(c.backing, r)
+	c.clear()
+
+	return imported, err
+}
+
+// GC runs the garbage collection of the backing store
+func (c *LRUCache) GC() (int, error) {
+	return CollectStore(c.backing)
+}
+
+// VerifyChain verifies the chain of the backing store, not the cached blocks
+func (c *LRUCache) VerifyChain(from uint64, to uint64) (*ChainReport, error) {
+	return VerifyStoreChain(c.backing, from, to)
+}
+
+// Drop all the cached blocks, so the next reads go to the backing store
+func (c *LRUCache) clear() {
+	c.lock.Lock()
+	c.order.Init()
+	c.hashes = make(map[string]*list.Element)
+	c.heights = make(map[uint64]string)
+	c.lock.Unlock()
+}
+
+// Txn runs the transaction in the backing store, and caches the blocks it wrote once it is committed
+func (c *LRUCache) Txn(fn func(tx StoreTxn) error) error {
+	recorded, err := recordTxn(c.backing, fn)
+	if err != nil {
+		return err
+	}
+	for i := range recorded.blocks {
+		c.put(&recorded.blocks[i])
+	}
+
+	return nil
+}
+
+// Copy a block, so the callers can´t change the cached one
+func cloneBlock(block *types.FullSignedBlock) *types.FullSignedBlock {
+	clone := block.Clone()
+	return &clone
+}
+
+// Add or refresh a block, removing the oldest ones over the size
+func (c *LRUCache) put(block *types.FullSignedBlock) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, exists := c.hashes[block.Hash]; exists {
+		c.removeElement(element)
+	}
+	if hash, exists := c.heights[block.Height]; exists {
+		c.removeElement(c.hashes[hash])
+	}
+
+	c.hashes[block.Hash] = c.order.PushFront(cloneBlock(block))
+	c.heights[block.Height] = block.Hash
+
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Remove a cached block. The lock must be held
+func (c *LRUCache) removeElement(element *list.Element) {
+	block := c.order.Remove(element).(*types.FullSignedBlock)
+	delete(c.hashes, block.Hash)
+	if c.heights[block.Height] == block.Hash {
+		delete(c.heights, block.Height)
+	}
+}
+
+// Read a cached block, marking it as the newest
+func (c *LRUCache) get(hash string) (*types.FullSignedBlock, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, exists := c.hashes[hash]
+	if !exists {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	return cloneBlock(element.Value.(*types.FullSignedBlock)), true
+}
+
+// Read the cached block at a height
+func (c *LRUCache) getByHeight(height uint64) (*types.FullSignedBlock, bool) {
+	c.lock.Lock()
+	hash, exists := c.heights[height]
+	c.lock.Unlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	return c.get(hash)
+}
+
+// StoreValue stores the value in the backing store
+func (c *LRUCache) StoreValue(key string, value []byte) error {
+	return c.backing.StoreValue(key, value)
+}
+
+// StoreValueWithTTL stores the value with a TTL in the backing store
+func (c *LRUCache) StoreValueWithTTL(key string, value []byte, ttl time.Duration) error {
+	return StoreValueWithTTL(c.backing, key, value, ttl)
+}
+
+// GetValue reads the value from the backing store
+func (c *LRUCache) GetValue(key string) ([]byte, error) {
+	return c.backing.GetValue(key)
+}
+
+// StoreBlock stores the block in the backing store and in the cache
+func (c *LRUCache) StoreBlock(block types.FullSignedBlock) error {
+	if err := c.backing.StoreBlock(block); err != nil {
+		return err
+	}
+	c.put(&block)
+
+	return nil
+}
+
+// StoreBlocks stores the blocks in the backing store and in the cache
+func (c *LRUCache) StoreBlocks(blocks []types.FullSignedBlock) error {
+	if err := c.backing.StoreBlocks(blocks); err != nil {
+		return err
+	}
+	for i := range blocks {
+		c.put(&blocks[i])
+	}
+
+	return nil
+}
+
+// GetBlock reads a block from the cache, or from the backing store if missing
+func (c *LRUCache) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	if block, found := c.get(hash); found {
+		return block, nil
+	}
+
+	block, err := c.backing.GetBlock(hash)
+	if err == nil {
+		c.put(block)
+	}
+
+	return block, err
+}
+
+// FindBlockByTimestamp reads the block from the backing store
+func (c *LRUCache) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	return c.backing.FindBlockByTimestamp(timestamp)
+}
+
+// FindBlockByHeight reads a block from the cache, or from the backing store if missing
+func (c *LRUCache) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	if block, found := c.getByHeight(Height); found {
+		return block, nil
+	}
+
+	block, err := c.backing.FindBlockByHeight(Height)
+	if err == nil {
+		c.put(block)
+	}
+
+	return block, err
+}
+
+// GetLatestBlocks reads the blocks from the backing store
+func (c *LRUCache) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.GetLatestBlocks(timestamp, offset, limit)
+}
+
+// GetBlocksByHeightRange reads the blocks from the backing store
+func (c *LRUCache) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	return c.backing.GetBlocksByHeightRange(from, to)
+}
+
+// FindBlocksBetween reads the blocks from the backing store
+func (c *LRUCache) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.FindBlocksBetween(startTs, endTs, limit)
+}
+
+// GetHead reads the head from the backing store
+func (c *LRUCache) GetHead() (*types.FullSignedBlock, error) {
+	return c.backing.GetHead()
+}
+
+// GetLatestHeight reads the height of the head from the backing store
+func (c *LRUCache) GetLatestHeight() (uint64, error) {
+	return c.backing.GetLatestHeight()
+}
+
+// GetBlocksByTicker reads the blocks of a ticker from the backing store
+func (c *LRUCache) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.GetBlocksByTicker(ticker, limit)
+}
+
+// GetBlocksByAddress reads the blocks of a producing node from the backing store
+func (c *LRUCache) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.GetBlocksByAddress(address, limit)
+}
+
+// FindBlocksByHashPrefix searches the blocks in the backing store
+func (c *LRUCache) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.FindBlocksByHashPrefix(prefix, limit)
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A memory store counting the block reads that reach it
+type countingStore struct {
+	*MemoryStore
+	reads int
+}
+
+func (s *countingStore) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	s.reads++
+	return s.MemoryStore.GetBlock(hash)
+}
+
+func (s *countingStore) FindBlockByHeight(height uint64) (*types.FullSignedBlock, error) {
+	s.reads++
+	return s.MemoryStore.FindBlockByHeight(height)
+}
+
+func TestLRUCacheRoundTrip(t *testing.T) {
+	testStoreRoundTrip(t, NewLRUCache(NewMemoryStore(), 2))
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	backing := &countingStore{MemoryStore: NewMemoryStore()}
+	cache := NewLRUCache(backing, 2)
+	blocks := testChain(t, 4, 1600000000)
+	if err := cache.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the heights 2 and 3 are cached after the writes. Each step reads the height and counts the reads of the
+	// backing store so far
+	tests := []struct {
+		height uint64
+		reads  int
+	}{
+		{3, 0},
+		{2, 0},
+		{0, 1}, // Evicts the height 3
+		{2, 1},
+		{3, 2}, // Evicts the height 0
+		{0, 3},
+		{0, 3},
+	}
+	for _, test := range tests {
+		block, err := cache.FindBlockByHeight(test.height)
+		if err != nil || block.Hash != blocks[test.height].Hash {
+			t.Fatalf("FindBlockByHeight(%d) returned %v (%v)", test.height, block, err)
+		}
+		if backing.reads != test.reads {
+			t.Errorf("after reading the height %d the store was read %d times, expected %d", test.height, backing.reads, test.reads)
+		}
+	}
+
+	if _, err := cache.GetBlock(blocks[0].Hash); err != nil || backing.reads != 3 {
+		t.Errorf("GetBlock of a cached block read the store %d times (%v)", backing.reads, err)
+	}
+	if _, err := cache.GetBlock("unknown"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("GetBlock of an unknown hash returned %v", err)
+	}
+}
+
+// A block rewritten with the same hash, as the pruner does, replaces the cached one
+func TestLRUCacheRewrite(t *testing.T) {
+	cache := NewLRUCache(NewMemoryStore(), 2)
+	block := testChain(t, 1, 1600000000)[0]
+	block.Evidence = []types.Result{{CrawlerName: "binance", Ticker: "BTCUSD"}}
+	if err := cache.StoreBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned := block
+	pruned.Evidence = nil
+	if err := cache.StoreBlocks([]types.FullSignedBlock{pruned}); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := cache.GetBlock(block.Hash); err != nil || len(found.Evidence) != 0 {
+		t.Errorf("the cached block after the rewrite is %v (%v)", found, err)
+	}
+
+	// A rejected block is not cached
+	other := block
+	other.Memo = "other"
+	if err := cache.StoreBlock(other); err == nil {
+		t.Error("storing a changed block with a stored hash didn´t fail")
+	}
+	if found, err := cache.FindBlockByHeight(0); err != nil || found.Memo != "" {
+		t.Errorf("the cached block after a conflict is %v (%v)", found, err)
+	}
+}
+
+func TestLRUCacheCopies(t *testing.T) {
+	cache := NewLRUCache(NewMemoryStore(), 2)
+	block := testChain(t, 1, 1600000000)[0]
+	block.Evidence = []types.Result{{CrawlerName: "binance", Ticker: "BTCUSD"}}
+	if err := cache.StoreBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	block.Evidence[0].CrawlerName = "changed"
+
+	found, err := cache.GetBlock(block.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.Evidence[0].CrawlerName = "changed"
+	found.Ticker = "ETHUSD"
+
+	if again, _ := cache.GetBlock(block.Hash); again.Ticker != "BTCUSD" || again.Evidence[0].CrawlerName != "binance" {
+		t.Errorf("the cached block changed to %v", again)
+	}
+}
+
+// The wrappers forward the maintenance of the store behind them, and drop their cached blocks when a restore
+// replaces the stored ones
+func TestWrappersForward(t *testing.T) {
+	metrics, err := NewStoreMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	redisCache := func(t *testing.T, backing types.KVStore) types.KVStore {
+		server, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(server.Close)
+		cache, err := NewRedisCache(backing, RedisCacheConfig{Addr: server.Addr(), Prefix: "test:", NewestBlocks: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cache
+	}
+
+	tests := []struct {
+		name string
+		wrap func(t *testing.T, backing types.KVStore) types.KVStore
+	}{
+		{"lru", func(t *testing.T, backing types.KVStore) types.KVStore { return NewLRUCache(backing, 0) }},
+		{"metered", func(t *testing.T, backing types.KVStore) types.KVStore { return NewMeteredStore(backing, metrics) }},
+		{"redis", redisCache},
+		{"lru over metered", func(t *testing.T, backing types.KVStore) types.KVStore {
+			return NewLRUCache(NewMeteredStore(backing, metrics), 0)
+		}},
+	}
+	for _, test := range tests {
+		blocks := testChain(t, 5, 1600000000)
+		store := test.wrap(t, testOpenStore(t))
+		if err := store.StoreBlocks(blocks); err != nil {
+			t.Fatal(err)
+		}
+
+		sub, err := SubscribeStore(store, "")
+		if err != nil {
+			t.Errorf("%s: subscribing returned %v", test.name, err)
+		} else {
+			sub.Close()
+		}
+		if report, err := VerifyStoreChain(store, 0, 4); err != nil || !report.OK() {
+			t.Errorf("%s: the verification returned %v (%v)", test.name, report, err)
+		}
+		if _, err = CollectStore(store); err != nil {
+			t.Errorf("%s: the garbage collection returned %v", test.name, err)
+		}
+
+		var exported bytes.Buffer
+		if err = ExportStore(store, &exported, ExportJSONL); err != nil {
+			t.Errorf("%s: the export returned %v", test.name, err)
+		}
+		imported := test.wrap(t, testOpenStore(t))
+		if count, err := ImportStore(imported, &exported); err != nil || count != len(blocks) {
+			t.Errorf("%s: the import returned %d (%v)", test.name, count, err)
+		}
+
+		// Another chain restored over the cached one replaces their blocks
+		other := testChain(t, 5, 1700000000)
+		source := testOpenStore(t)
+		if err = source.StoreBlocks(other); err != nil {
+			t.Fatal(err)
+		}
+		var backup bytes.Buffer
+		if _, err = BackupStore(source, &backup, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = store.FindBlockByHeight(2); err != nil {
+			t.Fatal(err)
+		}
+		if err = RestoreStore(store, &backup); err != nil {
+			t.Errorf("%s: the restore returned %v", test.name, err)
+		}
+		if block, err := store.FindBlockByHeight(2); err != nil || block.Hash != other[2].Hash {
+			t.Errorf("%s: FindBlockByHeight(2) after the restore returned %v (%v), expected %s", test.name, block, err, other[2].Hash)
+		}
+	}
+
+	// The stores without the maintenance say it through the wrappers
+	store := NewLRUCache(NewMeteredStore(NewMemoryStore(), metrics), 0)
+	if _, err = SubscribeStore(store, ""); !errors.Is(err, ErrSubscribeUnsupported) {
+		t.Errorf("subscribing to a memory store returned %v", err)
+	}
+	if _, err = BackupStore(store, &bytes.Buffer{}, 0); !errors.Is(err, ErrBackupUnsupported) {
+		t.Errorf("backing up a memory store returned %v", err)
+	}
+	if err = ExportStore(store, &bytes.Buffer{}, ExportJSONL); !errors.Is(err, ErrExportUnsupported) {
+		t.Errorf("exporting a memory store returned %v", err)
+	}
+	if _, err = CollectStore(store); !errors.Is(err, ErrGCUnsupported) {
+		t.Errorf("collecting a memory store returned %v", err)
+	}
+	if _, err = VerifyStoreChain(store, 0, 4); !errors.Is(err, ErrVerifyUnsupported) {
+		t.Errorf("verifying a memory store returned %v", err)
+	}
+}
@@ -0,0 +1,421 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// An ordered index from an uint64 to the hash of a block
+type memoryIndex struct {
+	keys   []uint64 // Always sorted
+	hashes map[uint64]string
+}
+
+// An ordered index from the timestamp and the height of the blocks to their hash, so all the blocks created in the
+// same second are kept
+type memoryTimeIndex struct {
+	entries []memoryTimeEntry // Always sorted by timestamp and height
+}
+
+type memoryTimeEntry struct {
+	timestamp uint64
+	height    uint64
+	hash      string
+}
+
+// MemoryStore implements the KVStore interface keeping all the data in memory, for tests and ephemeral deployments.
+// The missing keys are reported with the same errors than the Badger store
+type MemoryStore struct {
+	lock sync.RWMutex
+
+	values     map[string][]byte
+	expiries   map[string]time.Time // When the values stored with a TTL expire
+	blocks     map[string][]byte    // Serialized, so the callers can´t change the stored blocks
+	timestamps memoryTimeIndex
+	heights    memoryIndex
+	tickers    map[string]*memoryIndex // The heights of the blocks of each ticker
+	addresses  map[string]*memoryIndex // The heights of the blocks of each producing node
+}
+
+// NewMemoryStore creates a new empty store in memory
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values:     make(map[string][]byte),
+		expiries:   make(map[string]time.Time),
+		blocks:     make(map[string][]byte),
+		timestamps: memoryTimeIndex{},
+		heights:    memoryIndex{hashes: make(map[uint64]string)},
+		tickers:    make(map[string]*memoryIndex),
+		addresses:  make(map[string]*memoryIndex),
+	}
+}
+
+// Position of the first key greater than the key
+func (idx *memoryIndex) after(key uint64) int {
+	return sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i] > key })
+}
+
+// Position of the first key greater or equal than the key
+func (idx *memoryIndex) from(key uint64) int {
+	return sort.Search(len(idx.keys), func(i int) bool { return idx.keys[i] >= key })
+}
+
+// Set the hash for a key, keeping the keys sorted
+func (idx *memoryIndex) set(key uint64, hash string) {
+	if _, exists := idx.hashes[key]; !exists {
+		pos := idx.from(key)
+		idx.keys = append(idx.keys, 0)
+		copy(idx.keys[pos+1:], idx.keys[pos:])
+		idx.keys[pos] = key
+	}
+	idx.hashes[key] = hash
+}
+
+// Position of the first entry with a timestamp greater than the timestamp
+func (idx *memoryTimeIndex) after(timestamp uint64) int {
+	return sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].timestamp > timestamp })
+}
+
+// Position of the first entry with a timestamp greater or equal than the timestamp
+func (idx *memoryTimeIndex) from(timestamp uint64) int {
+	return sort.Search(len(idx.entries), func(i int) bool { return idx.entries[i].timestamp >= timestamp })
+}
+
+// Set the hash for a timestamp and a height, keeping the entries sorted
+func (idx *memoryTimeIndex) set(timestamp uint64, height uint64, hash string) {
+	pos := sort.Search(len(idx.entries), func(i int) bool {
+		entry := idx.entries[i]
+		return entry.timestamp > timestamp || (entry.timestamp == timestamp && entry.height >= height)
+	})
+	if pos < len(idx.entries) && idx.entries[pos].timestamp == timestamp && idx.entries[pos].height == height {
+		idx.entries[pos].hash = hash
+		return
+	}
+
+	idx.entries = append(idx.entries, memoryTimeEntry{})
+	copy(idx.entries[pos+1:], idx.entries[pos:])
+	idx.entries[pos] = memoryTimeEntry{timestamp, height, hash}
+}
+
+// Write a full block and their indexes. The lock must be held
+func (m *MemoryStore) writeBlock(block types.FullSignedBlock) error {
+	bytes, err := encodeBlock(block, false)
+	if err != nil {
+		return err
+	}
+
+	m.blocks[block.Hash] = bytes
+	m.timestamps.set(block.Timestamp, block.Height, block.Hash)
+	m.heights.set(block.Height, block.Hash)
+
+	setStringIndex(m.tickers, block.Ticker, block)
+	setStringIndex(m.addresses, block.Address, block)
+
+	return nil
+}
+
+// Set the height of the block in the index of a value (a ticker or an address). The lock must be held
+func setStringIndex(indexes map[string]*memoryIndex, value string, block types.FullSignedBlock) {
+	idx, exists := indexes[value]
+	if !exists {
+		idx = &memoryIndex{hashes: make(map[uint64]string)}
+		indexes[value] = idx
+	}
+	idx.set(block.Height, block.Hash)
+}
+
+// Read a block using their hash. The lock must be held
+func (m *MemoryStore) readBlock(hash string) (*types.FullSignedBlock, error) {
+	bytes, exists := m.blocks[hash]
+	if !exists {
+		return nil, types.ErrNotFound
+	}
+
+	return decodeBlock(bytes)
+}
+
+// Read a block using an index. The lock must be held
+func (m *MemoryStore) readBlockByIndex(idx *memoryIndex, key uint64) (*types.FullSignedBlock, error) {
+	hash, exists := idx.hashes[key]
+	if !exists {
+		return nil, types.ErrNotFound
+	}
+
+	return m.readBlock(hash)
+}
+
+// Read the blocks in the positions of the index between start and end (not included). The lock must be held
+func (m *MemoryStore) readBlocks(idx *memoryIndex, start int, end int) ([]types.FullSignedBlock, error) {
+	var blocks []types.FullSignedBlock
+	for i := start; i < end; i++ {
+		block, err := m.readBlock(idx.hashes[idx.keys[i]])
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	return blocks, nil
+}
+
+// StoreValue stores an abritrary value, indexed by a string
+func (m *MemoryStore) StoreValue(key string, value []byte) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.values[key] = append([]byte(nil), value...)
+	delete(m.expiries, key)
+
+	return nil
+}
+
+// StoreValueWithTTL stores a value that expires after the ttl. The expired values are removed by the next write
+// with a TTL
+func (m *MemoryStore) StoreValueWithTTL(key string, value []byte, ttl time.Duration) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	now := time.Now()
+	for expiredKey, expiry := range m.expiries {
+		if !now.Before(expiry) {
+			delete(m.values, expiredKey)
+			delete(m.expiries, expiredKey)
+		}
+	}
+
+	m.values[key] = append([]byte(nil), value...)
+	m.expiries[key] = now.Add(ttl)
+
+	return nil
+}
+
+// GetValue returns a value indexed by an string
+func (m *MemoryStore) GetValue(key string) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	value, exists := m.values[key]
+	if expiry, expires := m.expiries[key]; !exists || (expires && !time.Now().Before(expiry)) {
+		return nil, types.ErrNotFound
+	}
+
+	return append([]byte(nil), value...), nil
+}
+
+// StoreBlock stores a full block indexed by their timestamp and Height. Storing the same block again does
+// nothing, and a different block at the same height or with the same hash is a *ConflictError
+func (m *MemoryStore) StoreBlock(block types.FullSignedBlock) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stored, err := m.readBlock(block.Hash)
+	if errors.Is(err, types.ErrNotFound) {
+		stored, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if exists, err := checkStoredBlock(block, m.heights.hashes[block.Height], stored); err != nil || exists {
+		return err
+	}
+
+	return m.writeBlock(block)
+}
+
+// StoreBlocks stores many blocks at once
+func (m *MemoryStore) StoreBlocks(blocks []types.FullSignedBlock) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, block := range blocks {
+		if err := m.writeBlock(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetBlock reads a block using their hash
+func (m *MemoryStore) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.readBlock(hash)
+}
+
+// FindBlockByTimestamp reads a block using their timestamp as index. If many blocks were created in the same
+// second, the newest one
+func (m *MemoryStore) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	i := m.timestamps.after(timestamp) - 1
+	if i < 0 || m.timestamps.entries[i].timestamp != timestamp {
+		return nil, types.ErrNotFound
+	}
+
+	return m.readBlock(m.timestamps.entries[i].hash)
+}
+
+// FindBlockByHeight reads a block using their height as index
+func (m *MemoryStore) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.readBlockByIndex(&m.heights, Height)
+}
+
+// GetLatestBlocks returns the blocks created at or before the timestamp, the newest block first, skipping the
+// first offset blocks and returning no more than limit blocks, unless limit is zero
+func (m *MemoryStore) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var blocks []types.FullSignedBlock
+	for i := m.timestamps.after(timestamp) - 1 - offset; i >= 0 && (limit == 0 || len(blocks) < limit); i-- {
+		block, err := m.readBlock(m.timestamps.entries[i].hash)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	return blocks, nil
+}
+
+// GetBlocksByHeightRange returns the blocks between the heights from and to, both included
+func (m *MemoryStore) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	start, end := m.heights.from(from), m.heights.after(to)
+	if end < start {
+		return nil, nil
+	}
+
+	return m.readBlocks(&m.heights, start, end)
+}
+
+// FindBlocksBetween returns the blocks created between startTs and endTs, both included, the oldest first.
+// No more than limit blocks are returned, unless limit is zero
+func (m *MemoryStore) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	start, end := m.timestamps.from(startTs), m.timestamps.after(endTs)
+	if end < start {
+		return nil, nil
+	}
+	if limit > 0 && end-start > limit {
+		end = start + limit
+	}
+
+	var blocks []types.FullSignedBlock
+	for _, entry := range m.timestamps.entries[start:end] {
+		block, err := m.readBlock(entry.hash)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	return blocks, nil
+}
+
+// GetHead returns the block at the tip of the chain, the one with the greatest height
+func (m *MemoryStore) GetHead() (*types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if len(m.heights.keys) == 0 {
+		return nil, types.ErrNotFound
+	}
+
+	return m.readBlockByIndex(&m.heights, m.heights.keys[len(m.heights.keys)-1])
+}
+
+// GetLatestHeight returns the height of the tip of the chain. The height index is sorted, so the head is their
+// last key
+func (m *MemoryStore) GetLatestHeight() (uint64, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if len(m.heights.keys) == 0 {
+		return 0, types.ErrNotFound
+	}
+
+	return m.heights.keys[len(m.heights.keys)-1], nil
+}
+
+// Read the blocks of a value of a string index, the newest first. The lock must be held
+func (m *MemoryStore) readBlocksByString(indexes map[string]*memoryIndex, value string, limit int) ([]types.FullSignedBlock, error) {
+	idx, exists := indexes[value]
+	if !exists {
+		return nil, nil
+	}
+
+	var blocks []types.FullSignedBlock
+	for i := len(idx.keys) - 1; i >= 0 && (limit == 0 || len(blocks) < limit); i-- {
+		block, err := m.readBlock(idx.hashes[idx.keys[i]])
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	return blocks, nil
+}
+
+// GetBlocksByTicker returns the blocks of a ticker, the newest first. No more than limit blocks are returned,
+// unless limit is zero
+func (m *MemoryStore) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.readBlocksByString(m.tickers, ticker, limit)
+}
+
+// GetBlocksByAddress returns the blocks produced by the node with the address, the newest first. No more than
+// limit blocks are returned, unless limit is zero
+func (m *MemoryStore) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.readBlocksByString(m.addresses, address, limit)
+}
+
+// FindBlocksByHashPrefix returns the blocks whose hash starts with the prefix, sorted by hash. No more than limit
+// blocks are returned, unless limit is zero
+func (m *MemoryStore) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	var hashes []string
+	for hash := range m.blocks {
+		if strings.HasPrefix(hash, prefix) {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Strings(hashes)
+	if limit > 0 && len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+
+	var blocks []types.FullSignedBlock
+	for _, hash := range hashes {
+		block, err := m.readBlock(hash)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, *block)
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,314 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// A chain of blocks, one per second from the timestamp
+func testChain(t *testing.T, length int, timestamp uint64) []types.FullSignedBlock {
+	var blocks []types.FullSignedBlock
+	previous := ""
+	for i := 0; i < length; i++ {
+		block := types.FullSignedBlock{
+			Height:       uint64(i),
+			Timestamp:    timestamp + uint64(i),
+			Ticker:       "BTCUSD",
+			Address:      "node",
+			PreviousHash: previous,
+			AveragePrice: float64(1000 + i),
+		}
+		if err := block.CreateHash(); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+		previous = block.Hash
+	}
+
+	return blocks
+}
+
+// The heights of the blocks, to compare the results of the queries
+func heightsOf(blocks []types.FullSignedBlock) []uint64 {
+	heights := make([]uint64, 0, len(blocks))
+	for _, block := range blocks {
+		heights = append(heights, block.Height)
+	}
+
+	return heights
+}
+
+func equalHeights(a []uint64, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Store a chain of five blocks and read them back with every query. Shared by the tests of all the backends
+func testStoreRoundTrip(t *testing.T, store types.KVStore) {
+	blocks := testChain(t, 5, 1600000000)
+	if _, err := store.GetHead(); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("GetHead of an empty store returned %v", err)
+	}
+	if err := store.StoreBlocks(blocks[:2]); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks[2:] {
+		if err := store.StoreBlock(block); err != nil {
+			t.Fatalf("can´t store the block at the height %d: %v", block.Height, err)
+		}
+	}
+
+	// Storing the same block again does nothing, and another block at a stored height or a changed block with a
+	// stored hash is a conflict that leaves the stored block
+	if err := store.StoreBlock(blocks[2]); err != nil {
+		t.Errorf("storing the same block again failed: %v", err)
+	}
+	other := blocks[2]
+	other.AveragePrice++
+	if err := other.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+	changed := blocks[2]
+	changed.Memo = "changed"
+	conflicts := []struct {
+		name       string
+		block      types.FullSignedBlock
+		storedHash string
+	}{
+		{"another block at the height", other, blocks[2].Hash},
+		{"a changed block with the hash", changed, blocks[2].Hash},
+	}
+	for _, test := range conflicts {
+		var conflict *ConflictError
+		if err := store.StoreBlock(test.block); !errors.As(err, &conflict) || conflict.StoredHash != test.storedHash {
+			t.Errorf("storing %s returned %v, expected a conflict with %s", test.name, err, test.storedHash)
+		}
+	}
+	if block, err := store.FindBlockByHeight(2); err != nil || block.Hash != blocks[2].Hash || block.Memo != "" {
+		t.Errorf("after the conflicts the height 2 holds %v (%v)", block, err)
+	}
+
+	heights := []struct {
+		height uint64
+		hash   string
+		err    error
+	}{
+		{0, blocks[0].Hash, nil},
+		{2, blocks[2].Hash, nil},
+		{4, blocks[4].Hash, nil},
+		{5, "", types.ErrNotFound},
+	}
+	for _, test := range heights {
+		block, err := store.FindBlockByHeight(test.height)
+		if !errors.Is(err, test.err) {
+			t.Errorf("FindBlockByHeight(%d) returned %v, expected %v", test.height, err, test.err)
+			continue
+		}
+		if err == nil && block.Hash != test.hash {
+			t.Errorf("FindBlockByHeight(%d) returned the block %s, expected %s", test.height, block.Hash, test.hash)
+		}
+	}
+
+	if block, err := store.GetBlock(blocks[3].Hash); err != nil || block.Height != 3 {
+		t.Errorf("GetBlock of the height 3 returned %v (%v)", block, err)
+	}
+	if block, err := store.FindBlockByTimestamp(blocks[1].Timestamp); err != nil || block.Height != 1 {
+		t.Errorf("FindBlockByTimestamp of the height 1 returned %v (%v)", block, err)
+	}
+	if _, err := store.GetBlock("unknown"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("GetBlock of an unknown hash returned %v", err)
+	}
+
+	latest := []struct {
+		name      string
+		timestamp uint64
+		offset    int
+		limit     int
+		heights   []uint64
+	}{
+		{"all", blocks[4].Timestamp, 0, 10, []uint64{4, 3, 2, 1, 0}},
+		{"no limit", blocks[4].Timestamp, 0, 0, []uint64{4, 3, 2, 1, 0}},
+		{"limit", blocks[4].Timestamp, 0, 2, []uint64{4, 3}},
+		{"offset", blocks[4].Timestamp, 1, 2, []uint64{3, 2}},
+		{"before the timestamp", blocks[2].Timestamp, 0, 10, []uint64{2, 1, 0}},
+		{"offset over the blocks", blocks[4].Timestamp, 5, 10, []uint64{}},
+		{"before the chain", blocks[0].Timestamp - 1, 0, 10, []uint64{}},
+	}
+	for _, test := range latest {
+		found, err := store.GetLatestBlocks(test.timestamp, test.offset, test.limit)
+		if err != nil {
+			t.Errorf("GetLatestBlocks %s: %v", test.name, err)
+			continue
+		}
+		if got := heightsOf(found); !equalHeights(got, test.heights) {
+			t.Errorf("GetLatestBlocks %s returned the heights %v, expected %v", test.name, got, test.heights)
+		}
+	}
+
+	ranges := []struct {
+		name    string
+		from    uint64
+		to      uint64
+		heights []uint64
+	}{
+		{"all", 0, 4, []uint64{0, 1, 2, 3, 4}},
+		{"inside", 1, 3, []uint64{1, 2, 3}},
+		{"one block", 2, 2, []uint64{2}},
+		{"over the head", 3, 10, []uint64{3, 4}},
+		{"reversed", 3, 1, []uint64{}},
+	}
+	for _, test := range ranges {
+		found, err := store.GetBlocksByHeightRange(test.from, test.to)
+		if err != nil {
+			t.Errorf("GetBlocksByHeightRange %s: %v", test.name, err)
+			continue
+		}
+		if got := heightsOf(found); !equalHeights(got, test.heights) {
+			t.Errorf("GetBlocksByHeightRange %s returned the heights %v, expected %v", test.name, got, test.heights)
+		}
+	}
+
+	between := []struct {
+		name    string
+		start   uint64
+		end     uint64
+		limit   int
+		heights []uint64
+	}{
+		{"all", blocks[0].Timestamp, blocks[4].Timestamp, 0, []uint64{0, 1, 2, 3, 4}},
+		{"window", blocks[1].Timestamp, blocks[2].Timestamp, 0, []uint64{1, 2}},
+		{"limit", blocks[1].Timestamp, blocks[4].Timestamp, 2, []uint64{1, 2}},
+		{"after the chain", blocks[4].Timestamp + 1, blocks[4].Timestamp + 10, 0, []uint64{}},
+	}
+	for _, test := range between {
+		found, err := store.FindBlocksBetween(test.start, test.end, test.limit)
+		if err != nil {
+			t.Errorf("FindBlocksBetween %s: %v", test.name, err)
+			continue
+		}
+		if got := heightsOf(found); !equalHeights(got, test.heights) {
+			t.Errorf("FindBlocksBetween %s returned the heights %v, expected %v", test.name, got, test.heights)
+		}
+	}
+
+	// Storing an older block again keeps the head
+	if err := store.StoreBlock(blocks[1]); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := store.GetHead(); err != nil || head.Hash != blocks[4].Hash {
+		t.Errorf("GetHead returned %v (%v), expected the block at the height 4", head, err)
+	}
+	if height, err := store.GetLatestHeight(); err != nil || height != 4 {
+		t.Errorf("GetLatestHeight returned %d (%v), expected 4", height, err)
+	}
+
+	// The blocks created in the same second are all indexed by their timestamp, the newest first
+	second := blocks[4].Timestamp + 10
+	previous := blocks[4].Hash
+	for height := uint64(5); height < 8; height++ {
+		block := types.FullSignedBlock{Height: height, Timestamp: second, Ticker: "BTCUSD", Address: "node", PreviousHash: previous}
+		if err := block.CreateHash(); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.StoreBlock(block); err != nil {
+			t.Fatal(err)
+		}
+		previous = block.Hash
+	}
+	if found, err := store.GetLatestBlocks(second, 0, 0); err != nil || !equalHeights(heightsOf(found), []uint64{7, 6, 5, 4, 3, 2, 1, 0}) {
+		t.Errorf("GetLatestBlocks of the same second returned the heights %v (%v)", heightsOf(found), err)
+	}
+	if found, err := store.FindBlocksBetween(second, second, 0); err != nil || !equalHeights(heightsOf(found), []uint64{5, 6, 7}) {
+		t.Errorf("FindBlocksBetween of the same second returned the heights %v (%v)", heightsOf(found), err)
+	}
+	if block, err := store.FindBlockByTimestamp(second); err != nil || block.Height != 7 {
+		t.Errorf("FindBlockByTimestamp of the same second returned %v (%v), expected the height 7", block, err)
+	}
+
+	if err := store.StoreValue("key", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.GetValue("key"); err != nil || string(value) != "value" {
+		t.Errorf("GetValue returned %q (%v)", value, err)
+	}
+	if _, err := store.GetValue("missing"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("GetValue of a missing key returned %v", err)
+	}
+}
+
+func TestMemoryStoreRoundTrip(t *testing.T) {
+	testStoreRoundTrip(t, NewMemoryStore())
+}
+
+// The stored blocks and values are copies, the callers can´t change them
+func TestMemoryStoreCopies(t *testing.T) {
+	store := NewMemoryStore()
+	value := []byte("value")
+	if err := store.StoreValue("key", value); err != nil {
+		t.Fatal(err)
+	}
+	value[0] = 'X'
+	read, err := store.GetValue("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read[1] = 'X'
+	if again, _ := store.GetValue("key"); string(again) != "value" {
+		t.Errorf("the stored value changed to %q", again)
+	}
+
+	block := testChain(t, 1, 1600000000)[0]
+	if err = store.StoreBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.GetBlock(block.Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.Ticker = "ETHUSD"
+	if again, _ := store.GetBlock(block.Hash); again.Ticker != "BTCUSD" {
+		t.Errorf("the stored block changed to the ticker %s", again.Ticker)
+	}
+}
+
+func TestOpenKVStore(t *testing.T) {
+	store, err := OpenKVStore(MemoryBackend, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.(*MemoryStore); !ok {
+		t.Errorf("the memory backend opened a %T", store)
+	}
+
+	if _, err = OpenKVStore("unknown", ""); err == nil {
+		t.Error("an unknown backend was opened")
+	}
+
+	names := Backends()
+	for _, name := range []string{BadgerBackend, MemoryBackend} {
+		found := false
+		for _, registered := range names {
+			found = found || registered == name
+		}
+		if !found {
+			t.Errorf("the backend %s is not in %v", name, names)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a backend twice didn´t panic")
+		}
+	}()
+	RegisterBackend(MemoryBackend, nil)
+}
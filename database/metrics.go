@@ -0,0 +1,330 @@
+package database
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StoreMetrics holds the Prometheus collectors of the store operations. The operations are labeled with their
+// kind (read, write or scan) and the index they use, so a slow index can be found
+type StoreMetrics struct {
+	operations *prometheus.CounterVec
+	latency    *prometheus.HistogramVec
+	blocks     *prometheus.CounterVec
+}
+
+// NewStoreMetrics creates the collectors and registers them in the registry (prometheus.DefaultRegisterer to
+// publish them with the default handler)
+func NewStoreMetrics(registry prometheus.Registerer) (*StoreMetrics, error) {
+	m := &StoreMetrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "darkmatter",
+			Subsystem: "store",
+			Name:      "operations_total",
+			Help:      "Operations on the store, by kind, index and result (ok, not_found or error).",
+		}, []string{"operation", "index", "result"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "darkmatter",
+			Subsystem: "store",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of the operations on the store, by kind and index.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14), // From 0.5ms to about 4s
+		}, []string{"operation", "index"}),
+		blocks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "darkmatter",
+			Subsystem: "store",
+			Name:      "blocks_total",
+			Help:      "Blocks written to and read from the store.",
+		}, []string{"operation"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.operations, m.latency, m.blocks} {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Record an operation that started at start and ended with err
+func (m *StoreMetrics) observe(operation string, index string, start time.Time, err error) {
+	m.latency.WithLabelValues(operation, index).Observe(time.Since(start).Seconds())
+
+	result := "ok"
+	switch {
+	case errors.Is(err, types.ErrNotFound):
+		result = "not_found"
+	case err != nil:
+		result = "error"
+	}
+	m.operations.WithLabelValues(operation, index, result).Inc()
+}
+
+// MeteredStore implements the KVStore interface recording the metrics of each call to another store, from any
+// backend
+type MeteredStore struct {
+	store   types.KVStore
+	metrics *StoreMetrics
+}
+
+// NewMeteredStore puts the metrics in front of the store
+func NewMeteredStore(store types.KVStore, metrics *StoreMetrics) *MeteredStore {
+	return &MeteredStore{store: store, metrics: metrics}
+}
+
+// Close closes the measured store
+func (s *MeteredStore) Close() error {
+	return CloseStore(s.store)
+}
+
+// Health checks the health of the measured store
+func (s *MeteredStore) Health() error {
+	return StoreHealth(s.store)
+}
+
+// DiskUsage returns the disk usage of the measured store
+func (s *MeteredStore) DiskUsage() (int64, error) {
+	return StoreDiskUsage(s.store)
+}
+
+// Repair repairs the measured store
+func (s *MeteredStore) Repair(dryRun bool) (*RepairReport, error) {
+	start := time.Now()
+	report, err := RepairStore(s.store, dryRun)
+	s.metrics.observe("scan", "repair", start, err)
+
+	return report, err
+}
+
+// Subscribe subscribes to the blocks written to the measured store
+func (s *MeteredStore) Subscribe(prefix string) (*Subscription, error) {
+	return SubscribeStore(s.store, prefix)
+}
+
+// Backup writes a backup of the measured store
+func (s *MeteredStore) Backup(w io.Writer, since uint64) (uint64, error) {
+	start := time.Now()
+	next, err := BackupStore(s.store, w, since)
+	s.metrics.observe("scan", "backup", start, err)
+
+	return next, err
+}
+
+// Restore loads a backup in the measured store
+func (s *MeteredStore) Restore(r io.Reader) error {
+	start := time.Now()
+	err := RestoreStore(s.store, r)
+	s.metrics.observe("write", "restore", start, err)
+
+	return err
+}
+
+// Export exports the blocks of the measured store
+func (s *MeteredStore) Export(w io.Writer, format ExportFormat) error {
+	start := time.Now()
+	err := ExportStore(s.store, w, format)
+	s.metrics.observe("scan", "export", start, err)
+
+	return err
+}
+
+// Import imports the exported blocks in the measured store
+func (s *MeteredStore) Import(r io.Reader) (int, error) {
+	start := time.Now()
+	imported, err := ImportStore(s.store, r)
+	s.metrics.observe("write", "import", start, err)
+	s.metrics.blocks.WithLabelValues("write").Add(float64(imported))
+
+	return imported, err
+}
+
+// GC runs the garbage collection of the measured store
+func (s *MeteredStore) GC() (int, error) {
+	start := time.Now()
+	rewrites, err := CollectStore(s.store)
+	s.metrics.observe("scan", "gc", start, err)
+
+	return rewrites, err
+}
+
+// VerifyChain verifies the chain of the measured store
+func (s *MeteredStore) VerifyChain(from uint64, to uint64) (*ChainReport, error) {
+	start := time.Now()
+	report, err := VerifyStoreChain(s.store, from, to)
+	s.metrics.observe("scan", "verify", start, err)
+
+	return report, err
+}
+
+// Txn runs a transaction in the measured store
+func (s *MeteredStore) Txn(fn func(tx StoreTxn) error) error {
+	start := time.Now()
+	recorded, err := recordTxn(s.store, fn)
+	s.metrics.observe("write", "txn", start, err)
+	if err == nil {
+		s.metrics.blocks.WithLabelValues("write").Add(float64(len(recorded.blocks)))
+	}
+
+	return err
+}
+
+// Record a read of blocks, and how many were read
+func (s *MeteredStore) observeBlocks(index string, start time.Time, blocks []types.FullSignedBlock, err error) {
+	s.metrics.observe("scan", index, start, err)
+	s.metrics.blocks.WithLabelValues("read").Add(float64(len(blocks)))
+}
+
+// StoreValue stores an abritrary value, indexed by a string
+func (s *MeteredStore) StoreValue(key string, value []byte) error {
+	start := time.Now()
+	err := s.store.StoreValue(key, value)
+	s.metrics.observe("write", "value", start, err)
+
+	return err
+}
+
+// StoreValueWithTTL stores a value that expires after the ttl
+func (s *MeteredStore) StoreValueWithTTL(key string, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := StoreValueWithTTL(s.store, key, value, ttl)
+	s.metrics.observe("write", "value", start, err)
+
+	return err
+}
+
+// GetValue returns a value indexed by an string
+func (s *MeteredStore) GetValue(key string) ([]byte, error) {
+	start := time.Now()
+	value, err := s.store.GetValue(key)
+	s.metrics.observe("read", "value", start, err)
+
+	return value, err
+}
+
+// StoreBlock stores a full block
+func (s *MeteredStore) StoreBlock(block types.FullSignedBlock) error {
+	start := time.Now()
+	err := s.store.StoreBlock(block)
+	s.metrics.observe("write", "block", start, err)
+	if err == nil {
+		s.metrics.blocks.WithLabelValues("write").Inc()
+	}
+
+	return err
+}
+
+// StoreBlocks stores many blocks at once
+func (s *MeteredStore) StoreBlocks(blocks []types.FullSignedBlock) error {
+	start := time.Now()
+	err := s.store.StoreBlocks(blocks)
+	s.metrics.observe("write_batch", "block", start, err)
+	if err == nil {
+		s.metrics.blocks.WithLabelValues("write").Add(float64(len(blocks)))
+	}
+
+	return err
+}
+
+// GetBlock reads a block using their hash
+func (s *MeteredStore) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	start := time.Now()
+	block, err := s.store.GetBlock(hash)
+	s.metrics.observe("read", "hash", start, err)
+
+	return block, err
+}
+
+// FindBlockByTimestamp reads a block using their timestamp
+func (s *MeteredStore) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	start := time.Now()
+	block, err := s.store.FindBlockByTimestamp(timestamp)
+	s.metrics.observe("read", "timestamp", start, err)
+
+	return block, err
+}
+
+// FindBlockByHeight reads a block using their height
+func (s *MeteredStore) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	start := time.Now()
+	block, err := s.store.FindBlockByHeight(Height)
+	s.metrics.observe("read", "height", start, err)
+
+	return block, err
+}
+
+// GetLatestBlocks returns the blocks created at or before the timestamp, the newest block first
+func (s *MeteredStore) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	start := time.Now()
+	blocks, err := s.store.GetLatestBlocks(timestamp, offset, limit)
+	s.observeBlocks("timestamp", start, blocks, err)
+
+	return blocks, err
+}
+
+// GetBlocksByHeightRange returns the blocks between the heights from and to, both included
+func (s *MeteredStore) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	start := time.Now()
+	blocks, err := s.store.GetBlocksByHeightRange(from, to)
+	s.observeBlocks("height", start, blocks, err)
+
+	return blocks, err
+}
+
+// FindBlocksBetween returns the blocks created between startTs and endTs, both included, the oldest first
+func (s *MeteredStore) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	start := time.Now()
+	blocks, err := s.store.FindBlocksBetween(startTs, endTs, limit)
+	s.observeBlocks("timestamp", start, blocks, err)
+
+	return blocks, err
+}
+
+// GetHead returns the block at the tip of the chain
+func (s *MeteredStore) GetHead() (*types.FullSignedBlock, error) {
+	start := time.Now()
+	block, err := s.store.GetHead()
+	s.metrics.observe("read", "head", start, err)
+
+	return block, err
+}
+
+// GetLatestHeight returns the height of the tip of the chain
+func (s *MeteredStore) GetLatestHeight() (uint64, error) {
+	start := time.Now()
+	height, err := s.store.GetLatestHeight()
+	s.metrics.observe("read", "head", start, err)
+
+	return height, err
+}
+
+// GetBlocksByTicker returns the blocks of a ticker, the newest first
+func (s *MeteredStore) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	start := time.Now()
+	blocks, err := s.store.GetBlocksByTicker(ticker, limit)
+	s.observeBlocks("ticker", start, blocks, err)
+
+	return blocks, err
+}
+
+// GetBlocksByAddress returns the blocks produced by the node with the address, the newest first
+func (s *MeteredStore) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	start := time.Now()
+	blocks, err := s.store.GetBlocksByAddress(address, limit)
+	s.observeBlocks("address", start, blocks, err)
+
+	return blocks, err
+}
+
+// FindBlocksByHashPrefix returns the blocks whose hash starts with the prefix
+func (s *MeteredStore) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	start := time.Now()
+	blocks, err := s.store.FindBlocksByHashPrefix(prefix, limit)
+	s.observeBlocks("hash", start, blocks, err)
+
+	return blocks, err
+}
@@ -0,0 +1,270 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// The schema key holds the version of the key layout and the serialization of the stored data
+var schemaKey = []byte{SchemaKeyPrefix}
+
+// A migration upgrades the data written by the previous version of the schema. It reads the whole store with the
+// reader, and writes with set and del. The keys and values passed to them must not be the ones of the scans, as
+// the engines may keep them until the migration is written. A migration can be interrupted and run again, so it
+// must be idempotent
+type migration struct {
+	version     int
+	description string
+	migrate     func(r orderedReader, set func(key, value []byte) error, del func(key []byte) error) error
+}
+
+// All the migrations, in order. The version of each one is their position plus one.
+// The big endian keys were the version 1 when the string indexes were added: the stores marked with it were
+// written with big endian keys, so they are also up to date for the version 1 and only index the strings again
+var migrations = []migration{
+	{1, "rewrite the little endian keys of the height and timestamp indexes in big endian", migrateBigEndianKeys},
+	{2, "index the tickers and the addresses of the blocks, and write the head pointer", migrateStringIndexes},
+	{3, "encode the blocks stored in JSON with MessagePack", migrateMessagePack},
+	{4, "index the timestamps with the heights, to keep all the blocks of each second", migrateTimestampHeights},
+}
+
+// LatestSchemaVersion returns the version of the schema written by this code
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// Each engine reads the version and applies the migrations in their own way
+type migrator interface {
+	// schemaVersion returns the current version, zero if there is none
+	schemaVersion() (int, error)
+	// apply runs the migration and then writes their version
+	apply(m migration) error
+}
+
+// Apply the migrations newer than the current version of the store. A read-only store can´t be migrated, so it
+// must be up to date
+func runMigrations(mg migrator, readOnly bool) error {
+	current, err := mg.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("database: the schema version %d is newer than the supported one %d", current, LatestSchemaVersion())
+	}
+	if current < LatestSchemaVersion() && readOnly {
+		return fmt.Errorf("database: the schema version %d needs migrations, open the store with write access once", current)
+	}
+
+	for _, m := range migrations[current:] {
+		log.Println("Migrating the store to the schema version", m.version, "-", m.description)
+		if err = mg.apply(m); err != nil {
+			return fmt.Errorf("database: the migration to the schema version %d failed: %v", m.version, err)
+		}
+	}
+
+	return nil
+}
+
+// Serialize a schema version
+func encodeSchemaVersion(version int) []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(version))
+
+	return value
+}
+
+// Read the schema version from an ordered engine. The stores written before the versions existed have none
+func orderedSchemaVersion(r orderedReader) (int, error) {
+	value, err := r.get(schemaKey)
+	if errors.Is(err, types.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("database: the schema version is corrupt")
+	}
+
+	return int(binary.BigEndian.Uint64(value)), nil
+}
+
+// Version 1. The heights and the timestamps were little endian encoded in the keys of their indexes, so the keys
+// didn´t sort in the order of the numbers. Each key is rewritten in big endian if their block has the number of the
+// key in little endian. The keys already in big endian are kept, and the ones that match neither are left to Repair
+func migrateBigEndianKeys(r orderedReader, set func(key, value []byte) error, del func(key []byte) error) error {
+	for _, prefix := range []byte{HeightKeyPrefix, TimestampKeyPrefix} {
+		err := r.scan([]byte{prefix}, []byte{prefix}, false, func(key, hash []byte) (bool, error) {
+			if len(key) != 9 {
+				return true, nil
+			}
+
+			block, err := orderedReadBlock(r, string(hash))
+			if err != nil {
+				return false, err
+			}
+			number := block.Height
+			if prefix == TimestampKeyPrefix {
+				number = block.Timestamp
+			}
+
+			if binary.BigEndian.Uint64(key[1:]) == number || binary.LittleEndian.Uint64(key[1:]) != number {
+				return true, nil
+			}
+			if err = del(append([]byte(nil), key...)); err != nil {
+				return false, err
+			}
+
+			return true, set(uintIndexKey(number, prefix), []byte(block.Hash))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Version 2. The ticker and address indexes and the head pointer were added after the first blocks were stored,
+// so they are built for the blocks of the height index, in big endian since the version 1
+func migrateStringIndexes(r orderedReader, set func(key, value []byte) error, _ func(key []byte) error) error {
+	var head *types.FullSignedBlock
+	err := r.scan([]byte{HeightKeyPrefix}, []byte{HeightKeyPrefix}, false, func(key, hash []byte) (bool, error) {
+		block, err := orderedReadBlock(r, string(hash))
+		if err != nil {
+			return false, err
+		}
+
+		if err = set(stringHeightKey(block.Ticker, block.Height, TickerKeyPrefix), []byte(block.Hash)); err != nil {
+			return false, err
+		}
+		if err = set(stringHeightKey(block.Address, block.Height, AddressKeyPrefix), []byte(block.Hash)); err != nil {
+			return false, err
+		}
+		head = block // The heights are sorted, so the last one is the head
+
+		return true, nil
+	})
+	if err != nil || head == nil {
+		return err
+	}
+
+	return set(headKey, encodeHead(*head))
+}
+
+// Version 3. The blocks were stored in JSON, compressed or not, before MessagePack. They are encoded again with
+// MessagePack, compressed if they were, so the JSON is only read by this migration
+func migrateMessagePack(r orderedReader, set func(key, value []byte) error, _ func(key []byte) error) error {
+	return r.scan([]byte{HashKeyPrefix}, []byte{HashKeyPrefix}, false, func(key, value []byte) (bool, error) {
+		compressed := bytes.HasPrefix(value, zstdMagic)
+		content := value
+		if compressed {
+			var err error
+			if content, err = zstdDecoder.DecodeAll(value, nil); err != nil {
+				return false, err
+			}
+		}
+		if len(content) == 0 || content[0] != '{' {
+			return true, nil // Already in MessagePack
+		}
+
+		block, err := decodeBlock(value)
+		if err != nil {
+			return false, err
+		}
+		encoded, err := encodeBlock(*block, compressed)
+		if err != nil {
+			return false, err
+		}
+
+		return true, set(append([]byte(nil), key...), encoded)
+	})
+}
+
+// Version 4. The timestamp index had a key for each second, so a block replaced the entry of the previous one
+// created in the same second. The old keys are removed, and all the blocks of the height index are indexed again
+// by their timestamp and height
+func migrateTimestampHeights(r orderedReader, set func(key, value []byte) error, del func(key []byte) error) error {
+	err := r.scan([]byte{TimestampKeyPrefix}, []byte{TimestampKeyPrefix}, false, func(key, hash []byte) (bool, error) {
+		if len(key) != 9 {
+			return true, nil
+		}
+
+		return true, del(append([]byte(nil), key...))
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.scan([]byte{HeightKeyPrefix}, []byte{HeightKeyPrefix}, false, func(key, hash []byte) (bool, error) {
+		block, err := orderedReadBlock(r, string(hash))
+		if err != nil {
+			return false, err
+		}
+
+		return true, set(timestampHeightKey(block.Timestamp, block.Height), []byte(block.Hash))
+	})
+}
+
+// SchemaVersion returns the version of the schema of the stored data
+func (s Store) SchemaVersion() (int, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.release(stor)
+
+	var version int
+	err = stor.View(func(txn *badger.Txn) error {
+		version, err = orderedSchemaVersion(badgerReader{txn: txn})
+		return err
+	})
+
+	return version, err
+}
+
+// Migrate upgrades the stored data to the latest schema version. It runs when the store is created with
+// NewKVStore, NewKVStoreWithConfig or the badger backend
+func (s Store) Migrate() error {
+	return runMigrations(s, s.config.ReadOnly)
+}
+
+func (s Store) schemaVersion() (int, error) {
+	return s.SchemaVersion()
+}
+
+// The migration reads from a transaction and writes with a batch, so it is not limited by the size of a transaction
+func (s Store) apply(m migration) error {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer s.release(stor)
+
+	wb := stor.NewWriteBatch()
+	defer wb.Cancel()
+
+	err = stor.View(func(txn *badger.Txn) error {
+		return m.migrate(badgerReader{txn: txn}, wb.Set, wb.Delete)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = wb.Set(schemaKey, encodeSchemaVersion(m.version)); err != nil {
+		return err
+	}
+
+	return wb.Flush()
+}
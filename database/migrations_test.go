@@ -0,0 +1,155 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// The blocks of a chain written by the first versions: in JSON, with the height and timestamp keys, but without
+// the ticker and address indexes, the head pointer and the schema version
+func writeLegacyStore(t *testing.T, dir string, blocks []types.FullSignedBlock) {
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(txn *badger.Txn) error {
+		for _, block := range blocks {
+			content, err := json.Marshal(block)
+			if err != nil {
+				return err
+			}
+			if err = txn.Set(stringIndexKey(block.Hash, HashKeyPrefix), content); err != nil {
+				return err
+			}
+			if err = txn.Set(uintIndexKey(block.Height, HeightKeyPrefix), []byte(block.Hash)); err != nil {
+				return err
+			}
+			if err = txn.Set(uintIndexKey(block.Timestamp, TimestampKeyPrefix), []byte(block.Hash)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateLegacyStore(t *testing.T) {
+	dir := t.TempDir()
+	blocks := testChain(t, 20, 1600000000)
+	writeLegacyStore(t, dir, blocks)
+
+	store := NewKVStoreWithConfig(dir, StoreConfig{}).(*Store)
+	version, err := store.SchemaVersion()
+	if err != nil || version != LatestSchemaVersion() {
+		t.Fatalf("the schema version is %d (%v), expected %d", version, err, LatestSchemaVersion())
+	}
+
+	head, err := store.GetHead()
+	if err != nil || head.Height != 19 {
+		t.Errorf("the head is wrong after the migration: %v", err)
+	}
+	byTicker, err := store.GetBlocksByTicker("BTCUSD", 2)
+	if err != nil || len(byTicker) != 2 || byTicker[0].Height != 19 {
+		t.Errorf("the ticker index is wrong after the migration: %d blocks, %v", len(byTicker), err)
+	}
+	byAddress, err := store.GetBlocksByAddress("node", 0)
+	if err != nil || len(byAddress) != 20 {
+		t.Errorf("the address index is wrong after the migration: %d blocks, %v", len(byAddress), err)
+	}
+
+	// Opening the store again runs no migration
+	if err = NewKVStoreWithConfig(dir, StoreConfig{}).(*Store).Migrate(); err != nil {
+		t.Errorf("the migrated store failed to migrate again: %v", err)
+	}
+}
+
+// The other ordered engines are migrated when opened, so a new file is at the latest version
+func TestMigrateOrderedEngines(t *testing.T) {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+	leveldb, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer leveldb.Close()
+
+	stores := []struct {
+		name  string
+		store interface{ SchemaVersion() (int, error) }
+	}{
+		{"bolt", bolt},
+		{"leveldb", leveldb},
+	}
+	for _, test := range stores {
+		if version, err := test.store.SchemaVersion(); err != nil || version != LatestSchemaVersion() {
+			t.Errorf("the %s store has the schema version %d (%v)", test.name, version, err)
+		}
+	}
+}
+
+// A migrator that only records the migrations applied
+type fakeMigrator struct {
+	version int
+	applied []int
+}
+
+func (f *fakeMigrator) schemaVersion() (int, error) {
+	return f.version, nil
+}
+
+func (f *fakeMigrator) apply(m migration) error {
+	f.applied = append(f.applied, m.version)
+	f.version = m.version
+	return nil
+}
+
+func TestRunMigrations(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  int
+		readOnly bool
+		applied  int
+		fails    bool
+	}{
+		{"from the first version", 0, false, LatestSchemaVersion(), false},
+		{"up to date", LatestSchemaVersion(), false, 0, false},
+		{"up to date and read-only", LatestSchemaVersion(), true, 0, false},
+		{"read-only with migrations", 0, true, 0, true},
+		{"newer than the code", LatestSchemaVersion() + 1, false, 0, true},
+	}
+	for _, test := range tests {
+		mg := &fakeMigrator{version: test.version}
+		err := runMigrations(mg, test.readOnly)
+		if (err != nil) != test.fails {
+			t.Errorf("the migrations %s returned %v", test.name, err)
+		}
+		if len(mg.applied) != test.applied {
+			t.Errorf("the migrations %s applied %v", test.name, mg.applied)
+		}
+	}
+
+	failing := migrator(&failingMigrator{})
+	if err := runMigrations(failing, false); err == nil {
+		t.Error("a failed migration returned no error")
+	}
+}
+
+type failingMigrator struct {
+	fakeMigrator
+}
+
+func (f *failingMigrator) apply(m migration) error {
+	return errors.New("the disk is full")
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidNamespace is returned for the namespace names that can´t be encoded in the keys
+var ErrInvalidNamespace = errors.New("database: the namespace name can´t hold a zero byte")
+
+// A namespace is prepended to all the keys of a chain, so one database can hold many independent chains (e.g.
+// BTCUSD and ETHUSD) without colliding their height and timestamp indexes. The keys of a named namespace start
+// with NamespaceKeyPrefix and the name ended by a zero byte, so the name of a namespace never matches the keys of
+// a longer one. The default namespace has no prefix: it holds the chains written before the namespaces existed
+type namespace []byte
+
+// Build the namespace for a name. The empty name is the default namespace
+func newNamespace(name string) (namespace, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if strings.IndexByte(name, 0) >= 0 {
+		return nil, ErrInvalidNamespace
+	}
+
+	ns := make([]byte, 0, len(name)+2)
+	ns = append(ns, NamespaceKeyPrefix)
+	ns = append(ns, name...)
+
+	return append(ns, 0), nil
+}
+
+// Prepend the namespace to a key
+func (n namespace) key(key []byte) []byte {
+	if len(n) == 0 {
+		return key
+	}
+
+	full := make([]byte, 0, len(n)+len(key))
+	full = append(full, n...)
+
+	return append(full, key...)
+}
+
+// Scope a reader to the namespace
+func (n namespace) reader(r orderedReader) orderedReader {
+	if len(n) == 0 {
+		return r
+	}
+
+	return namespacedReader{r: r, ns: n}
+}
+
+// Scope a set function to the namespace
+func (n namespace) setter(set func(key, value []byte) error) func(key, value []byte) error {
+	if len(n) == 0 {
+		return set
+	}
+
+	return func(key, value []byte) error {
+		return set(n.key(key), value)
+	}
+}
+
+// Implements orderedReader over the keys of a namespace. The keys passed to scan don´t have the namespace, so the
+// queries read the same layout in any namespace
+type namespacedReader struct {
+	r  orderedReader
+	ns namespace
+}
+
+func (r namespacedReader) get(key []byte) ([]byte, error) {
+	return r.r.get(r.ns.key(key))
+}
+
+func (r namespacedReader) scan(prefix []byte, start []byte, reverse bool, fn func(key, value []byte) (bool, error)) error {
+	return r.r.scan(r.ns.key(prefix), r.ns.key(start), reverse, func(key, value []byte) (bool, error) {
+		return fn(key[len(r.ns):], value)
+	})
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// The engines that hold namespaces, with a function scoping the store to a namespace
+func testNamespacedStores(t *testing.T) []struct {
+	name      string
+	store     types.KVStore
+	namespace func(name string) (types.KVStore, error)
+} {
+	badger := NewKVStore(t.TempDir()).(*Store)
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	level, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { level.Close() })
+
+	return []struct {
+		name      string
+		store     types.KVStore
+		namespace func(name string) (types.KVStore, error)
+	}{
+		{"badger", badger, func(name string) (types.KVStore, error) { return badger.Namespace(name) }},
+		{"bolt", bolt, func(name string) (types.KVStore, error) { return bolt.Namespace(name) }},
+		{"leveldb", level, func(name string) (types.KVStore, error) { return level.Namespace(name) }},
+	}
+}
+
+// A namespace works as an empty store, even when the default namespace already holds a chain
+func TestNamespaceRoundTrip(t *testing.T) {
+	for _, test := range testNamespacedStores(t) {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.store.StoreBlocks(testChain(t, 3, 1500000000)); err != nil {
+				t.Fatal(err)
+			}
+			scoped, err := test.namespace("ETHUSD")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testStoreRoundTrip(t, scoped)
+		})
+	}
+}
+
+// The chains of two namespaces, one name the prefix of the other, share the heights and timestamps without
+// colliding with each other or with the default namespace
+func TestNamespaceIsolation(t *testing.T) {
+	for _, test := range testNamespacedStores(t) {
+		t.Run(test.name, func(t *testing.T) {
+			chains := []struct {
+				namespace string
+				length    int
+				address   string
+			}{
+				{"", 2, "default"},
+				{"BTC", 3, "btc"},
+				{"BTCUSD", 4, "btcusd"},
+			}
+
+			stores := make([]types.KVStore, len(chains))
+			hashes := make([]string, len(chains))
+			for i, chain := range chains {
+				stores[i] = test.store
+				if chain.namespace != "" {
+					scoped, err := test.namespace(chain.namespace)
+					if err != nil {
+						t.Fatal(err)
+					}
+					stores[i] = scoped
+				}
+
+				blocks := testChain(t, chain.length, 1600000000)
+				for j := range blocks {
+					blocks[j].Address = chain.address
+					if j > 0 {
+						blocks[j].PreviousHash = blocks[j-1].Hash
+					}
+					if err := blocks[j].CreateHash(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := stores[i].StoreBlocks(blocks); err != nil {
+					t.Fatal(err)
+				}
+				if err := stores[i].StoreValue("key", []byte(chain.address)); err != nil {
+					t.Fatal(err)
+				}
+				hashes[i] = blocks[0].Hash
+			}
+
+			for i, chain := range chains {
+				if height, err := stores[i].GetLatestHeight(); err != nil || height != uint64(chain.length-1) {
+					t.Errorf("the namespace %q has the head at %d (%v), expected %d", chain.namespace, height, err, chain.length-1)
+				}
+				if block, err := stores[i].FindBlockByTimestamp(1600000000); err != nil || block.Hash != hashes[i] {
+					t.Errorf("the namespace %q has at the first timestamp the block %v (%v)", chain.namespace, block, err)
+				}
+				if found, err := stores[i].GetBlocksByHeightRange(0, 10); err != nil || len(found) != chain.length {
+					t.Errorf("the namespace %q has %d blocks (%v), expected %d", chain.namespace, len(found), err, chain.length)
+				}
+				if value, err := stores[i].GetValue("key"); err != nil || string(value) != chain.address {
+					t.Errorf("the namespace %q has the value %q (%v)", chain.namespace, value, err)
+				}
+				for j := range chains {
+					if _, err := stores[i].GetBlock(hashes[j]); j != i && err == nil {
+						t.Errorf("the namespace %q reads the block of the namespace %q", chain.namespace, chains[j].namespace)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNamespaceInvalidName(t *testing.T) {
+	for _, test := range testNamespacedStores(t) {
+		if _, err := test.namespace("BTC\x00USD"); !errors.Is(err, ErrInvalidNamespace) {
+			t.Errorf("%s: a name with a zero byte returned %v", test.name, err)
+		}
+	}
+}
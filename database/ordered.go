@@ -0,0 +1,208 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"math"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// orderedReader reads from a sorted key-value engine that keeps the same key layout than the Badger store, so
+// the backends over these engines share the queries. Both methods are called inside a read transaction
+type orderedReader interface {
+	// get returns the value of the key, or types.ErrNotFound
+	get(key []byte) ([]byte, error)
+	// scan calls fn for each key that starts with the prefix, beginning at the key start (or the nearest one in the direction
+	// of the scan), until fn returns false. The key and value are only valid while fn runs
+	scan(prefix []byte, start []byte, reverse bool, fn func(key, value []byte) (bool, error)) error
+}
+
+// Implements orderedReader over a badger transaction, so the Badger store can share the queries
+type badgerReader struct {
+	txn *badger.Txn
+}
+
+func (r badgerReader) get(key []byte) ([]byte, error) {
+	item, err := r.txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, types.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return item.ValueCopy(nil)
+}
+
+func (r badgerReader) scan(prefix []byte, start []byte, reverse bool, fn func(key, value []byte) (bool, error)) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = reverse
+	opts.Prefix = prefix
+	// The scans stop early (limits, ranges) and most of the values are hashes of the indexes, so prefetching
+	// them in the background was slower in the benchmarks
+	opts.PrefetchValues = false
+
+	it := r.txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(start); it.Valid(); it.Next() {
+		value, err := it.Item().ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+
+		next, err := fn(it.Item().Key(), value)
+		if err != nil || !next {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Read a block using their hash
+func orderedReadBlock(r orderedReader, hash string) (*types.FullSignedBlock, error) {
+
+	bytes, err := r.get(stringIndexKey(hash, HashKeyPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeBlock(bytes)
+}
+
+// Read a block using an uint64 index
+func orderedReadBlockByIndex(r orderedReader, key uint64, prefix byte) (*types.FullSignedBlock, error) {
+
+	hash, err := r.get(uintIndexKey(key, prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	return orderedReadBlock(r, string(hash))
+}
+
+// Read the newest block created in the second of the timestamp, the one with the greatest height
+func orderedReadBlockByTimestamp(r orderedReader, timestamp uint64) (*types.FullSignedBlock, error) {
+
+	var block *types.FullSignedBlock
+	err := r.scan(uintIndexKey(timestamp, TimestampKeyPrefix), timestampHeightKey(timestamp, math.MaxUint64), true, func(key, hash []byte) (bool, error) {
+		var err error
+		block, err = orderedReadBlock(r, string(hash))
+		return false, err
+	})
+	if err == nil && block == nil {
+		err = types.ErrNotFound
+	}
+
+	return block, err
+}
+
+// Read the blocks created at or before the timestamp, the newest first. No more than limit blocks are read, unless
+// limit is zero
+func orderedLatestBlocks(r orderedReader, timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+
+	var blocks []types.FullSignedBlock
+	skipped := 0
+	err := r.scan([]byte{TimestampKeyPrefix}, timestampHeightKey(timestamp, math.MaxUint64), true, func(key, value []byte) (bool, error) {
+		if limit > 0 && len(blocks) == limit {
+			return false, nil
+		}
+		if skipped < offset {
+			skipped++
+			return true, nil
+		}
+
+		block, err := orderedReadBlock(r, string(value))
+		if err != nil {
+			return false, err
+		}
+		blocks = append(blocks, *block)
+
+		return true, nil
+	})
+
+	return blocks, err
+}
+
+// Read the blocks the index points to, from the key "from" to the key "to" (both included), in ascending order.
+// No more than limit blocks are read, unless limit is zero
+func orderedBlocksInRange(r orderedReader, from uint64, to uint64, limit int, prefix byte) ([]types.FullSignedBlock, error) {
+
+	return orderedBlocksInKeys(r, prefix, uintIndexKey(from, prefix), uintIndexKey(to, prefix), limit)
+}
+
+// Read the blocks created between the timestamps startTs and endTs (both included), the oldest first. No more than
+// limit blocks are read, unless limit is zero
+func orderedBlocksBetween(r orderedReader, startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+
+	return orderedBlocksInKeys(r, TimestampKeyPrefix, timestampHeightKey(startTs, 0), timestampHeightKey(endTs, math.MaxUint64), limit)
+}
+
+// Read the blocks an index points to, from the key first to the key last (both included), in ascending order
+func orderedBlocksInKeys(r orderedReader, prefix byte, first []byte, last []byte, limit int) ([]types.FullSignedBlock, error) {
+
+	var blocks []types.FullSignedBlock
+	err := r.scan([]byte{prefix}, first, false, func(key, value []byte) (bool, error) {
+		if bytes.Compare(key, last) > 0 || (limit > 0 && len(blocks) == limit) {
+			return false, nil // Out of the range
+		}
+
+		block, err := orderedReadBlock(r, string(value))
+		if err != nil {
+			return false, err
+		}
+		blocks = append(blocks, *block)
+
+		return true, nil
+	})
+
+	return blocks, err
+}
+
+// Read the blocks of a value of a string index, the newest first. No more than limit blocks are read, unless
+// limit is zero
+func orderedBlocksByString(r orderedReader, value string, limit int, prefix byte) ([]types.FullSignedBlock, error) {
+
+	var blocks []types.FullSignedBlock
+	err := r.scan(stringHeightPrefix(value, prefix), stringHeightKey(value, math.MaxUint64, prefix), true, func(key, hash []byte) (bool, error) {
+		if limit > 0 && len(blocks) == limit {
+			return false, nil
+		}
+
+		block, err := orderedReadBlock(r, string(hash))
+		if err != nil {
+			return false, err
+		}
+		blocks = append(blocks, *block)
+
+		return true, nil
+	})
+
+	return blocks, err
+}
+
+// Read the blocks whose hash starts with the prefix, sorted by hash. No more than limit blocks are read, unless
+// limit is zero
+func orderedBlocksByHashPrefix(r orderedReader, prefix string, limit int) ([]types.FullSignedBlock, error) {
+
+	var blocks []types.FullSignedBlock
+	first := stringIndexKey(prefix, HashKeyPrefix)
+	err := r.scan(first, first, false, func(key, value []byte) (bool, error) {
+		if limit > 0 && len(blocks) == limit {
+			return false, nil
+		}
+
+		block, err := decodeBlock(value)
+		if err != nil {
+			return false, err
+		}
+		blocks = append(blocks, *block)
+
+		return true, nil
+	})
+
+	return blocks, err
+}
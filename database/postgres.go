@@ -0,0 +1,120 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+
+	// Registers the "postgres" driver
+	"github.com/lib/pq"
+)
+
+// PostgresBackend is the name of the backend over a PostgreSQL database. The location is the connection string
+const PostgresBackend = "postgres"
+
+// PoolConfig sets the connection pool of a SQL database shared by many nodes
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// DefaultPoolConfig is the pool used when the backend is opened by name
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:    16,
+	MaxIdleConns:    4,
+	ConnMaxLifetime: 30 * time.Minute,
+}
+
+var postgresDialect = sqlDialect{
+	migrations: []string{
+		`CREATE TABLE blocks (
+			hash          TEXT PRIMARY KEY,
+			height        BIGINT NOT NULL,
+			timestamp     BIGINT NOT NULL,
+			ticker        TEXT NOT NULL,
+			previous_hash TEXT NOT NULL,
+			address       TEXT NOT NULL,
+			avg_price     DOUBLE PRECISION NOT NULL,
+			avg_volume    DOUBLE PRECISION NOT NULL,
+			payload       TEXT NOT NULL
+		)`,
+		`CREATE INDEX blocks_height ON blocks (height)`,
+		`CREATE INDEX blocks_timestamp ON blocks (timestamp)`,
+		`CREATE TABLE kv_values (
+			key   TEXT PRIMARY KEY,
+			value BYTEA NOT NULL
+		)`,
+		`CREATE INDEX blocks_ticker ON blocks (ticker, height)`,
+		`CREATE INDEX blocks_address ON blocks (address, height)`,
+		// The databases with many blocks at a height must be repaired before, keeping one of them
+		`CREATE UNIQUE INDEX blocks_height_unique ON blocks (height)`,
+	},
+	rebind:          rebindDollar,
+	lock:            `SELECT pg_advisory_xact_lock(4444)`, // Any number, only the migrations take this lock
+	uniqueViolation: isPostgresUniqueViolation,
+}
+
+// The SQLSTATE of the violations of the unique constraints
+const postgresUniqueViolation = "23505"
+
+func isPostgresUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == postgresUniqueViolation
+}
+
+// Rewrite the "?" placeholders as the numbered ones of PostgreSQL ($1, $2...)
+func rebindDollar(query string) string {
+	var result strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			result.WriteString("$" + strconv.Itoa(n))
+		} else {
+			result.WriteRune(c)
+		}
+	}
+
+	return result.String()
+}
+
+// NewPostgresStore connects to the PostgreSQL database and upgrades their schema. Many nodes can share the same
+// database: the migrations are applied in transactions, and a node can also use it only to read
+func NewPostgresStore(connection string, pool PoolConfig) (*SQLStore, error) {
+	db, err := sql.Open("postgres", connection)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store, err := newSQLStore(db, postgresDialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func init() {
+	RegisterBackend(PostgresBackend, func(location string) (types.KVStore, error) {
+		store, err := NewPostgresStore(location, DefaultPoolConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	})
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestRebindDollar(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{`SELECT 1`, `SELECT 1`},
+		{`SELECT payload FROM blocks WHERE hash = ?`, `SELECT payload FROM blocks WHERE hash = $1`},
+		{`INSERT INTO kv_values (key, value) VALUES (?, ?)`, `INSERT INTO kv_values (key, value) VALUES ($1, $2)`},
+		{`? ? ? ? ? ? ? ? ? ?`, `$1 $2 $3 $4 $5 $6 $7 $8 $9 $10`},
+	}
+	for _, test := range tests {
+		if query := rebindDollar(test.query); query != test.expected {
+			t.Errorf("%s was rewritten as %s, expected %s", test.query, query, test.expected)
+		}
+	}
+}
+
+// The errors of a write racing with another node map to a *ConflictError
+func TestPostgresUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		violation bool
+	}{
+		{"unique violation", &pq.Error{Code: "23505"}, true},
+		{"wrapped unique violation", fmt.Errorf("database: %w", &pq.Error{Code: "23505"}), true},
+		{"foreign key violation", &pq.Error{Code: "23503"}, false},
+		{"another error", errors.New("connection refused"), false},
+	}
+	for _, test := range tests {
+		if violation := isPostgresUniqueViolation(test.err); violation != test.violation {
+			t.Errorf("the %s is a unique violation: %t, expected %t", test.name, violation, test.violation)
+		}
+	}
+}
+
+// Runs only against a live server, with the connection string in DARKMATTER_POSTGRES. The tables are dropped first
+func TestPostgresStoreRoundTrip(t *testing.T) {
+	connection := os.Getenv("DARKMATTER_POSTGRES")
+	if connection == "" {
+		t.Skip("DARKMATTER_POSTGRES is not set")
+	}
+
+	store, err := NewPostgresStore(connection, DefaultPoolConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"blocks", "kv_values", "schema_migrations"} {
+		if _, err = store.DB().Exec(`DROP TABLE ` + table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	if store, err = NewPostgresStore(connection, DefaultPoolConfig); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	testStoreRoundTrip(t, store)
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+const (
+	// PrunedHeightKey is the literal used as a key to store the height of the next block to prune
+	PrunedHeightKey = "pruned-height"
+
+	// How many blocks are pruned on each write, if not set in the policy
+	defaultPruneBatchSize = 500
+)
+
+// RetentionPolicy sets which blocks keep their body (the evidence). A block is pruned when it is out of all the
+// enabled rules. The header and the indexes of the pruned blocks are always kept
+type RetentionPolicy struct {
+	// KeepBlocks is how many of the newest blocks keep their body. Disabled if zero
+	KeepBlocks uint64
+	// KeepFor is how long the blocks keep their body since they were created. Disabled if zero
+	KeepFor time.Duration
+	// DiskQuota is the disk usage in bytes that triggers a pruning without the KeepFor rule, so only the KeepBlocks
+	// newest blocks keep their body. It must be lower than the quota of the store, as the pruning writes the blocks
+	// again, and the space is only freed by the garbage collection of the value log. Disabled if zero, or if the
+	// store can´t report their disk usage
+	DiskQuota int64
+
+	// Interval is the time between two runs of the background job
+	Interval time.Duration
+	// BatchSize is how many blocks are archived and pruned at once
+	BatchSize int
+}
+
+// ArchiveFunc receives the full blocks before their bodies are removed. If it returns an error, the blocks are
+// not pruned, and the pruning stops until the next run. Archiver.Archive uploads them to an object storage
+type ArchiveFunc func(blocks []types.FullSignedBlock) error
+
+// Pruner removes the bodies of the old blocks of a store, following a retention policy
+type Pruner struct {
+	store   types.KVStore
+	policy  RetentionPolicy
+	archive ArchiveFunc
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPruner creates a pruner for the store. The archive function is optional
+func NewPruner(store types.KVStore, policy RetentionPolicy, archive ArchiveFunc) *Pruner {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = defaultPruneBatchSize
+	}
+
+	return &Pruner{
+		store:   store,
+		policy:  policy,
+		archive: archive,
+	}
+}
+
+// The block without their body. The hash, height and timestamp don´t change, so the indexes are still valid
+func prunedBlock(block types.FullSignedBlock) types.FullSignedBlock {
+	block.Evidence = nil
+
+	return block
+}
+
+// Returns true if any of the enabled rules keeps the body of the block. Over the disk quota, only KeepBlocks is used
+func (p *Pruner) keeps(block types.FullSignedBlock, tip types.FullSignedBlock, now time.Time, overQuota bool) bool {
+	if p.policy.KeepBlocks > 0 && block.Height+p.policy.KeepBlocks > tip.Height {
+		return true
+	}
+
+	return !overQuota && p.policy.KeepFor > 0 && now.Sub(block.Time()) < p.policy.KeepFor
+}
+
+// Returns true if the store is over the disk quota of the policy
+func (p *Pruner) overQuota() bool {
+	if p.policy.DiskQuota <= 0 {
+		return false
+	}
+
+	usage, err := StoreDiskUsage(p.store)
+	if err != nil {
+		return false
+	}
+	if usage > p.policy.DiskQuota {
+		log.Println("The store is over the disk quota of the pruning, using", usage, "bytes")
+		return true
+	}
+
+	return false
+}
+
+// Prune runs a pass over the blocks not pruned yet, from the oldest one, and returns how many were pruned
+func (p *Pruner) Prune() (int, error) {
+	overQuota := p.overQuota()
+	if p.policy.KeepBlocks == 0 && p.policy.KeepFor == 0 && !overQuota {
+		return 0, nil // Nothing to do
+	}
+
+	head, err := p.store.GetHead()
+	if errors.Is(err, types.ErrNotFound) {
+		return 0, nil // Empty store
+	}
+	if err != nil {
+		return 0, err
+	}
+	tip := *head
+
+	// Where the previous pass stopped
+	var next uint64
+	if value, err := p.store.GetValue(PrunedHeightKey); err == nil {
+		next, _ = strconv.ParseUint(string(value), 10, 64)
+	}
+
+	pruned := 0
+	now := time.Now()
+	for next <= tip.Height {
+		blocks, err := p.store.GetBlocksByHeightRange(next, next+uint64(p.policy.BatchSize)-1)
+		if err != nil {
+			return pruned, err
+		}
+
+		if len(blocks) == 0 {
+			next += uint64(p.policy.BatchSize) // A gap in the heights
+			continue
+		}
+
+		// The blocks are sorted by height, so the pass ends at the first block to keep
+		var batch []types.FullSignedBlock
+		finished := false
+		for _, block := range blocks {
+			if p.keeps(block, tip, now, overQuota) {
+				finished = true
+				break
+			}
+			batch = append(batch, block)
+		}
+
+		if len(batch) > 0 {
+			if p.archive != nil {
+				if err = p.archive(batch); err != nil {
+					return pruned, err
+				}
+			}
+
+			for i := range batch {
+				batch[i] = prunedBlock(batch[i])
+			}
+			if err = p.store.StoreBlocks(batch); err != nil {
+				return pruned, err
+			}
+
+			pruned += len(batch)
+			next = batch[len(batch)-1].Height + 1
+			if err = p.store.StoreValue(PrunedHeightKey, []byte(strconv.FormatUint(next, 10))); err != nil {
+				return pruned, err
+			}
+		}
+
+		if finished {
+			break
+		}
+	}
+
+	return pruned, nil
+}
+
+// Start launches the background job that prunes the store on each interval
+func (p *Pruner) Start() {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(p.policy.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				pruned, err := p.Prune()
+				if err != nil {
+					log.Println("The pruning of old blocks failed", err)
+				} else if pruned > 0 {
+					log.Println("Pruned old blocks:", pruned)
+				}
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the background job, waiting for the current pass to finish
+func (p *Pruner) Stop() {
+	close(p.stop)
+	<-p.done
+}
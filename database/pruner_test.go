@@ -0,0 +1,138 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// A store with a chain of ten blocks with evidence, one per second until now
+func testPrunerStore(t *testing.T) (*MemoryStore, []types.FullSignedBlock) {
+	blocks := testChain(t, 10, uint64(time.Now().Unix())-9)
+	for i := range blocks {
+		blocks[i].Evidence = []types.Result{{CrawlerName: "binance", Ticker: "BTCUSD"}}
+	}
+
+	store := NewMemoryStore()
+	if err := store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	return store, blocks
+}
+
+// The heights of the stored blocks that still have their evidence
+func keptHeights(t *testing.T, store types.KVStore) []uint64 {
+	blocks, err := store.GetBlocksByHeightRange(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	heights := []uint64{}
+	for _, block := range blocks {
+		if len(block.Evidence) > 0 {
+			heights = append(heights, block.Height)
+		}
+	}
+
+	return heights
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		pruned int
+		kept   []uint64
+	}{
+		{"disabled", RetentionPolicy{}, 0, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"keep the newest blocks", RetentionPolicy{KeepBlocks: 3, BatchSize: 2}, 7, []uint64{7, 8, 9}},
+		{"keep only the tip", RetentionPolicy{KeepBlocks: 1, BatchSize: 4}, 9, []uint64{9}},
+		{"keep more blocks than the chain", RetentionPolicy{KeepBlocks: 20}, 0, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"keep for a while", RetentionPolicy{KeepFor: time.Hour, BatchSize: 3}, 0, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{"keep by blocks or by time", RetentionPolicy{KeepBlocks: 2, KeepFor: time.Hour}, 0, []uint64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, test := range tests {
+		store, _ := testPrunerStore(t)
+		pruned, err := NewPruner(store, test.policy, nil).Prune()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pruned != test.pruned {
+			t.Errorf("the policy %s pruned %d blocks, expected %d", test.name, pruned, test.pruned)
+		}
+		if kept := keptHeights(t, store); !equalHeights(kept, test.kept) {
+			t.Errorf("the policy %s kept the evidence of the heights %v, expected %v", test.name, kept, test.kept)
+		}
+	}
+}
+
+// The pruned blocks keep their header and their indexes, and the next pass starts after them
+func TestPruneAgain(t *testing.T) {
+	store, blocks := testPrunerStore(t)
+	var archived []uint64
+	pruner := NewPruner(store, RetentionPolicy{KeepBlocks: 5, BatchSize: 2}, func(batch []types.FullSignedBlock) error {
+		archived = append(archived, heightsOf(batch)...)
+		return nil
+	})
+
+	if _, err := pruner.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	block, err := store.FindBlockByHeight(2)
+	if err != nil || block.Hash != blocks[2].Hash || len(block.Evidence) != 0 {
+		t.Errorf("the pruned block at the height 2 is %v (%v)", block, err)
+	}
+
+	more := testChain(t, 12, blocks[0].Timestamp)[10:]
+	more[0].PreviousHash = blocks[9].Hash
+	if err = store.StoreBlocks(more); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pruner.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []uint64{0, 1, 2, 3, 4, 5, 6}; !equalHeights(archived, expected) {
+		t.Errorf("the archived heights are %v, expected %v", archived, expected)
+	}
+}
+
+// A failed archive keeps the bodies of the blocks, and they are archived again on the next pass
+func TestPruneArchiveFailed(t *testing.T) {
+	store, _ := testPrunerStore(t)
+	failed := errors.New("the archive is not available")
+	archive := func(batch []types.FullSignedBlock) error { return failed }
+
+	pruned, err := NewPruner(store, RetentionPolicy{KeepBlocks: 5}, archive).Prune()
+	if !errors.Is(err, failed) || pruned != 0 {
+		t.Errorf("the pruning with a failed archive returned %d, %v", pruned, err)
+	}
+	if kept := keptHeights(t, store); len(kept) != 10 {
+		t.Errorf("the evidence of the heights %v was kept, expected all of them", kept)
+	}
+
+	pruned, err = NewPruner(store, RetentionPolicy{KeepBlocks: 5}, nil).Prune()
+	if err != nil || pruned != 5 {
+		t.Errorf("the pruning after the failed archive returned %d, %v", pruned, err)
+	}
+}
+
+func TestPrunerStartStop(t *testing.T) {
+	store, _ := testPrunerStore(t)
+	pruner := NewPruner(store, RetentionPolicy{KeepBlocks: 1, Interval: time.Millisecond}, nil)
+	pruner.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(keptHeights(t, store)) > 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	pruner.Stop()
+
+	if kept := keptHeights(t, store); !equalHeights(kept, []uint64{9}) {
+		t.Errorf("the background job kept the evidence of the heights %v", kept)
+	}
+}
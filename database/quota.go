@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+	"log"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrQuotaExceeded is returned by the writes to a store that uses more disk than their quota
+var ErrQuotaExceeded = errors.New("database: the store is over their disk quota")
+
+// ErrDiskUsageUnsupported is returned by StoreDiskUsage for the backends that can´t report their disk usage
+var ErrDiskUsageUnsupported = errors.New("database: the backend can´t report their disk usage")
+
+// DiskUsageReporter is implemented by the stores that know how much disk they use
+type DiskUsageReporter interface {
+	// DiskUsage returns the approximate size in bytes of the store on disk
+	DiskUsage() (int64, error)
+}
+
+// StoreDiskUsage returns the disk usage of a store, or ErrDiskUsageUnsupported if their backend can´t report it
+func StoreDiskUsage(store types.KVStore) (int64, error) {
+	if reporter, ok := store.(DiskUsageReporter); ok {
+		return reporter.DiskUsage()
+	}
+
+	return 0, ErrDiskUsageUnsupported
+}
+
+// Size of the tables and the value log. Badger refreshes them each minute, so they are approximate
+func diskUsage(stor *badger.DB) int64 {
+	lsm, vlog := stor.Size()
+
+	return lsm + vlog
+}
+
+// DiskUsage returns the approximate size in bytes of the database on disk, with all their namespaces. The space of
+// the rewritten and pruned values is only freed by the garbage collection of the value log
+func (s Store) DiskUsage() (int64, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+
+	defer s.release(stor)
+
+	return diskUsage(stor), nil
+}
+
+// Checks that the database is not over the quota of the config, before a write
+func (s Store) checkQuota(stor *badger.DB) error {
+	if s.config.DiskQuota > 0 && diskUsage(stor) > s.config.DiskQuota {
+		return ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// NewDiskUsageGauge registers in the registry a gauge with the disk usage of the store, read on each scrape. The
+// store must report their disk usage, directly or through their wrappers
+func NewDiskUsageGauge(registry prometheus.Registerer, store types.KVStore) error {
+	return registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "darkmatter",
+		Subsystem: "store",
+		Name:      "disk_usage_bytes",
+		Help:      "Approximate size of the store on disk.",
+	}, func() float64 {
+		usage, err := StoreDiskUsage(store)
+		if err != nil {
+			log.Println("Can´t read the disk usage of the store", err)
+		}
+
+		return float64(usage)
+	}))
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// A memory store that reports a fixed disk usage
+type usageStore struct {
+	*MemoryStore
+	usage int64
+}
+
+func (s *usageStore) DiskUsage() (int64, error) {
+	return s.usage, nil
+}
+
+// Badger computes the sizes when the database is opened, so the store is written and opened again with the quota
+func testQuotaStore(t *testing.T, quota int64) (*Store, []types.FullSignedBlock) {
+	dir := t.TempDir()
+	blocks := testChain(t, 4, 1600000000)
+	if err := NewKVStore(dir).StoreBlocks(blocks[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(dir, StoreConfig{DiskQuota: quota})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store, blocks
+}
+
+func TestDiskQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		quota int64
+		err   error
+	}{
+		{"no quota", 0, nil},
+		{"under the quota", 1 << 40, nil},
+		{"over the quota", 1, ErrQuotaExceeded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, blocks := testQuotaStore(t, test.quota)
+			if usage, err := store.DiskUsage(); err != nil || usage <= 1 {
+				t.Fatalf("the store uses %d bytes (%v)", usage, err)
+			}
+
+			var backup bytes.Buffer
+			if _, err := store.Backup(&backup, 0); err != nil {
+				t.Fatal(err)
+			}
+			var export bytes.Buffer
+			if err := store.Export(&export, ExportJSONL); err != nil {
+				t.Fatal(err)
+			}
+
+			writes := []struct {
+				name  string
+				write func() error
+			}{
+				{"StoreBlock", func() error { return store.StoreBlock(blocks[2]) }},
+				{"StoreBlocks", func() error { return store.StoreBlocks(blocks[3:]) }},
+				{"StoreValue", func() error { return store.StoreValue("key", []byte("value")) }},
+				{"Import", func() error { _, err := store.Import(&export); return err }},
+				{"Restore", func() error { return store.Restore(&backup) }},
+			}
+			for _, write := range writes {
+				if err := write.write(); !errors.Is(err, test.err) {
+					t.Errorf("%s returned %v, expected %v", write.name, err, test.err)
+				}
+			}
+
+			// The reads still work over the quota
+			if block, err := store.FindBlockByHeight(1); err != nil || block.Hash != blocks[1].Hash {
+				t.Errorf("FindBlockByHeight(1) returned %v (%v)", block, err)
+			}
+		})
+	}
+}
+
+// Over the quota of the policy, the pruner ignores KeepFor
+func TestPruneDiskQuota(t *testing.T) {
+	tests := []struct {
+		name   string
+		usage  int64
+		policy RetentionPolicy
+		kept   int
+	}{
+		{"under the quota", 100, RetentionPolicy{KeepBlocks: 3, KeepFor: time.Hour, DiskQuota: 1000}, 10},
+		{"over the quota", 2000, RetentionPolicy{KeepBlocks: 3, KeepFor: time.Hour, DiskQuota: 1000}, 3},
+		{"over the quota without KeepBlocks", 2000, RetentionPolicy{KeepFor: time.Hour, DiskQuota: 1000}, 0},
+		{"quota disabled", 2000, RetentionPolicy{KeepBlocks: 3, KeepFor: time.Hour}, 10},
+	}
+	for _, test := range tests {
+		memory, _ := testPrunerStore(t)
+		store := &usageStore{MemoryStore: memory, usage: test.usage}
+		if _, err := NewPruner(store, test.policy, nil).Prune(); err != nil {
+			t.Fatal(err)
+		}
+
+		if kept := keptHeights(t, store); len(kept) != test.kept {
+			t.Errorf("%s: the evidence of the heights %v was kept, expected %d blocks", test.name, kept, test.kept)
+		}
+	}
+}
+
+// The wrappers forward the disk usage, and the gauge reads it on each scrape
+func TestStoreDiskUsage(t *testing.T) {
+	metrics, err := NewStoreMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewLRUCache(NewMeteredStore(&usageStore{MemoryStore: NewMemoryStore(), usage: 1234}, metrics), 0)
+	if usage, err := StoreDiskUsage(store); err != nil || usage != 1234 {
+		t.Errorf("the disk usage through the wrappers is %d (%v)", usage, err)
+	}
+
+	registry := prometheus.NewRegistry()
+	if err = NewDiskUsageGauge(registry, store); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := testutil.GatherAndCount(registry, "darkmatter_store_disk_usage_bytes"); err != nil || count != 1 {
+		t.Errorf("the registry has %d disk usage gauges (%v)", count, err)
+	}
+
+	if _, err = StoreDiskUsage(NewMemoryStore()); !errors.Is(err, ErrDiskUsageUnsupported) {
+		t.Errorf("the disk usage of a memory store returned %v", err)
+	}
+}
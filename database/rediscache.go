@@ -0,0 +1,436 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/go-redis/redis"
+)
+
+// RedisCacheConfig sets the connection and the limits of a Redis cache
+type RedisCacheConfig struct {
+	Addr     string
+	Password string
+	DB       int
+
+	// Prefix is added to all the keys, so many chains can share the same Redis
+	Prefix string
+	// TTL is how long the cached entries live. They never expire if zero
+	TTL time.Duration
+	// NewestBlocks is how many of the newest blocks are kept to answer GetLatestBlocks
+	NewestBlocks int
+}
+
+// RedisCache implements the KVStore interface as a write-through cache in front of another store. The writes go
+// first to the backing store, so it is always the reference, and Redis only keeps copies of the blocks, the
+// indexes and the values for the reads. If Redis fails, the reads go to the backing store.
+//
+// The newest blocks are kept in a sorted set by timestamp, and by height for the blocks of the same second. The
+// set always holds all the blocks from their oldest
+// member to the tip of the chain, so a page of GetLatestBlocks found complete in the set is the right one. When a
+// write would break this rule (a block older than the tip), the set is dropped and built again from new blocks
+type RedisCache struct {
+	backing types.KVStore
+	client  *redis.Client
+	config  RedisCacheConfig
+}
+
+// NewRedisCache connects to Redis and puts the cache in front of the backing store
+func NewRedisCache(backing types.KVStore, config RedisCacheConfig) (*RedisCache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &RedisCache{backing: backing, client: client, config: config}, nil
+}
+
+// Close releases the connections to Redis. The backing store is not closed
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+// Health returns an error if the backing store is not healthy. Redis is optional, so their errors are only logged
+func (c *RedisCache) Health() error {
+	if err := c.client.Ping().Err(); err != nil {
+		log.Println("The Redis cache is unreachable:", err)
+	}
+
+	return StoreHealth(c.backing)
+}
+
+// DiskUsage returns the disk usage of the backing store. Redis is not counted
+func (c *RedisCache) DiskUsage() (int64, error) {
+	return StoreDiskUsage(c.backing)
+}
+
+// Repair repairs the backing store. The indexes may change, so the cached entries are dropped
+func (c *RedisCache) Repair(dryRun bool) (*RepairReport, error) {
+	report, err := RepairStore(c.backing, dryRun)
+	if !dryRun {
+		c.flush()
+	}
+
+	return report, err
+}
+
+// Subscribe subscribes to the blocks written to the backing store
+func (c *RedisCache) Subscribe(prefix string) (*Subscription, error) {
+	return SubscribeStore(c.backing, prefix)
+}
+
+// Backup writes a backup of the backing store
+func (c *RedisCache) Backup(w io.Writer, since uint64) (uint64, error) {
+	return BackupStore(c.backing, w, since)
+}
+
+// Restore loads a backup in the backing store. The restored pairs replace the stored ones, so the cached entries
+// are dropped
+func (c *RedisCache) Restore(r io.Reader) error {
+	err := RestoreStore(c.backing, r)
+	c.flush()
+
+	return err
+}
+
+// Export exports the blocks of the backing store
+func (c *RedisCache) Export(w io.Writer, format ExportFormat) error {
+	return ExportStore(c.backing, w, format)
+}
+
+// Import imports the exported blocks in the backing store. The head may move, so the cached entries are dropped
+func (c *RedisCache) Import(r io.Reader) (int, error) {
+	imported, err := ImportStore(c.backing, r)
+	c.flush()
+
+	return imported, err
+}
+
+// GC runs the garbage collection of the backing store
+func (c *RedisCache) GC() (int, error) {
+	return CollectStore(c.backing)
+}
+
+// VerifyChain verifies the chain of the backing store
+func (c *RedisCache) VerifyChain(from uint64, to uint64) (*ChainReport, error) {
+	return VerifyStoreChain(c.backing, from, to)
+}
+
+// Txn runs the transaction in the backing store, and caches the blocks and the values it wrote once it is committed
+func (c *RedisCache) Txn(fn func(tx StoreTxn) error) error {
+	recorded, err := recordTxn(c.backing, fn)
+	if err != nil {
+		return err
+	}
+
+	c.cacheBlocks(recorded.blocks)
+	for key, value := range recorded.values {
+		if err := c.client.Set(c.key("value", key), value, c.config.TTL).Err(); cacheFailed(err) {
+			c.client.Del(c.key("value", key))
+		}
+	}
+
+	return nil
+}
+
+// Remove all the entries of the cache, so the next reads go to the backing store
+func (c *RedisCache) flush() {
+	it := c.client.Scan(0, c.config.Prefix+"*", 1000).Iterator()
+	for it.Next() {
+		c.client.Del(it.Val())
+	}
+	cacheFailed(it.Err())
+}
+
+// Build a key of the cache
+func (c *RedisCache) key(kind string, id string) string {
+	return c.config.Prefix + kind + ":" + id
+}
+
+// The key of the sorted set with the newest blocks
+func (c *RedisCache) newestKey() string {
+	return c.config.Prefix + "newest"
+}
+
+// The member of a block in the set of the newest blocks. The members with the same score (the timestamp) sort as
+// strings, so they start with the height padded with zeros
+func newestMember(block types.FullSignedBlock) string {
+	return fmt.Sprintf("%020d:%s", block.Height, block.Hash)
+}
+
+// The hash of the block of a member of the set of the newest blocks
+func newestMemberHash(member string) string {
+	return member[strings.IndexByte(member, ':')+1:]
+}
+
+// Log the errors of Redis, except the missing keys. Returns true if there was any error or the key is missing
+func cacheFailed(err error) bool {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Println("Redis cache error, using the backing store:", err)
+	}
+
+	return err != nil
+}
+
+// Copy the written blocks to the cache
+func (c *RedisCache) cacheBlocks(blocks []types.FullSignedBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+
+	// Only the blocks newer than the tip of the set extend it
+	tip, err := c.client.ZRevRangeWithScores(c.newestKey(), 0, 0).Result()
+	if cacheFailed(err) {
+		return
+	}
+
+	extend, lastScore := false, math.Inf(-1)
+	switch {
+	case len(tip) == 1:
+		extend, lastScore = true, tip[0].Score
+	case len(blocks) == 1:
+		// The set is empty, so it only starts again with a block that is the tip of the backing store.
+		// A batch over an empty set is an import or a sync, and the set starts again with the next blocks
+		latest, err := c.backing.GetLatestBlocks(math.MaxUint64, 0, 1)
+		extend = err == nil && len(latest) == 1 && latest[0].Hash == blocks[0].Hash
+	}
+
+	pipe := c.client.TxPipeline()
+	for _, block := range blocks {
+		payload, err := json.Marshal(block)
+		if err != nil {
+			continue
+		}
+
+		pipe.Set(c.key("block", block.Hash), payload, c.config.TTL)
+		pipe.Set(c.key("height", strconv.FormatUint(block.Height, 10)), block.Hash, c.config.TTL)
+		pipe.Set(c.key("ts", strconv.FormatUint(block.Timestamp, 10)), block.Hash, c.config.TTL)
+
+		score := float64(block.Timestamp)
+		if score < lastScore {
+			extend = false
+		}
+		if extend {
+			pipe.ZAdd(c.newestKey(), redis.Z{Score: score, Member: newestMember(block)})
+			lastScore = score
+		}
+	}
+
+	if extend {
+		pipe.ZRemRangeByRank(c.newestKey(), 0, int64(-c.config.NewestBlocks-1)) // Keep only the newest ones
+	} else {
+		pipe.Del(c.newestKey())
+	}
+
+	if _, err = pipe.Exec(); cacheFailed(err) {
+		// The entries may be stale now, so try to remove them
+		for _, block := range blocks {
+			c.client.Del(c.key("block", block.Hash), c.key("height", strconv.FormatUint(block.Height, 10)),
+				c.key("ts", strconv.FormatUint(block.Timestamp, 10)), c.newestKey())
+		}
+	}
+}
+
+// Read a block from the cache. The second value is false if it is missing
+func (c *RedisCache) cachedBlock(hash string) (*types.FullSignedBlock, bool) {
+	payload, err := c.client.Get(c.key("block", hash)).Bytes()
+	if cacheFailed(err) {
+		return nil, false
+	}
+
+	var block types.FullSignedBlock
+	if err = json.Unmarshal(payload, &block); err != nil {
+		return nil, false
+	}
+
+	return &block, true
+}
+
+// Read a block from the cache through an index
+func (c *RedisCache) cachedBlockByIndex(kind string, key uint64) (*types.FullSignedBlock, bool) {
+	hash, err := c.client.Get(c.key(kind, strconv.FormatUint(key, 10))).Result()
+	if cacheFailed(err) {
+		return nil, false
+	}
+
+	return c.cachedBlock(hash)
+}
+
+// StoreValue stores the value in the backing store and in the cache
+func (c *RedisCache) StoreValue(key string, value []byte) error {
+	if err := c.backing.StoreValue(key, value); err != nil {
+		return err
+	}
+
+	if err := c.client.Set(c.key("value", key), value, c.config.TTL).Err(); cacheFailed(err) {
+		c.client.Del(c.key("value", key))
+	}
+
+	return nil
+}
+
+// StoreValueWithTTL stores the value with a TTL in the backing store and in the cache. The cached value expires with
+// the value, or before if the TTL of the cache is shorter
+func (c *RedisCache) StoreValueWithTTL(key string, value []byte, ttl time.Duration) error {
+	if err := StoreValueWithTTL(c.backing, key, value, ttl); err != nil {
+		return err
+	}
+
+	if c.config.TTL > 0 && c.config.TTL < ttl {
+		ttl = c.config.TTL
+	}
+	if err := c.client.Set(c.key("value", key), value, ttl).Err(); cacheFailed(err) {
+		c.client.Del(c.key("value", key))
+	}
+
+	return nil
+}
+
+// GetValue returns the value from the cache, or from the backing store if missing
+func (c *RedisCache) GetValue(key string) ([]byte, error) {
+	value, err := c.client.Get(c.key("value", key)).Bytes()
+	if !cacheFailed(err) {
+		return value, nil
+	}
+
+	value, err = c.backing.GetValue(key)
+	if err == nil {
+		c.client.Set(c.key("value", key), value, c.config.TTL)
+	}
+
+	return value, err
+}
+
+// StoreBlock stores the block in the backing store and in the cache
+func (c *RedisCache) StoreBlock(block types.FullSignedBlock) error {
+	if err := c.backing.StoreBlock(block); err != nil {
+		return err
+	}
+	c.cacheBlocks([]types.FullSignedBlock{block})
+
+	return nil
+}
+
+// StoreBlocks stores the blocks in the backing store and in the cache
+func (c *RedisCache) StoreBlocks(blocks []types.FullSignedBlock) error {
+	if err := c.backing.StoreBlocks(blocks); err != nil {
+		return err
+	}
+	c.cacheBlocks(blocks)
+
+	return nil
+}
+
+// GetBlock reads a block from the cache, or from the backing store if missing
+func (c *RedisCache) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	if block, found := c.cachedBlock(hash); found {
+		return block, nil
+	}
+
+	block, err := c.backing.GetBlock(hash)
+	if err == nil {
+		if payload, err := json.Marshal(block); err == nil {
+			c.client.Set(c.key("block", hash), payload, c.config.TTL)
+		}
+	}
+
+	return block, err
+}
+
+// FindBlockByTimestamp reads a block from the cache, or from the backing store if missing
+func (c *RedisCache) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	if block, found := c.cachedBlockByIndex("ts", timestamp); found {
+		return block, nil
+	}
+
+	return c.backing.FindBlockByTimestamp(timestamp)
+}
+
+// FindBlockByHeight reads a block from the cache, or from the backing store if missing
+func (c *RedisCache) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	if block, found := c.cachedBlockByIndex("height", Height); found {
+		return block, nil
+	}
+
+	return c.backing.FindBlockByHeight(Height)
+}
+
+// GetLatestBlocks returns the page from the set of newest blocks when it is complete there, or from the
+// backing store otherwise
+func (c *RedisCache) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	if limit == 0 {
+		return c.backing.GetLatestBlocks(timestamp, offset, limit) // The set doesn´t hold all the blocks
+	}
+
+	members, err := c.client.ZRevRangeByScore(c.newestKey(), redis.ZRangeBy{
+		Max:    strconv.FormatUint(timestamp, 10),
+		Min:    "-inf",
+		Offset: int64(offset),
+		Count:  int64(limit),
+	}).Result()
+
+	if !cacheFailed(err) && len(members) == limit {
+		var blocks []types.FullSignedBlock
+		for _, member := range members {
+			block, found := c.cachedBlock(newestMemberHash(member))
+			if !found {
+				break
+			}
+			blocks = append(blocks, *block)
+		}
+
+		if len(blocks) == limit {
+			return blocks, nil
+		}
+	}
+
+	return c.backing.GetLatestBlocks(timestamp, offset, limit)
+}
+
+// GetBlocksByHeightRange reads from the backing store
+func (c *RedisCache) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	return c.backing.GetBlocksByHeightRange(from, to)
+}
+
+// FindBlocksBetween reads from the backing store
+func (c *RedisCache) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.FindBlocksBetween(startTs, endTs, limit)
+}
+
+// GetHead always reads the head from the backing store, since it changes with every new block
+func (c *RedisCache) GetHead() (*types.FullSignedBlock, error) {
+	return c.backing.GetHead()
+}
+
+// GetLatestHeight always reads the height of the head from the backing store
+func (c *RedisCache) GetLatestHeight() (uint64, error) {
+	return c.backing.GetLatestHeight()
+}
+
+// GetBlocksByTicker reads the blocks of a ticker from the backing store
+func (c *RedisCache) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.GetBlocksByTicker(ticker, limit)
+}
+
+// GetBlocksByAddress reads the blocks of a producing node from the backing store
+func (c *RedisCache) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.GetBlocksByAddress(address, limit)
+}
+
+// FindBlocksByHashPrefix searches the blocks in the backing store
+func (c *RedisCache) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	return c.backing.FindBlocksByHashPrefix(prefix, limit)
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// A cache over a memory store and a Redis server in memory
+func testRedisCache(t *testing.T, newest int) (*RedisCache, *MemoryStore, *miniredis.Miniredis) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	backing := NewMemoryStore()
+	cache, err := NewRedisCache(backing, RedisCacheConfig{Addr: server.Addr(), Prefix: "test:", NewestBlocks: newest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	return cache, backing, server
+}
+
+func TestRedisCacheRoundTrip(t *testing.T) {
+	cache, _, _ := testRedisCache(t, 3)
+	testStoreRoundTrip(t, cache)
+}
+
+// The written blocks are copied to Redis, and the reads of the cached blocks don´t need the backing store
+func TestRedisCacheWriteThrough(t *testing.T) {
+	cache, backing, server := testRedisCache(t, 3)
+	blocks := testChain(t, 5, 1600000000)
+	for _, block := range blocks {
+		if err := cache.StoreBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, key := range []string{"test:block:" + blocks[4].Hash, "test:height:4", "test:ts:1600000004"} {
+		if !server.Exists(key) {
+			t.Errorf("the key %s is not in the cache", key)
+		}
+	}
+	newest, err := server.ZMembers("test:newest")
+	if err != nil || len(newest) != 3 {
+		t.Errorf("the set of the newest blocks has %d members (%v), expected 3", len(newest), err)
+	}
+
+	// A block changed only in the backing store shows which store answers
+	changed := blocks[4]
+	changed.Ticker = "ETHUSD"
+	payload, err := json.Marshal(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backing.blocks[changed.Hash] = payload
+
+	tests := []struct {
+		name   string
+		read   func() ([]types.FullSignedBlock, error)
+		ticker string
+	}{
+		{"GetBlock", func() ([]types.FullSignedBlock, error) { return one(cache.GetBlock(blocks[4].Hash)) }, "BTCUSD"},
+		{"FindBlockByHeight", func() ([]types.FullSignedBlock, error) { return one(cache.FindBlockByHeight(4)) }, "BTCUSD"},
+		{"GetLatestBlocks in the set", func() ([]types.FullSignedBlock, error) { return cache.GetLatestBlocks(math.MaxUint64, 0, 3) }, "BTCUSD"},
+		{"GetLatestBlocks over the set", func() ([]types.FullSignedBlock, error) { return cache.GetLatestBlocks(math.MaxUint64, 0, 4) }, "ETHUSD"},
+		{"GetBlocksByHeightRange", func() ([]types.FullSignedBlock, error) { return cache.GetBlocksByHeightRange(4, 4) }, "ETHUSD"},
+	}
+	for _, test := range tests {
+		found, err := test.read()
+		if err != nil || len(found) == 0 {
+			t.Errorf("%s returned %v (%v)", test.name, found, err)
+			continue
+		}
+		if found[0].Ticker != test.ticker {
+			t.Errorf("%s returned the ticker %s, expected %s", test.name, found[0].Ticker, test.ticker)
+		}
+	}
+}
+
+// A block older than the tip breaks the set of the newest blocks, so it is dropped
+func TestRedisCacheOlderBlock(t *testing.T) {
+	cache, _, server := testRedisCache(t, 10)
+	blocks := testChain(t, 3, 1600000000)
+	for _, block := range blocks[1:] {
+		if err := cache.StoreBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.StoreBlock(blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if server.Exists("test:newest") {
+		t.Error("the set of the newest blocks was kept after storing an older block")
+	}
+	found, err := cache.GetLatestBlocks(math.MaxUint64, 0, 3)
+	if err != nil || !equalHeights(heightsOf(found), []uint64{2, 1, 0}) {
+		t.Errorf("GetLatestBlocks returned %v (%v) without the set", heightsOf(found), err)
+	}
+}
+
+// Without Redis, the writes and the reads still go to the backing store
+func TestRedisCacheDown(t *testing.T) {
+	cache, backing, server := testRedisCache(t, 3)
+	server.Close()
+
+	block := testChain(t, 1, 1600000000)[0]
+	if err := cache.StoreBlock(block); err != nil {
+		t.Fatalf("the block was not stored without Redis: %v", err)
+	}
+	if _, err := backing.GetBlock(block.Hash); err != nil {
+		t.Errorf("the block is not in the backing store: %v", err)
+	}
+	if found, err := cache.FindBlockByHeight(0); err != nil || found.Hash != block.Hash {
+		t.Errorf("the block was not read without Redis: %v", err)
+	}
+	if err := cache.StoreValue("key", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := cache.GetValue("key"); err != nil || string(value) != "value" {
+		t.Errorf("GetValue without Redis returned %q (%v)", value, err)
+	}
+}
+
+func one(block *types.FullSignedBlock, err error) ([]types.FullSignedBlock, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return []types.FullSignedBlock{*block}, nil
+}
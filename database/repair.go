@@ -0,0 +1,280 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// RepairKind classifies the problems found by Repair
+type RepairKind string
+
+const (
+	// RepairCorruptBlock is a block record that can´t be decoded (e.g. a truncated value), or that holds another
+	// block. It can´t be rebuilt, so it is only reported
+	RepairCorruptBlock RepairKind = "corrupt-block"
+	// RepairDanglingIndex is an index entry that points to a block that is not stored, or to a block that doesn´t
+	// match the key. It is removed
+	RepairDanglingIndex RepairKind = "dangling-index"
+	// RepairMissingIndex is an index entry of a stored block that is missing. It is rebuilt from the block
+	RepairMissingIndex RepairKind = "missing-index"
+	// RepairConflict is a stored block whose height is indexed for another block. It is only reported
+	RepairConflict RepairKind = "conflict"
+	// RepairHead is a head pointer that doesn´t point to the block with the greatest height. It is rebuilt
+	RepairHead RepairKind = "head"
+)
+
+// ErrRepairUnsupported is returned by RepairStore for the backends that can´t be repaired
+var ErrRepairUnsupported = errors.New("database: the backend can´t be repaired")
+
+// RepairIssue is a problem found in the store
+type RepairIssue struct {
+	Height   uint64     `json:"height"`
+	Hash     string     `json:"hash"`
+	Kind     RepairKind `json:"kind"`
+	Detail   string     `json:"detail"`
+	Repaired bool       `json:"repaired"`
+}
+
+// RepairReport is the result of a repair
+type RepairReport struct {
+	Scanned  int           `json:"scanned"` // How many block records were read
+	Issues   []RepairIssue `json:"issues"`
+	Repaired int           `json:"repaired"`
+}
+
+// OK returns true if no problem was found
+func (r *RepairReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *RepairReport) add(height uint64, hash string, kind RepairKind, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, RepairIssue{Height: height, Hash: hash, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Repairer is implemented by the stores that can be repaired
+type Repairer interface {
+	// Repair finds the problems of the store and fixes the ones that can be fixed, unless dryRun is set
+	Repair(dryRun bool) (*RepairReport, error)
+}
+
+// RepairStore repairs a store, or returns ErrRepairUnsupported if their backend can´t be repaired
+func RepairStore(store types.KVStore, dryRun bool) (*RepairReport, error) {
+	if repairer, ok := store.(Repairer); ok {
+		return repairer.Repair(dryRun)
+	}
+
+	return nil, ErrRepairUnsupported
+}
+
+// The indexes of a block, rebuilt from the main register
+type indexedBlock struct {
+	hash      string
+	height    uint64
+	timestamp uint64
+	ticker    string
+	address   string
+}
+
+// The key of the entry of the block in an index
+func (b indexedBlock) key(prefix byte) []byte {
+	switch prefix {
+	case HeightKeyPrefix:
+		return uintIndexKey(b.height, prefix)
+	case TimestampKeyPrefix:
+		return timestampHeightKey(b.timestamp, b.height)
+	case TickerKeyPrefix:
+		return stringHeightKey(b.ticker, b.height, prefix)
+	}
+
+	return stringHeightKey(b.address, b.height, prefix)
+}
+
+// The secondary indexes, all of them rebuilt from the blocks
+var repairedIndexes = []byte{HeightKeyPrefix, TimestampKeyPrefix, TickerKeyPrefix, AddressKeyPrefix}
+
+// The writes that fix the store
+type repairPlan struct {
+	sets    map[string][]byte
+	deletes map[string]bool
+}
+
+// Find the problems of an ordered engine and plan their fixes. The blocks are read from the main register, and then
+// each index entry is checked against them, so the map of the chain is kept in memory while it runs
+func orderedRepairPlan(r orderedReader, report *RepairReport) (*repairPlan, error) {
+	plan := &repairPlan{sets: make(map[string][]byte), deletes: make(map[string]bool)}
+
+	blocks := make(map[string]indexedBlock)
+	corrupt := make(map[string]bool)
+	err := r.scan([]byte{HashKeyPrefix}, []byte{HashKeyPrefix}, false, func(key, value []byte) (bool, error) {
+		hash := string(key[1:])
+		report.Scanned++
+
+		block, err := decodeBlock(value)
+		if err != nil {
+			report.add(0, hash, RepairCorruptBlock, "the block can´t be decoded: %v", err)
+			corrupt[hash] = true
+			return true, nil
+		}
+		if block.Hash != hash {
+			report.add(block.Height, hash, RepairCorruptBlock, "the record holds the block %s", block.Hash)
+			corrupt[hash] = true
+			return true, nil
+		}
+
+		blocks[hash] = indexedBlock{hash, block.Height, block.Timestamp, block.Ticker, block.Address}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The entries that point to a block matching their key, by key
+	entries := make(map[string]string)
+	for _, prefix := range repairedIndexes {
+		err = r.scan([]byte{prefix}, []byte{prefix}, false, func(key, value []byte) (bool, error) {
+			block, stored := blocks[string(value)]
+			switch {
+			case corrupt[string(value)]:
+				// Already reported. The entries are kept, in case the block can be recovered from a backup
+			case !stored:
+				report.add(0, string(value), RepairDanglingIndex, "the index %#x points to a block that is not stored", prefix)
+				plan.deletes[string(key)] = true
+			case string(block.key(prefix)) != string(key):
+				report.add(block.height, block.hash, RepairDanglingIndex, "the index %#x points to the block from another key", prefix)
+				plan.deletes[string(key)] = true
+			default:
+				entries[string(key)] = block.hash
+			}
+
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The missing entries, sorted by height so the fixes don´t depend on the order of the map
+	sorted := make([]indexedBlock, 0, len(blocks))
+	for _, block := range blocks {
+		sorted = append(sorted, block)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].height != sorted[j].height {
+			return sorted[i].height < sorted[j].height
+		}
+		return sorted[i].hash < sorted[j].hash
+	})
+
+	var head *indexedBlock
+	for i, block := range sorted {
+		for _, prefix := range repairedIndexes {
+			key := string(block.key(prefix))
+			hash, indexed := entries[key]
+			if !indexed {
+				report.add(block.height, block.hash, RepairMissingIndex, "the block is not in the index %#x", prefix)
+				plan.sets[key] = []byte(block.hash)
+				delete(plan.deletes, key)
+				entries[key] = block.hash
+				continue
+			}
+
+			if hash != block.hash {
+				report.add(block.height, block.hash, RepairConflict, "the index %#x points to the block %s", prefix, hash)
+			}
+		}
+
+		if entries[string(block.key(HeightKeyPrefix))] == block.hash {
+			head = &sorted[i]
+		}
+	}
+
+	orderedRepairHead(r, head, plan, report)
+
+	return plan, nil
+}
+
+// Plan the fix of the head pointer, if it doesn´t point to the head found by the repair
+func orderedRepairHead(r orderedReader, head *indexedBlock, plan *repairPlan, report *RepairReport) {
+	value, err := r.get(headKey)
+	if head == nil {
+		if err == nil {
+			report.add(0, "", RepairHead, "the store has no blocks, but has a head pointer")
+			plan.deletes[string(headKey)] = true
+		}
+		return
+	}
+
+	if err == nil {
+		height, hash, err := decodeHead(value)
+		if err == nil && height == head.height && hash == head.hash {
+			return
+		}
+	}
+
+	report.add(head.height, head.hash, RepairHead, "the head pointer doesn´t point to the block")
+	plan.sets[string(headKey)] = encodeHead(types.FullSignedBlock{Hash: head.hash, Height: head.height})
+}
+
+// Mark the issues fixed by the plan
+func (r *RepairReport) repaired() {
+	for i := range r.Issues {
+		switch r.Issues[i].Kind {
+		case RepairDanglingIndex, RepairMissingIndex, RepairHead:
+			r.Issues[i].Repaired = true
+			r.Repaired++
+		}
+	}
+}
+
+// Repair scans the store for dangling index entries, index entries of missing blocks, and block records that can´t
+// be decoded, and rebuilds the secondary indexes and the head pointer from the blocks. The corrupt blocks and the
+// conflicts are only reported. Nothing is written if dryRun is set
+func (s Store) Repair(dryRun bool) (*RepairReport, error) {
+	if !dryRun {
+		if err := s.writable(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	report := &RepairReport{}
+	var plan *repairPlan
+	err = stor.View(func(txn *badger.Txn) error {
+		plan, err = orderedRepairPlan(s.reader(txn), report)
+		return err
+	})
+	if err != nil || dryRun {
+		return report, err
+	}
+
+	wb := stor.NewWriteBatch()
+	defer wb.Cancel()
+
+	for key := range plan.deletes {
+		if err = wb.Delete(s.namespace.key([]byte(key))); err != nil {
+			return report, err
+		}
+	}
+	for key, value := range plan.sets {
+		if err = wb.Set(s.namespace.key([]byte(key)), value); err != nil {
+			return report, err
+		}
+	}
+	if err = wb.Flush(); err != nil {
+		return report, err
+	}
+
+	report.repaired()
+	return report, nil
+}
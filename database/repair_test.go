@@ -0,0 +1,172 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A Badger store with a chain of six blocks, broken by the tamper function
+func testBrokenStore(t *testing.T, tamper func(txn *badger.Txn, blocks []types.FullSignedBlock) error) (*Store, []types.FullSignedBlock) {
+	store := NewKVStore(t.TempDir()).(*Store)
+	blocks := testChain(t, 6, 1600000000)
+	if err := store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	stor, err := store.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stor.Update(func(txn *badger.Txn) error { return tamper(txn, blocks) })
+	stor.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, blocks
+}
+
+// Find an issue of the report, nil if there is none
+func findIssue(report *RepairReport, kind RepairKind, height uint64) *RepairIssue {
+	for i, issue := range report.Issues {
+		if issue.Kind == kind && issue.Height == height {
+			return &report.Issues[i]
+		}
+	}
+
+	return nil
+}
+
+func TestRepair(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(txn *badger.Txn, blocks []types.FullSignedBlock) error
+		kind     RepairKind
+		height   uint64
+		repaired bool
+	}{
+		{"dangling height", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Set(uintIndexKey(9, HeightKeyPrefix), []byte("unknown"))
+		}, RepairDanglingIndex, 0, true},
+		{"index from another key", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Set(stringHeightKey("ETHUSD", 2, TickerKeyPrefix), []byte(blocks[2].Hash))
+		}, RepairDanglingIndex, 2, true},
+		{"missing timestamp", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Delete(timestampHeightKey(blocks[2].Timestamp, 2))
+		}, RepairMissingIndex, 2, true},
+		{"missing height", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Delete(uintIndexKey(3, HeightKeyPrefix))
+		}, RepairMissingIndex, 3, true},
+		{"stale head", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Set(headKey, encodeHead(blocks[1]))
+		}, RepairHead, 5, true},
+		{"corrupt block", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Set(stringIndexKey(blocks[3].Hash, HashKeyPrefix), []byte{0x85, 0xa4})
+		}, RepairCorruptBlock, 0, false},
+		{"another block at the height", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			fork := blocks[4]
+			fork.Address = "other node"
+			if err := fork.CreateHash(); err != nil {
+				return err
+			}
+			data, err := encodeBlock(fork, false)
+			if err != nil {
+				return err
+			}
+			return txn.Set(stringIndexKey(fork.Hash, HashKeyPrefix), data)
+		}, RepairConflict, 4, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, _ := testBrokenStore(t, test.tamper)
+
+			// A dry run only reports
+			report, err := store.Repair(true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if findIssue(report, test.kind, test.height) == nil || report.Repaired != 0 || report.Scanned < 6 {
+				t.Fatalf("the dry run found %v, expected a %s issue at the height %d", report.Issues, test.kind, test.height)
+			}
+			if again, err := store.Repair(true); err != nil || len(again.Issues) != len(report.Issues) {
+				t.Errorf("the dry run changed the store: %v (%v)", again, err)
+			}
+
+			report, err = store.Repair(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issue := findIssue(report, test.kind, test.height); issue == nil || issue.Repaired != test.repaired {
+				t.Errorf("the repair repaired %d of %v", report.Repaired, report.Issues)
+			}
+
+			after, err := store.Repair(true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.repaired && !after.OK() {
+				t.Errorf("the store still has the issues %v after the repair", after.Issues)
+			}
+			if !test.repaired && findIssue(after, test.kind, test.height) == nil {
+				t.Errorf("the %s issue is not reported after the repair: %v", test.kind, after.Issues)
+			}
+		})
+	}
+}
+
+// After a repair the indexes answer the queries again
+func TestRepairRebuildsQueries(t *testing.T) {
+	store, blocks := testBrokenStore(t, func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+		for _, block := range blocks[2:4] {
+			if err := txn.Delete(uintIndexKey(block.Height, HeightKeyPrefix)); err != nil {
+				return err
+			}
+		}
+		return txn.Delete(headKey)
+	})
+
+	if _, err := store.FindBlockByHeight(3); err == nil {
+		t.Fatal("the height index was not broken")
+	}
+	if _, err := store.Repair(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if block, err := store.FindBlockByHeight(3); err != nil || block.Hash != blocks[3].Hash {
+		t.Errorf("FindBlockByHeight(3) after the repair returned %v (%v)", block, err)
+	}
+	if head, err := store.GetHead(); err != nil || head.Hash != blocks[5].Hash {
+		t.Errorf("GetHead after the repair returned %v (%v)", head, err)
+	}
+	if report, err := store.VerifyChain(0, 5); err != nil || !report.OK() {
+		t.Errorf("the chain after the repair has the breaks %v (%v)", report, err)
+	}
+}
+
+// The wrappers repair the store behind them, and the stores that can´t be repaired say it
+func TestRepairStore(t *testing.T) {
+	metrics, err := NewStoreMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, blocks := testBrokenStore(t, func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+		return txn.Delete(uintIndexKey(2, HeightKeyPrefix))
+	})
+	cache := NewLRUCache(NewMeteredStore(store, metrics), 0)
+
+	report, err := RepairStore(cache, false)
+	if err != nil || report.Repaired != 1 {
+		t.Errorf("the repair through the wrappers returned %v (%v)", report, err)
+	}
+	if block, err := cache.FindBlockByHeight(2); err != nil || block.Hash != blocks[2].Hash {
+		t.Errorf("FindBlockByHeight(2) after the repair returned %v (%v)", block, err)
+	}
+
+	if _, err = RepairStore(NewMemoryStore(), false); !errors.Is(err, ErrRepairUnsupported) {
+		t.Errorf("repairing a memory store returned %v", err)
+	}
+}
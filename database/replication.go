@@ -0,0 +1,226 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultReplicationInterval is the time between two passes of the replicator, if not set in the config
+	DefaultReplicationInterval = 5 * time.Second
+
+	// How many blocks are copied on each write, if not set in the config
+	defaultReplicationBatchSize = 500
+)
+
+// ReplicationConfig sets how often and how many blocks are copied to the secondary store
+type ReplicationConfig struct {
+	// Interval is the time between two passes of the background job
+	Interval time.Duration
+	// BatchSize is how many blocks are read from the primary store and written to the secondary one at once
+	BatchSize int
+	// Notify runs a pass for each block received, besides the interval, so the writes are copied as they happen.
+	// The Blocks of a subscription to the primary store (SubscribeStore) can be used. Optional
+	Notify <-chan types.FullSignedBlock
+}
+
+// ReplicationMetrics holds the Prometheus collectors of a replicator
+type ReplicationMetrics struct {
+	lag        prometheus.Gauge
+	replicated prometheus.Counter
+	failures   prometheus.Counter
+}
+
+// NewReplicationMetrics creates the collectors and registers them in the registry (prometheus.DefaultRegisterer
+// to publish them with the default handler)
+func NewReplicationMetrics(registry prometheus.Registerer) (*ReplicationMetrics, error) {
+	m := &ReplicationMetrics{
+		lag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "darkmatter",
+			Subsystem: "replication",
+			Name:      "lag_blocks",
+			Help:      "Blocks of the primary store not copied yet to the secondary store.",
+		}),
+		replicated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "darkmatter",
+			Subsystem: "replication",
+			Name:      "blocks_total",
+			Help:      "Blocks copied to the secondary store.",
+		}),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "darkmatter",
+			Subsystem: "replication",
+			Name:      "failures_total",
+			Help:      "Passes of the replicator that failed.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.lag, m.replicated, m.failures} {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Replicator mirrors the blocks written to a primary store in a secondary one, from any backend (e.g. a local
+// Badger store to a remote Postgres). Each pass copies the blocks above the head of the secondary store, so when
+// the secondary store is unreachable the next passes catch up from where it stopped.
+// Only the new heights are copied: the blocks rewritten below the head of the secondary store (as the pruner does)
+// are not mirrored
+type Replicator struct {
+	primary   types.KVStore
+	secondary types.KVStore
+	config    ReplicationConfig
+	metrics   *ReplicationMetrics
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReplicator creates the replicator between both stores. The metrics are optional. The zero values of the
+// config are replaced by the defaults
+func NewReplicator(primary types.KVStore, secondary types.KVStore, config ReplicationConfig, metrics *ReplicationMetrics) *Replicator {
+	if config.Interval <= 0 {
+		config.Interval = DefaultReplicationInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultReplicationBatchSize
+	}
+
+	return &Replicator{
+		primary:   primary,
+		secondary: secondary,
+		config:    config,
+		metrics:   metrics,
+	}
+}
+
+// Read the height of the head of a store, and if it has any block
+func latestHeight(store types.KVStore) (uint64, bool, error) {
+	height, err := store.GetLatestHeight()
+	if errors.Is(err, types.ErrNotFound) {
+		return 0, false, nil // Empty store
+	}
+
+	return height, err == nil, err
+}
+
+// Publish the blocks of the primary store above the next height to copy
+func (r *Replicator) setLag(tip uint64, next uint64) {
+	if r.metrics == nil {
+		return
+	}
+
+	lag := 0.0
+	if tip >= next {
+		lag = float64(tip - next + 1)
+	}
+	r.metrics.lag.Set(lag)
+}
+
+// Replicate runs a pass, copying the blocks of the primary store above the head of the secondary one. Returns how
+// many blocks were copied
+func (r *Replicator) Replicate() (int, error) {
+	replicated, err := r.replicate()
+	if err != nil && r.metrics != nil {
+		r.metrics.failures.Inc()
+	}
+
+	return replicated, err
+}
+
+func (r *Replicator) replicate() (int, error) {
+	tip, hasTip, err := latestHeight(r.primary)
+	if err != nil || !hasTip {
+		return 0, err
+	}
+
+	// Where the secondary store stopped
+	height, hasHead, err := latestHeight(r.secondary)
+	if err != nil {
+		return 0, err
+	}
+
+	var next uint64
+	if hasHead {
+		next = height + 1
+	}
+	r.setLag(tip, next)
+
+	replicated := 0
+	for next <= tip {
+		blocks, err := r.primary.GetBlocksByHeightRange(next, next+uint64(r.config.BatchSize)-1)
+		if err != nil {
+			return replicated, err
+		}
+
+		if len(blocks) == 0 {
+			next += uint64(r.config.BatchSize) // A gap in the heights
+			continue
+		}
+
+		if err = r.secondary.StoreBlocks(blocks); err != nil {
+			return replicated, err
+		}
+
+		replicated += len(blocks)
+		next = blocks[len(blocks)-1].Height + 1
+		if r.metrics != nil {
+			r.metrics.replicated.Add(float64(len(blocks)))
+		}
+		r.setLag(tip, next)
+	}
+
+	return replicated, nil
+}
+
+// Start launches the background job that replicates the new blocks on each interval
+func (r *Replicator) Start() {
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		notify := r.config.Notify
+		for {
+			select {
+			case <-ticker.C:
+				r.pass()
+			case _, open := <-notify:
+				if !open {
+					notify = nil // The subscription ended, so only the interval is left
+					continue
+				}
+				r.pass()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Run a pass of the background job
+func (r *Replicator) pass() {
+	replicated, err := r.Replicate()
+	if err != nil {
+		log.Println("The replication of the new blocks failed", err)
+	} else if replicated > 0 {
+		log.Println("Replicated blocks:", replicated)
+	}
+}
+
+// Stop ends the background job, waiting for the current pass to finish
+func (r *Replicator) Stop() {
+	close(r.stop)
+	<-r.done
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var errUnreachable = errors.New("the store is unreachable")
+
+// A memory store that can be made unreachable, as a remote database would be
+type unreachableStore struct {
+	*MemoryStore
+	down bool
+}
+
+func (s *unreachableStore) StoreBlocks(blocks []types.FullSignedBlock) error {
+	if s.down {
+		return errUnreachable
+	}
+	return s.MemoryStore.StoreBlocks(blocks)
+}
+
+func (s *unreachableStore) GetLatestHeight() (uint64, error) {
+	if s.down {
+		return 0, errUnreachable
+	}
+	return s.MemoryStore.GetLatestHeight()
+}
+
+// The secondary store is unreachable for some passes while the primary one grows, and then catches up
+func TestReplicatorCatchUp(t *testing.T) {
+	blocks := testChain(t, 12, 1600000000)
+	primary := NewMemoryStore()
+	secondary := &unreachableStore{MemoryStore: NewMemoryStore()}
+	metrics, err := NewReplicationMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicator := NewReplicator(primary, secondary, ReplicationConfig{BatchSize: 4}, metrics)
+
+	tests := []struct {
+		name       string
+		stored     int // The blocks of the primary store
+		down       bool
+		replicated int
+		height     uint64 // The head of the secondary store after the pass
+		lag        float64
+		failures   float64
+	}{
+		{"first pass", 3, false, 3, 2, 0, 0},
+		{"nothing new", 3, false, 0, 2, 0, 0},
+		{"unreachable", 6, true, 0, 2, 0, 1},
+		{"still unreachable", 10, true, 0, 2, 0, 2},
+		{"back", 10, false, 7, 9, 0, 2},
+		{"more blocks", 12, false, 2, 11, 0, 2},
+	}
+	for _, test := range tests {
+		if err := primary.StoreBlocks(blocks[:test.stored]); err != nil {
+			t.Fatal(err)
+		}
+		secondary.down = test.down
+
+		replicated, err := replicator.Replicate()
+		if test.down != (err != nil) {
+			t.Errorf("%s: the pass returned %v", test.name, err)
+		}
+		if replicated != test.replicated {
+			t.Errorf("%s: the pass copied %d blocks, expected %d", test.name, replicated, test.replicated)
+		}
+
+		secondary.down = false
+		if height, err := secondary.GetLatestHeight(); err != nil || height != test.height {
+			t.Errorf("%s: the secondary store has the head at %d (%v), expected %d", test.name, height, err, test.height)
+		}
+		if failures := testutil.ToFloat64(metrics.failures); failures != test.failures {
+			t.Errorf("%s: %v failures were counted, expected %v", test.name, failures, test.failures)
+		}
+		if !test.down {
+			if lag := testutil.ToFloat64(metrics.lag); lag != test.lag {
+				t.Errorf("%s: the lag is %v blocks, expected %v", test.name, lag, test.lag)
+			}
+		}
+	}
+
+	if copied := testutil.ToFloat64(metrics.replicated); copied != 12 {
+		t.Errorf("%v blocks were counted as copied, expected 12", copied)
+	}
+	found, err := secondary.GetBlocksByHeightRange(0, 20)
+	if err != nil || len(found) != 12 {
+		t.Fatalf("the secondary store holds %d blocks (%v)", len(found), err)
+	}
+	for i, block := range found {
+		if block.Hash != blocks[i].Hash {
+			t.Errorf("the secondary store holds at the height %d the block %s, expected %s", i, block.Hash, blocks[i].Hash)
+		}
+	}
+}
+
+// A gap in the heights of the primary store, larger than a batch, doesn´t stop the copy
+func TestReplicatorGap(t *testing.T) {
+	blocks := testChain(t, 10, 1600000000)
+	primary := NewMemoryStore()
+	if err := primary.StoreBlocks(append(blocks[:2:2], blocks[7:]...)); err != nil {
+		t.Fatal(err)
+	}
+	secondary := NewMemoryStore()
+
+	replicated, err := NewReplicator(primary, secondary, ReplicationConfig{BatchSize: 2}, nil).Replicate()
+	if err != nil || replicated != 5 {
+		t.Errorf("the pass copied %d blocks (%v), expected 5", replicated, err)
+	}
+	found, _ := secondary.GetBlocksByHeightRange(0, 20)
+	if got := heightsOf(found); !equalHeights(got, []uint64{0, 1, 7, 8, 9}) {
+		t.Errorf("the secondary store holds the heights %v", got)
+	}
+}
+
+func TestReplicatorEmptyPrimary(t *testing.T) {
+	replicated, err := NewReplicator(NewMemoryStore(), NewMemoryStore(), ReplicationConfig{}, nil).Replicate()
+	if err != nil || replicated != 0 {
+		t.Errorf("the pass of an empty store copied %d blocks (%v)", replicated, err)
+	}
+}
+
+func TestReplicatorStartStop(t *testing.T) {
+	primary := NewMemoryStore()
+	if err := primary.StoreBlocks(testChain(t, 5, 1600000000)); err != nil {
+		t.Fatal(err)
+	}
+	secondary := NewMemoryStore()
+	replicator := NewReplicator(primary, secondary, ReplicationConfig{Interval: time.Millisecond}, nil)
+	replicator.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if height, err := secondary.GetLatestHeight(); err == nil && height == 4 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	replicator.Stop()
+
+	if height, err := secondary.GetLatestHeight(); err != nil || height != 4 {
+		t.Errorf("the background job copied up to the height %d (%v)", height, err)
+	}
+}
@@ -0,0 +1,123 @@
+//go:build sqlite
+// +build sqlite
+
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+
+	// Registers the "sqlite3" driver
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteBackend is the name of the backend over a SQLite file. The driver needs cgo, so the backend is only built
+// with the sqlite tag (go build -tags sqlite)
+const SQLiteBackend = "sqlite"
+
+// The tables existed before the migrations were tracked, so the first statements tolerate them
+var sqliteDialect = sqlDialect{
+	migrations: []string{
+		`CREATE TABLE IF NOT EXISTS blocks (
+			hash          TEXT PRIMARY KEY,
+			height        INTEGER NOT NULL,
+			timestamp     INTEGER NOT NULL,
+			ticker        TEXT NOT NULL,
+			previous_hash TEXT NOT NULL,
+			address       TEXT NOT NULL,
+			avg_price     REAL NOT NULL,
+			avg_volume    REAL NOT NULL,
+			payload       TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS blocks_height ON blocks (height)`,
+		`CREATE INDEX IF NOT EXISTS blocks_timestamp ON blocks (timestamp)`,
+		`CREATE TABLE IF NOT EXISTS kv_values (
+			key   TEXT PRIMARY KEY,
+			value BLOB NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS blocks_ticker ON blocks (ticker, height)`,
+		`CREATE INDEX IF NOT EXISTS blocks_address ON blocks (address, height)`,
+		// The files with many blocks at a height must be repaired before, keeping one of them
+		`CREATE UNIQUE INDEX IF NOT EXISTS blocks_height_unique ON blocks (height)`,
+	},
+	rebind: func(query string) string { return query },
+	uniqueViolation: func(err error) bool {
+		var sqliteErr sqlite3.Error
+		return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+	},
+}
+
+// The parameters of the connections, unless the location sets them. The first name is the one added, the others are
+// their aliases in the driver
+var sqliteDefaults = []struct {
+	names []string
+	value string
+}{
+	{[]string{"_journal_mode", "_journal"}, "WAL"},
+	{[]string{"_busy_timeout", "_timeout"}, "5000"},
+}
+
+// Add the default parameters missing in the location, which may already have their own (e.g. "file.db?mode=ro")
+func sqliteDSN(fileLocation string) string {
+	dsn := fileLocation
+	query := ""
+	if i := strings.IndexByte(fileLocation, '?'); i >= 0 {
+		query = fileLocation[i+1:]
+	}
+	params, _ := url.ParseQuery(query) // The driver reports the malformed parameters
+
+	for _, param := range sqliteDefaults {
+		set := false
+		for _, name := range param.names {
+			_, found := params[name]
+			set = set || found
+		}
+		if set {
+			continue
+		}
+
+		switch {
+		case !strings.Contains(dsn, "?"):
+			dsn += "?"
+		case !strings.HasSuffix(dsn, "?") && !strings.HasSuffix(dsn, "&"):
+			dsn += "&"
+		}
+		dsn += param.names[0] + "=" + param.value
+	}
+
+	return dsn
+}
+
+// NewSQLiteStore opens (or creates) the SQLite file in the location, in WAL mode and waiting 5 seconds for the
+// locks. The location can have the parameters of the driver (e.g. "file.db?_busy_timeout=10000"), which replace
+// the defaults
+func NewSQLiteStore(fileLocation string) (*SQLStore, error) {
+	db, err := sql.Open("sqlite3", sqliteDSN(fileLocation))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1) // SQLite has a single writer, so a single connection avoids the "database is locked" errors
+
+	store, err := newSQLStore(db, sqliteDialect)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func init() {
+	RegisterBackend(SQLiteBackend, func(location string) (types.KVStore, error) {
+		store, err := NewSQLiteStore(location)
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	})
+}
@@ -0,0 +1,120 @@
+//go:build sqlite
+// +build sqlite
+
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+func testSQLiteStore(t *testing.T) *SQLStore {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestSQLiteStoreRoundTrip(t *testing.T) {
+	testStoreRoundTrip(t, testSQLiteStore(t))
+}
+
+// As in the KV stores, a different block at a stored height is a conflict, and the blocks can be queried with
+// plain SQL
+func TestSQLiteStoreSameHeight(t *testing.T) {
+	store := testSQLiteStore(t)
+	blocks := testChain(t, 3, 1600000000)
+	if err := store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	fork := blocks[2]
+	fork.Address = "other node"
+	if err := fork.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+	var conflict *ConflictError
+	if err := store.StoreBlock(fork); !errors.As(err, &conflict) || conflict.StoredHash != blocks[2].Hash {
+		t.Errorf("storing a fork at the height 2 returned %v, expected a conflict with %s", err, blocks[2].Hash)
+	}
+
+	// The writes in a batch are not checked before, the unique index of the heights rejects the fork
+	if err := store.StoreBlocks([]types.FullSignedBlock{fork}); !errors.As(err, &conflict) || conflict.StoredHash != blocks[2].Hash {
+		t.Errorf("storing a fork at the height 2 in a batch returned %v, expected a conflict with %s", err, blocks[2].Hash)
+	}
+
+	var count int
+	err := store.DB().QueryRow(`SELECT COUNT(*) FROM blocks WHERE ticker = ? AND address = ?`, "BTCUSD", "other node").Scan(&count)
+	if err != nil || count != 0 {
+		t.Errorf("the blocks table has %d blocks of the other node (%v), expected none", count, err)
+	}
+
+	// Storing a block again keeps a single row
+	if err = store.StoreBlock(blocks[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.DB().QueryRow(`SELECT COUNT(*) FROM blocks`).Scan(&count); err != nil || count != 3 {
+		t.Errorf("the blocks table has %d blocks (%v), expected 3", count, err)
+	}
+}
+
+// The migrations are applied once, and opening the database again keeps their version
+func TestSQLMigrations(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "chain.db")
+	for i := 0; i < 2; i++ {
+		store, err := NewSQLiteStore(location)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := store.SchemaVersion()
+		if err != nil || version != len(sqliteDialect.migrations) {
+			t.Errorf("the schema version is %d (%v), expected %d", version, err, len(sqliteDialect.migrations))
+		}
+
+		var applied int
+		if err = store.DB().QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+			t.Fatal(err)
+		}
+		if applied != len(sqliteDialect.migrations) {
+			t.Errorf("%d migrations are recorded after opening the database %d times", applied, i+1)
+		}
+		store.Close()
+	}
+}
+
+// The default parameters are added to the ones of the location, which are kept
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		location string
+		dsn      string
+	}{
+		{"chain.db", "chain.db?_journal_mode=WAL&_busy_timeout=5000"},
+		{"chain.db?cache=shared", "chain.db?cache=shared&_journal_mode=WAL&_busy_timeout=5000"},
+		{"chain.db?", "chain.db?_journal_mode=WAL&_busy_timeout=5000"},
+		{"chain.db?_busy_timeout=10000", "chain.db?_busy_timeout=10000&_journal_mode=WAL"},
+		{"chain.db?_journal=DELETE&_timeout=100", "chain.db?_journal=DELETE&_timeout=100"},
+		{"file:chain.db?mode=rwc&_journal_mode=TRUNCATE", "file:chain.db?mode=rwc&_journal_mode=TRUNCATE&_busy_timeout=5000"},
+	}
+	for _, test := range tests {
+		if dsn := sqliteDSN(test.location); dsn != test.dsn {
+			t.Errorf("the location %q opens %q, expected %q", test.location, dsn, test.dsn)
+		}
+	}
+
+	// The parameters of the location reach the connection
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "chain.db") + "?_busy_timeout=10000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	var timeout int
+	if err = store.db.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil || timeout != 10000 {
+		t.Errorf("the busy timeout of the connection is %d (%v), expected 10000", timeout, err)
+	}
+}
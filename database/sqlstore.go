@@ -0,0 +1,383 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// sqlDialect holds what changes between the SQL engines. The queries are written with "?" placeholders
+type sqlDialect struct {
+	// Statements to create and upgrade the schema. The position of each statement (starting at 1) is their
+	// version, so new statements are only appended at the end
+	migrations []string
+	// Rewrites the placeholders of a query to the ones of the engine
+	rebind func(query string) string
+	// Statement that locks the schema until the end of the transaction, to apply the migrations. Optional
+	lock string
+	// Returns true if the error is the violation of a unique index, as the one of the heights. Optional
+	uniqueViolation func(err error) bool
+}
+
+// SQLStore implements the KVStore interface over a SQL database. The blocks are stored in relational form in the
+// "blocks" table (hash, height, timestamp, ticker, addresses, prices and the full block as JSON in "payload"),
+// so the chain can be queried with plain SQL. The arbitrary values live in the "kv_values" table.
+// As in the KV stores, StoreBlock rejects a different block at a stored height with a *ConflictError, and the
+// blocks created in the same second are all kept. The heights have a unique index, so the nodes sharing the
+// database can´t store two blocks at the same height, even when they write at the same time
+type SQLStore struct {
+	db      *sql.DB
+	dialect sqlDialect
+}
+
+// Creates the store over an opened database, upgrading the schema if needed
+func newSQLStore(db *sql.DB, dialect sqlDialect) (*SQLStore, error) {
+	s := &SQLStore{db: db, dialect: dialect}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Apply the migrations not applied yet, one by one. Each one runs in their own transaction together with the
+// update of the version, so a failed migration can be retried. When the dialect has a lock, many nodes can start
+// at the same time over the same database: the table of the versions is created and read once the lock is held
+func (s *SQLStore) migrate() error {
+	for {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		var current int
+		if s.dialect.lock != "" {
+			_, err = tx.Exec(s.dialect.lock)
+		}
+		if err == nil {
+			_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`)
+		}
+		if err == nil {
+			err = tx.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if current >= len(s.dialect.migrations) {
+			return tx.Commit() // Up to date
+		}
+
+		version := current + 1
+		if _, err = tx.Exec(s.dialect.migrations[version-1]); err == nil {
+			_, err = tx.Exec(s.dialect.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), version)
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("database: migration %d failed: %v", version, err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+}
+
+// SchemaVersion returns the version of the last migration applied to the database
+func (s *SQLStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+
+	return version, err
+}
+
+// DB returns the database behind the store, to run ad-hoc queries over the blocks table
+func (s *SQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close releases the database
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Health returns an error if the database can´t be reached
+func (s *SQLStore) Health() error {
+	return s.db.Ping()
+}
+
+// The statements share this interface, so the writes work inside and outside a transaction
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Insert or replace a block
+func (s *SQLStore) writeBlock(ex sqlExecer, block types.FullSignedBlock) error {
+	payload, err := json.Marshal(block)
+	if err != nil {
+		return err
+	}
+
+	_, err = ex.Exec(s.dialect.rebind(`INSERT INTO blocks
+		(hash, height, timestamp, ticker, previous_hash, address, avg_price, avg_volume, payload)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (hash) DO UPDATE SET height = excluded.height, timestamp = excluded.timestamp,
+		ticker = excluded.ticker, previous_hash = excluded.previous_hash, address = excluded.address,
+		avg_price = excluded.avg_price, avg_volume = excluded.avg_volume, payload = excluded.payload`),
+		block.Hash, int64(block.Height), int64(block.Timestamp), block.Ticker, block.PreviousHash, block.Address,
+		block.AveragePrice, block.AverageVolume, string(payload))
+
+	return err
+}
+
+// Read the payload of the first row of a query as a block
+func (s *SQLStore) queryBlock(query string, args ...interface{}) (*types.FullSignedBlock, error) {
+	var payload string
+	err := s.db.QueryRow(s.dialect.rebind(query), args...).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.ErrNotFound // Same error than the KV stores
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var block types.FullSignedBlock
+	if err = json.Unmarshal([]byte(payload), &block); err != nil {
+		return nil, err
+	}
+
+	return &block, nil
+}
+
+// Read the payloads of all the rows of a query as blocks
+func (s *SQLStore) queryBlocks(query string, args ...interface{}) ([]types.FullSignedBlock, error) {
+	rows, err := s.db.Query(s.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var blocks []types.FullSignedBlock
+	for rows.Next() {
+		var payload string
+		if err = rows.Scan(&payload); err != nil {
+			return nil, err
+		}
+
+		var block types.FullSignedBlock
+		if err = json.Unmarshal([]byte(payload), &block); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+
+	return blocks, rows.Err()
+}
+
+// StoreValue stores an abritrary value, indexed by a string
+func (s *SQLStore) StoreValue(key string, value []byte) error {
+	_, err := s.db.Exec(s.dialect.rebind(`INSERT INTO kv_values (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`), key, value)
+
+	return err
+}
+
+// GetValue returns a value indexed by an string
+func (s *SQLStore) GetValue(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow(s.dialect.rebind(`SELECT value FROM kv_values WHERE key = ?`), key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, types.ErrNotFound
+	}
+
+	return value, err
+}
+
+// StoreBlock stores a full block. Storing the same block again does nothing, and a different block at the same
+// height or with the same hash is a *ConflictError
+func (s *SQLStore) StoreBlock(block types.FullSignedBlock) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	hashAtHeight, stored := "", (*types.FullSignedBlock)(nil)
+	rows, err := tx.Query(s.dialect.rebind(`SELECT hash, height, payload FROM blocks WHERE height = ? OR hash = ?`),
+		int64(block.Height), block.Hash)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for rows.Next() {
+		var hash, payload string
+		var height int64
+		if err = rows.Scan(&hash, &height, &payload); err != nil {
+			break
+		}
+
+		if uint64(height) == block.Height {
+			hashAtHeight = hash
+		}
+		if hash == block.Hash {
+			stored = new(types.FullSignedBlock)
+			if err = json.Unmarshal([]byte(payload), stored); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	rows.Close()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	exists, err := checkStoredBlock(block, hashAtHeight, stored)
+	if err == nil && !exists {
+		err = s.writeBlock(tx, block)
+	}
+	if err != nil {
+		tx.Rollback()
+		return s.conflictError(err, block)
+	}
+
+	return tx.Commit()
+}
+
+// StoreBlocks stores many blocks in a single transaction
+func (s *SQLStore) StoreBlocks(blocks []types.FullSignedBlock) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		if err = s.writeBlock(tx, block); err != nil {
+			tx.Rollback()
+			return s.conflictError(err, block)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Return a *ConflictError if the write of the block violated the unique index of the heights: another node stored
+// a block at the height after it was checked. Any other error is returned as it is. The transaction of the write
+// must be rolled back before, to read the stored block
+func (s *SQLStore) conflictError(err error, block types.FullSignedBlock) error {
+	if s.dialect.uniqueViolation == nil || !s.dialect.uniqueViolation(err) {
+		return err
+	}
+
+	var stored string
+	row := s.db.QueryRow(s.dialect.rebind(`SELECT hash FROM blocks WHERE height = ?`), int64(block.Height))
+	if scanErr := row.Scan(&stored); scanErr != nil {
+		return err
+	}
+
+	return &ConflictError{Height: block.Height, Hash: block.Hash, StoredHash: stored}
+}
+
+// GetBlock reads a block using their hash
+func (s *SQLStore) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	return s.queryBlock(`SELECT payload FROM blocks WHERE hash = ?`, hash)
+}
+
+// FindBlockByTimestamp reads a block using their timestamp. If many blocks were created in the same second, the
+// newest one
+func (s *SQLStore) FindBlockByTimestamp(timestamp uint64) (*types.FullSignedBlock, error) {
+	return s.queryBlock(`SELECT payload FROM blocks WHERE timestamp = ? ORDER BY height DESC LIMIT 1`, int64(timestamp))
+}
+
+// FindBlockByHeight reads a block using their height
+func (s *SQLStore) FindBlockByHeight(Height uint64) (*types.FullSignedBlock, error) {
+	return s.queryBlock(`SELECT payload FROM blocks WHERE height = ? LIMIT 1`, int64(Height))
+}
+
+// GetLatestBlocks returns the blocks created at or before the timestamp, the newest block first, skipping the
+// first offset blocks and returning no more than limit blocks, unless limit is zero
+func (s *SQLStore) GetLatestBlocks(timestamp uint64, offset int, limit int) ([]types.FullSignedBlock, error) {
+	if limit == 0 {
+		limit = math.MaxInt64 // No limit. The engines disagree on how to write it, but not on a limit over all the rows
+	}
+
+	return s.queryBlocks(`SELECT payload FROM blocks WHERE timestamp <= ? ORDER BY timestamp DESC, height DESC LIMIT ? OFFSET ?`,
+		int64(timestamp), limit, offset)
+}
+
+// GetBlocksByHeightRange returns the blocks between the heights from and to, both included
+func (s *SQLStore) GetBlocksByHeightRange(from uint64, to uint64) ([]types.FullSignedBlock, error) {
+	return s.queryBlocks(`SELECT payload FROM blocks WHERE height BETWEEN ? AND ? ORDER BY height`,
+		int64(from), int64(to))
+}
+
+// FindBlocksBetween returns the blocks created between startTs and endTs, both included, the oldest first.
+// No more than limit blocks are returned, unless limit is zero
+func (s *SQLStore) FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]types.FullSignedBlock, error) {
+	query := `SELECT payload FROM blocks WHERE timestamp BETWEEN ? AND ? ORDER BY timestamp, height`
+	if limit > 0 {
+		return s.queryBlocks(query+` LIMIT ?`, int64(startTs), int64(endTs), limit)
+	}
+
+	return s.queryBlocks(query, int64(startTs), int64(endTs))
+}
+
+// GetHead returns the block at the tip of the chain, the one with the greatest height. The blocks are written
+// in a single statement or transaction, so the table is always its own head pointer
+func (s *SQLStore) GetHead() (*types.FullSignedBlock, error) {
+	return s.queryBlock(`SELECT payload FROM blocks ORDER BY height DESC LIMIT 1`)
+}
+
+// GetLatestHeight returns the height of the tip of the chain, without reading the block
+func (s *SQLStore) GetLatestHeight() (uint64, error) {
+	var height sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MAX(height) FROM blocks`).Scan(&height); err != nil {
+		return 0, err
+	}
+	if !height.Valid {
+		return 0, types.ErrNotFound // Empty store
+	}
+
+	return uint64(height.Int64), nil
+}
+
+// GetBlocksByTicker returns the blocks of a ticker, the newest first. No more than limit blocks are returned,
+// unless limit is zero
+func (s *SQLStore) GetBlocksByTicker(ticker string, limit int) ([]types.FullSignedBlock, error) {
+	query := `SELECT payload FROM blocks WHERE ticker = ? ORDER BY height DESC`
+	if limit > 0 {
+		return s.queryBlocks(query+` LIMIT ?`, ticker, limit)
+	}
+
+	return s.queryBlocks(query, ticker)
+}
+
+// GetBlocksByAddress returns the blocks produced by the node with the address, the newest first. No more than
+// limit blocks are returned, unless limit is zero
+func (s *SQLStore) GetBlocksByAddress(address string, limit int) ([]types.FullSignedBlock, error) {
+	query := `SELECT payload FROM blocks WHERE address = ? ORDER BY height DESC`
+	if limit > 0 {
+		return s.queryBlocks(query+` LIMIT ?`, address, limit)
+	}
+
+	return s.queryBlocks(query, address)
+}
+
+// FindBlocksByHashPrefix returns the blocks whose hash starts with the prefix, sorted by hash. No more than limit
+// blocks are returned, unless limit is zero. The prefix is compared with substr, not LIKE, so it is case sensitive
+// in all the dialects and needs no escaping
+func (s *SQLStore) FindBlocksByHashPrefix(prefix string, limit int) ([]types.FullSignedBlock, error) {
+	query := `SELECT payload FROM blocks WHERE substr(hash, 1, ?) = ? ORDER BY hash`
+	if limit > 0 {
+		return s.queryBlocks(query+` LIMIT ?`, len(prefix), prefix, limit)
+	}
+
+	return s.queryBlocks(query, len(prefix), prefix)
+}
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/options"
+)
+
+const (
+	// DefaultDataKeyRotation is how often Badger creates a new data key when the store is encrypted
+	DefaultDataKeyRotation = 10 * 24 * time.Hour
+
+	// DefaultBlockCacheSize is the size of the cache of the blocks of the tables when they are compressed or
+	// encrypted. Without it, Badger decompresses or decrypts the block on each read
+	DefaultBlockCacheSize = 256 << 20
+
+	// Badger keeps the decrypted indexes of the tables in this cache, so it is needed when the store is encrypted
+	encryptedIndexCacheSize = 64 << 20
+)
+
+// ErrReadOnly is returned by the writes to a store opened read-only
+var ErrReadOnly = errors.New("database: the store is read-only")
+
+// TableCompression is how Badger compresses the blocks of their tables (the keys and the small values)
+type TableCompression string
+
+const (
+	// CompressionNone doesn´t compress the tables, the Badger default
+	CompressionNone TableCompression = "none"
+	// CompressionSnappy is fast, with a lower ratio
+	CompressionSnappy TableCompression = "snappy"
+	// CompressionZSTD has a better ratio, but uses more CPU
+	CompressionZSTD TableCompression = "zstd"
+)
+
+// StoreConfig holds the settings of a Badger store
+type StoreConfig struct {
+	// EncryptionKey is the master key to encrypt the data at rest with AES. The size of the key (16, 24 or 32 bytes)
+	// selects AES-128, AES-192 or AES-256. The data is not encrypted if empty. The master key only encrypts the
+	// data keys, so it can be changed with RotateEncryptionKey without rewriting the data
+	EncryptionKey []byte
+	// DataKeyRotation is how often a new data key is created for the new data. DefaultDataKeyRotation if zero
+	DataKeyRotation time.Duration
+
+	// CompressBlocks compresses the blocks with zstd before writing them. The blocks are read in both forms, so
+	// it can be enabled or disabled at any moment
+	CompressBlocks bool
+
+	// ReadOnly opens the database without write access, so many processes (analytics tools, standby replicas) can
+	// read the same directory or a snapshot. All the writes fail with ErrReadOnly. Badger can´t open read-only a
+	// directory that was not closed cleanly
+	ReadOnly bool
+
+	// DiskQuota is how many bytes the database can use on disk. The writes of blocks and values fail with
+	// ErrQuotaExceeded while it is over the quota. The usage is refreshed each minute, so it can go a bit over the
+	// quota before the writes are refused. No limit if zero
+	DiskQuota int64
+
+	// The settings below tune Badger for the hardware. The zero values keep the defaults, chosen with the benchmarks of the store
+
+	// ValueLogFileSize is the size in bytes of each value log file, before a new one is created. Smaller files are
+	// collected sooner by the GC. 1 GB if zero
+	ValueLogFileSize int64
+	// Compression of the tables. The blocks are compressed apart, with CompressBlocks
+	Compression TableCompression
+	// BlockCacheSize is how many bytes of the blocks of the tables are kept decompressed and decrypted. It is only
+	// used when the tables are compressed or encrypted. DefaultBlockCacheSize if zero
+	BlockCacheSize int64
+	// DisableSyncWrites doesn´t wait for each write to reach the disk. The writes are faster, but the last ones
+	// can be lost if the machine crashes (not if only the process does)
+	DisableSyncWrites bool
+	// InMemory keeps all the data in memory, without files, for tests and caches. The data is lost on close, so it
+	// needs a store held open by Open
+	InMemory bool
+	// DisableConflictDetection doesn´t check the transactions for conflicts, so the writes are faster. The blocks
+	// of a chain must then be written from a single goroutine
+	DisableConflictDetection bool
+	// Truncate removes the corrupt end of the value log left by a crash, instead of failing to open the database.
+	// The writes that were not synced are lost
+	Truncate bool
+}
+
+// Badger compression type for the setting
+func (c TableCompression) badger() (options.CompressionType, error) {
+	switch c {
+	case "", CompressionNone:
+		return options.None, nil
+	case CompressionSnappy:
+		return options.Snappy, nil
+	case CompressionZSTD:
+		return options.ZSTD, nil
+	}
+
+	return options.None, fmt.Errorf("database: unknown table compression %q", c)
+}
+
+// Badger options for the config
+func (c StoreConfig) options(locationDirectory string) (badger.Options, error) {
+	if c.InMemory {
+		locationDirectory = "" // Badger refuses a directory in memory
+	}
+	opts := badger.DefaultOptions(locationDirectory)
+
+	compression, err := c.Compression.badger()
+	if err != nil {
+		return opts, err
+	}
+	opts = opts.WithCompression(compression).
+		WithSyncWrites(!c.DisableSyncWrites).
+		WithInMemory(c.InMemory).
+		WithDetectConflicts(!c.DisableConflictDetection).
+		WithTruncate(c.Truncate)
+	if compression != options.None || len(c.EncryptionKey) > 0 {
+		cache := c.BlockCacheSize
+		if cache == 0 {
+			cache = DefaultBlockCacheSize
+		}
+		opts = opts.WithBlockCacheSize(cache)
+	}
+	if c.ValueLogFileSize > 0 {
+		opts = opts.WithValueLogFileSize(c.ValueLogFileSize)
+	}
+
+	if len(c.EncryptionKey) > 0 {
+		rotation := c.DataKeyRotation
+		if rotation == 0 {
+			rotation = DefaultDataKeyRotation
+		}
+
+		opts = opts.WithEncryptionKey(c.EncryptionKey).
+			WithEncryptionKeyRotationDuration(rotation).
+			WithIndexCacheSize(encryptedIndexCacheSize)
+	}
+
+	return opts.WithReadOnly(c.ReadOnly), nil
+}
+
+// Checks that the store can be written
+func (s Store) writable() error {
+	if s.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	return nil
+}
+
+// RotateEncryptionKey changes the master key of an encrypted store. Only the registry of data keys is written
+// again, encrypted with the new key. The store must be closed, so no other operation can be running
+func RotateEncryptionKey(locationDirectory string, oldKey []byte, newKey []byte) error {
+	opts := badger.KeyRegistryOptions{
+		Dir:                           locationDirectory,
+		ReadOnly:                      true,
+		EncryptionKey:                 oldKey,
+		EncryptionKeyRotationDuration: DefaultDataKeyRotation,
+	}
+
+	registry, err := badger.OpenKeyRegistry(opts)
+	if err != nil {
+		return err
+	}
+	defer registry.Close()
+
+	opts.EncryptionKey = newKey
+	return badger.WriteKeyRegistry(registry, opts)
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// Read a block of the store opened with the config, reporting the panic of a store that can´t be opened as an error
+func readsBlock(dir string, config StoreConfig, hash string) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	_, err := NewKVStoreWithConfig(dir, config).GetBlock(hash)
+
+	return err == nil
+}
+
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	store := NewKVStoreWithConfig(t.TempDir(), StoreConfig{EncryptionKey: bytes.Repeat([]byte{1}, 32)})
+	testStoreRoundTrip(t, store)
+}
+
+// The files of the store don´t hold the data in clear, and the store only opens with their key
+func TestEncryptionKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16)
+	block := testChain(t, 1, 1600000000)[0]
+	block.Ticker = "PLAINTEXTTICKER"
+	if err := block.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewKVStoreWithConfig(dir, StoreConfig{EncryptionKey: oldKey}).(*Store)
+	if err := store.StoreBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(content, []byte(block.Ticker)) {
+			t.Errorf("the file %s holds the data in clear", filepath.Base(file))
+		}
+	}
+
+	if err = RotateEncryptionKey(dir, oldKey, newKey); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		key   []byte
+		reads bool
+	}{
+		{"the new key", newKey, true},
+		{"the old key", oldKey, false},
+		{"no key", nil, false},
+	}
+	for _, test := range tests {
+		if reads := readsBlock(dir, StoreConfig{EncryptionKey: test.key}, block.Hash); reads != test.reads {
+			t.Errorf("the store opened with %s read the block: %t, expected %t", test.name, reads, test.reads)
+		}
+	}
+
+	if err = RotateEncryptionKey(dir, oldKey, newKey); err == nil {
+		t.Error("the key was rotated with the old key")
+	}
+}
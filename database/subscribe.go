@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// How many written blocks wait in the channel of a subscription for the reader
+const subscriptionBufferSize = 64
+
+// ErrNotOpen is returned by the operations that need the database held open by Open
+var ErrNotOpen = errors.New("database: the store is not held open, use Open")
+
+// ErrSubscribeUnsupported is returned by SubscribeStore for the backends that can´t deliver their writes
+var ErrSubscribeUnsupported = errors.New("database: the backend can´t be subscribed to")
+
+// Subscriber is implemented by the stores that deliver the blocks written to them
+type Subscriber interface {
+	// Subscribe returns a subscription to the blocks written from now on whose hash starts with the prefix
+	Subscribe(prefix string) (*Subscription, error)
+}
+
+// SubscribeStore subscribes to the blocks written to a store, or returns ErrSubscribeUnsupported if their backend
+// can´t deliver them
+func SubscribeStore(store types.KVStore, prefix string) (*Subscription, error) {
+	if subscriber, ok := store.(Subscriber); ok {
+		return subscriber.Subscribe(prefix)
+	}
+
+	return nil, ErrSubscribeUnsupported
+}
+
+// Subscription delivers the blocks written to a store, in the order they were written. The blocks written together
+// by StoreBlocks come in any order
+type Subscription struct {
+	// Blocks receives each written block. It is closed when the subscription ends
+	Blocks <-chan types.FullSignedBlock
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Subscribe returns a subscription to the blocks written from now on whose hash starts with the prefix (all blocks
+// if empty), so the writes can be followed without polling the store. The blocks rewritten by StoreBlocks (as the
+// pruner does) are delivered again.
+// Badger waits for the readers of the subscriptions, so the blocks must be read as they arrive, or the writes stall.
+// Badger registers the subscription asynchronously, so the blocks written while subscribing may be missed. It only
+// works for the stores held open by Open, else ErrNotOpen is returned
+func (s *Store) Subscribe(prefix string) (*Subscription, error) {
+	if s.db == nil {
+		return nil, ErrNotOpen
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	blocks := make(chan types.FullSignedBlock, subscriptionBufferSize)
+	sub := &Subscription{
+		Blocks: blocks,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	key := s.namespace.key(stringIndexKey(prefix, HashKeyPrefix))
+	go func() {
+		defer close(sub.done)
+		defer close(blocks)
+
+		err := s.db.Subscribe(ctx, func(list *badger.KVList) error {
+			for _, kv := range list.Kv {
+				if len(kv.Value) == 0 {
+					continue // A deleted key
+				}
+
+				block, err := decodeBlock(kv.Value)
+				if err != nil {
+					return err
+				}
+
+				select {
+				case blocks <- *block:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			return nil
+		}, key)
+		if err != context.Canceled {
+			sub.err = err
+		}
+	}()
+
+	return sub, nil
+}
+
+// Close ends the subscription and returns the error that ended it before, if any
+func (sub *Subscription) Close() error {
+	sub.cancel()
+	<-sub.done
+
+	return sub.err
+}
@@ -0,0 +1,202 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+func testOpenStore(t *testing.T) *Store {
+	store, err := Open(t.TempDir(), StoreConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+// Receive a block, failing after a while
+func receiveBlock(t *testing.T, blocks <-chan types.FullSignedBlock) types.FullSignedBlock {
+	select {
+	case block, open := <-blocks:
+		if !open {
+			t.Fatal("the subscription ended")
+		}
+		return block
+	case <-time.After(5 * time.Second):
+		t.Fatal("no block was delivered")
+	}
+
+	return types.FullSignedBlock{}
+}
+
+// Badger registers the subscriptions asynchronously, so the probe block is written until it is delivered. The
+// blocks written after it can´t be missed
+func waitSubscribed(t *testing.T, store types.KVStore, sub *Subscription, probe types.FullSignedBlock) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := store.StoreBlocks([]types.FullSignedBlock{probe}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case block := <-sub.Blocks:
+			if block.Hash != probe.Hash {
+				t.Fatalf("the subscription delivered the block %s before the probe", block.Hash)
+			}
+			// Drain the probes written while waiting
+			for {
+				select {
+				case block = <-sub.Blocks:
+					if block.Hash != probe.Hash {
+						t.Fatalf("the subscription delivered the block %s before the probe", block.Hash)
+					}
+				case <-time.After(50 * time.Millisecond):
+					return
+				}
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("the subscription was never registered")
+}
+
+func TestSubscribeDelivers(t *testing.T) {
+	store := testOpenStore(t)
+	blocks := testChain(t, 5, 1600000000)
+	sub, err := store.Subscribe("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribed(t, store, sub, blocks[0])
+
+	if err = store.StoreBlocks(blocks[1:3]); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks[3:] {
+		if err = store.StoreBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The blocks of the batch come in any order, the others in the order they were written
+	batch := map[string]bool{blocks[1].Hash: true, blocks[2].Hash: true}
+	for range batch {
+		if block := receiveBlock(t, sub.Blocks); !batch[block.Hash] {
+			t.Errorf("the subscription delivered the block %d %s, expected one of the batch", block.Height, block.Hash)
+		}
+	}
+	for _, expected := range blocks[3:] {
+		if block := receiveBlock(t, sub.Blocks); block.Hash != expected.Hash || block.Height != expected.Height {
+			t.Errorf("the subscription delivered the block %d %s, expected %d %s", block.Height, block.Hash, expected.Height, expected.Hash)
+		}
+	}
+
+	if err = sub.Close(); err != nil {
+		t.Errorf("closing the subscription returned %v", err)
+	}
+	if _, open := <-sub.Blocks; open {
+		t.Error("the blocks channel is open after closing the subscription")
+	}
+}
+
+// Only the blocks whose hash starts with the prefix, and in the namespace of the store, are delivered
+func TestSubscribeFilters(t *testing.T) {
+	store := testOpenStore(t)
+	other, err := store.Namespace("ETHUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := testChain(t, 40, 1600000000)
+
+	// The most common prefix of the chain, and a block with it as the probe
+	counts := make(map[string]int)
+	prefix := ""
+	for _, block := range blocks {
+		counts[block.Hash[:3]]++
+		if counts[block.Hash[:3]] > counts[prefix] {
+			prefix = block.Hash[:3]
+		}
+	}
+	var probe types.FullSignedBlock
+	expected := make(map[string]bool)
+	for _, block := range blocks {
+		if block.Hash[:3] != prefix {
+			continue
+		}
+		if probe.Hash == "" {
+			probe = block
+		} else {
+			expected[block.Hash] = true
+		}
+	}
+	if len(expected) == 0 {
+		t.Fatal("no two blocks of the chain share a prefix")
+	}
+
+	sub, err := store.Subscribe(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	waitSubscribed(t, store, sub, probe)
+
+	if err = other.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.StoreBlocks(blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	// The probe is written again with the rest of the chain
+	expected[probe.Hash] = true
+	for range expected {
+		if block := receiveBlock(t, sub.Blocks); !expected[block.Hash] {
+			t.Errorf("the subscription delivered the block %s out of the prefix %s", block.Hash, prefix)
+		}
+	}
+	select {
+	case block := <-sub.Blocks:
+		t.Errorf("the subscription delivered the block %s out of the filter", block.Hash)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSubscribeNotOpen(t *testing.T) {
+	if _, err := NewKVStore(t.TempDir()).(*Store).Subscribe(""); !errors.Is(err, ErrNotOpen) {
+		t.Errorf("subscribing to a store opened on each call returned %v", err)
+	}
+}
+
+// The replicator runs a pass for each notified block, without waiting for the interval
+func TestReplicatorNotify(t *testing.T) {
+	store := testOpenStore(t)
+	blocks := testChain(t, 4, 1600000000)
+	sub, err := store.Subscribe("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	waitSubscribed(t, store, sub, blocks[0])
+
+	secondary := NewMemoryStore()
+	replicator := NewReplicator(store, secondary, ReplicationConfig{Interval: time.Hour, Notify: sub.Blocks}, nil)
+	replicator.Start()
+	defer replicator.Stop()
+
+	if err = store.StoreBlocks(blocks[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if height, err := secondary.GetLatestHeight(); err == nil && height == 3 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("the notified blocks were not replicated")
+}
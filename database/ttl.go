@@ -0,0 +1,55 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// ErrTTLUnsupported is returned by StoreValueWithTTL for the backends that can´t expire the values
+var ErrTTLUnsupported = errors.New("database: the backend can´t expire the values")
+
+// ValueExpirer is implemented by the stores that can store values that expire
+type ValueExpirer interface {
+	// StoreValueWithTTL stores a value that is removed once the ttl has passed
+	StoreValueWithTTL(key string, value []byte, ttl time.Duration) error
+}
+
+// StoreValueWithTTL stores a value in a store that expires after the ttl, so the transient items (sessions, rate
+// limits, caches) don´t need to be removed. Returns ErrTTLUnsupported if the backend can´t expire the values
+func StoreValueWithTTL(store types.KVStore, key string, value []byte, ttl time.Duration) error {
+	if expirer, ok := store.(ValueExpirer); ok {
+		return expirer.StoreValueWithTTL(key, value, ttl)
+	}
+
+	return ErrTTLUnsupported
+}
+
+// StoreValueWithTTL stores an abritrary value, indexed by a string, that expires after the ttl. Badger keeps the
+// expiration in seconds, so the ttl is rounded. Once expired, GetValue returns types.ErrNotFound, and the space
+// is freed by the compactions. Storing the value again with StoreValue removes the TTL
+func (s Store) StoreValueWithTTL(key string, value []byte, ttl time.Duration) error {
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer s.release(stor)
+
+	if err = s.checkQuota(stor); err != nil {
+		return err
+	}
+
+	return stor.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry(s.namespace.key(stringIndexKey(key, FixedKeyPrefix)), value).WithTTL(ttl)
+		return txn.SetEntry(entry)
+	})
+}
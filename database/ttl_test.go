@@ -0,0 +1,145 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Badger keeps the expiration in seconds, so the value is read until the next second has passed
+func TestStoreValueWithTTL(t *testing.T) {
+	store := NewKVStore(t.TempDir()).(*Store)
+	if err := store.StoreValueWithTTL("expires", []byte("value"), 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreValueWithTTL("cleared", []byte("value"), 2*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreValue("cleared", []byte("again")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.GetValue("expires"); err != nil || string(value) != "value" {
+		t.Fatalf("GetValue before the TTL returned %q (%v)", value, err)
+	}
+
+	time.Sleep(2100 * time.Millisecond)
+	if _, err := store.GetValue("expires"); !errors.Is(err, types.ErrNotFound) {
+		t.Errorf("GetValue after the TTL returned %v", err)
+	}
+	if value, err := store.GetValue("cleared"); err != nil || string(value) != "again" {
+		t.Errorf("GetValue of a value stored again without TTL returned %q (%v)", value, err)
+	}
+}
+
+func TestMemoryStoreValueWithTTL(t *testing.T) {
+	store := NewMemoryStore()
+	tests := []struct {
+		key     string
+		ttl     time.Duration
+		expired bool
+	}{
+		{"short", time.Millisecond, true},
+		{"long", time.Hour, false},
+	}
+	for _, test := range tests {
+		if err := store.StoreValueWithTTL(test.key, []byte(test.key), test.ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.StoreValueWithTTL("cleared", []byte("value"), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreValue("cleared", []byte("again")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	for _, test := range tests {
+		value, err := store.GetValue(test.key)
+		if test.expired && !errors.Is(err, types.ErrNotFound) {
+			t.Errorf("GetValue of the expired %s returned %q (%v)", test.key, value, err)
+		}
+		if !test.expired && (err != nil || string(value) != test.key) {
+			t.Errorf("GetValue of the %s returned %q (%v)", test.key, value, err)
+		}
+	}
+	if value, err := store.GetValue("cleared"); err != nil || string(value) != "again" {
+		t.Errorf("GetValue of a value stored again without TTL returned %q (%v)", value, err)
+	}
+
+	// The next write with a TTL removes the expired values
+	if err := store.StoreValueWithTTL("other", []byte("other"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := store.values["short"]; exists {
+		t.Error("the expired value is still stored after a write with a TTL")
+	}
+}
+
+// The value cached in Redis expires with the value, or with the TTL of the cache if it is shorter
+func TestRedisCacheValueWithTTL(t *testing.T) {
+	tests := []struct {
+		name     string
+		cacheTTL time.Duration
+		ttl      time.Duration
+		expected time.Duration
+	}{
+		{"without TTL of the cache", 0, time.Hour, time.Hour},
+		{"longer TTL of the cache", 2 * time.Hour, time.Hour, time.Hour},
+		{"shorter TTL of the cache", time.Minute, time.Hour, time.Minute},
+	}
+	for _, test := range tests {
+		server, err := miniredis.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		backing := NewMemoryStore()
+		cache, err := NewRedisCache(backing, RedisCacheConfig{Addr: server.Addr(), Prefix: "test:", TTL: test.cacheTTL})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = cache.StoreValueWithTTL("key", []byte("value"), test.ttl); err != nil {
+			t.Fatal(err)
+		}
+		if ttl := server.TTL("test:value:key"); ttl != test.expected {
+			t.Errorf("%s: the cached value expires in %v, expected %v", test.name, ttl, test.expected)
+		}
+		if _, expires := backing.expiries["key"]; !expires {
+			t.Errorf("%s: the value of the backing store doesn´t expire", test.name)
+		}
+
+		cache.Close()
+		server.Close()
+	}
+}
+
+// The wrappers forward the TTL, and the stores that can´t expire the values say it
+func TestStoreValueWithTTLWrappers(t *testing.T) {
+	metrics, err := NewStoreMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backing := NewMemoryStore()
+	store := NewLRUCache(NewMeteredStore(backing, metrics), 0)
+	if err = StoreValueWithTTL(store, "key", []byte("value"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, expires := backing.expiries["key"]; !expires {
+		t.Error("the value stored through the wrappers doesn´t expire")
+	}
+
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+	if err = StoreValueWithTTL(bolt, "key", []byte("value"), time.Hour); !errors.Is(err, ErrTTLUnsupported) {
+		t.Errorf("storing a value with TTL in bolt returned %v", err)
+	}
+}
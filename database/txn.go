@@ -0,0 +1,160 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrTxnUnsupported is returned by RunTxn for the backends that can´t write many keys atomically
+var ErrTxnUnsupported = errors.New("database: the backend doesn´t support transactions")
+
+// StoreTxn reads and writes the store inside a transaction. The reads see the writes of the same transaction, and
+// all the writes are committed at once when the function passed to Txn returns nil
+type StoreTxn interface {
+	// StoreBlock stores a block and their indexes, and moves the head pointer. It fails in the same way than
+	// the StoreBlock of the store
+	StoreBlock(block types.FullSignedBlock) error
+	// StoreValue stores an abritrary value, indexed by a string
+	StoreValue(key string, value []byte) error
+	// GetBlock reads a block using their hash
+	GetBlock(hash string) (*types.FullSignedBlock, error)
+	// FindBlockByHeight reads a block using their height as index
+	FindBlockByHeight(height uint64) (*types.FullSignedBlock, error)
+	// GetHead returns the block at the tip of the chain
+	GetHead() (*types.FullSignedBlock, error)
+	// GetValue returns a value indexed by a string
+	GetValue(key string) ([]byte, error)
+}
+
+// Transactor is implemented by the stores that can combine many writes in one atomic commit
+type Transactor interface {
+	// Txn runs fn in a read-write transaction, and commits it if fn returns nil. Any error discards all the writes
+	Txn(fn func(tx StoreTxn) error) error
+}
+
+// RunTxn runs fn in a transaction of the store, or returns ErrTxnUnsupported if their backend has no transactions
+func RunTxn(store types.KVStore, fn func(tx StoreTxn) error) error {
+	if transactor, ok := store.(Transactor); ok {
+		return transactor.Txn(fn)
+	}
+
+	return ErrTxnUnsupported
+}
+
+// Implements StoreTxn over a transaction of an ordered engine
+type orderedTxn struct {
+	r        orderedReader
+	set      func(key, value []byte) error
+	compress bool
+}
+
+func (tx orderedTxn) StoreBlock(block types.FullSignedBlock) error {
+	if stored, err := orderedCheckBlock(tx.r, block); err != nil || stored {
+		return err
+	}
+
+	if err := writeBlock(tx.set, block, tx.compress); err != nil {
+		return err
+	}
+
+	return orderedUpdateHead(tx.r, tx.set, []types.FullSignedBlock{block})
+}
+
+func (tx orderedTxn) StoreValue(key string, value []byte) error {
+	return tx.set(stringIndexKey(key, FixedKeyPrefix), value)
+}
+
+func (tx orderedTxn) GetBlock(hash string) (*types.FullSignedBlock, error) {
+	return orderedReadBlock(tx.r, hash)
+}
+
+func (tx orderedTxn) FindBlockByHeight(height uint64) (*types.FullSignedBlock, error) {
+	return orderedReadBlockByIndex(tx.r, height, HeightKeyPrefix)
+}
+
+func (tx orderedTxn) GetHead() (*types.FullSignedBlock, error) {
+	return orderedHead(tx.r)
+}
+
+func (tx orderedTxn) GetValue(key string) ([]byte, error) {
+	return tx.r.get(stringIndexKey(key, FixedKeyPrefix))
+}
+
+// Txn runs fn in a Badger transaction over the namespace of the store, so a block, the head pointer and the
+// values that describe it are written together or not at all. Badger limits the size of a transaction: fn gets
+// badger.ErrTxnTooBig if it writes too much, and the imports should use StoreBlocks instead
+func (s Store) Txn(fn func(tx StoreTxn) error) error {
+
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return err
+	}
+
+	defer s.release(stor)
+
+	if err = s.checkQuota(stor); err != nil {
+		return err
+	}
+
+	return stor.Update(func(txn *badger.Txn) error {
+		return fn(orderedTxn{r: s.reader(txn), set: s.namespace.setter(txn.Set), compress: s.config.CompressBlocks})
+	})
+}
+
+// Txn runs fn in a Bolt read-write transaction over the namespace of the store
+func (b *BoltStore) Txn(fn func(tx StoreTxn) error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		return fn(orderedTxn{r: b.namespace.reader(boltReader{bucket: bucket}), set: b.namespace.setter(bucket.Put)})
+	})
+}
+
+// Records the writes of a transaction, so the wrappers can update their caches once it is committed
+type recordedTxn struct {
+	StoreTxn
+	blocks []types.FullSignedBlock
+	values map[string][]byte
+}
+
+func (tx *recordedTxn) StoreBlock(block types.FullSignedBlock) error {
+	if err := tx.StoreTxn.StoreBlock(block); err != nil {
+		return err
+	}
+	tx.blocks = append(tx.blocks, block)
+
+	return nil
+}
+
+func (tx *recordedTxn) StoreValue(key string, value []byte) error {
+	if err := tx.StoreTxn.StoreValue(key, value); err != nil {
+		return err
+	}
+	if tx.values == nil {
+		tx.values = make(map[string][]byte)
+	}
+	tx.values[key] = append([]byte(nil), value...)
+
+	return nil
+}
+
+// Run a transaction of the store, returning the writes it committed
+func recordTxn(store types.KVStore, fn func(tx StoreTxn) error) (*recordedTxn, error) {
+	var recorded *recordedTxn
+	err := RunTxn(store, func(tx StoreTxn) error {
+		recorded = &recordedTxn{StoreTxn: tx}
+		return fn(recorded)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recorded, nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The engines with transactions
+func testTransactors(t *testing.T) []struct {
+	name  string
+	store types.KVStore
+} {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	namespaced, err := NewKVStore(t.TempDir()).(*Store).Namespace("ETHUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []struct {
+		name  string
+		store types.KVStore
+	}{
+		{"badger", NewKVStore(t.TempDir())},
+		{"badger namespace", namespaced},
+		{"bolt", bolt},
+	}
+}
+
+// The writes of a transaction are read inside it, and are committed together
+func TestTxnCommit(t *testing.T) {
+	for _, test := range testTransactors(t) {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := testChain(t, 2, 1600000000)
+			err := RunTxn(test.store, func(tx StoreTxn) error {
+				for _, block := range blocks {
+					if err := tx.StoreBlock(block); err != nil {
+						return err
+					}
+				}
+				if err := tx.StoreValue("latest", []byte(blocks[1].Hash)); err != nil {
+					return err
+				}
+
+				if head, err := tx.GetHead(); err != nil || head.Hash != blocks[1].Hash {
+					t.Errorf("GetHead inside the transaction returned %v (%v)", head, err)
+				}
+				if block, err := tx.FindBlockByHeight(0); err != nil || block.Hash != blocks[0].Hash {
+					t.Errorf("FindBlockByHeight inside the transaction returned %v (%v)", block, err)
+				}
+				if block, err := tx.GetBlock(blocks[1].Hash); err != nil || block.Height != 1 {
+					t.Errorf("GetBlock inside the transaction returned %v (%v)", block, err)
+				}
+				if value, err := tx.GetValue("latest"); err != nil || string(value) != blocks[1].Hash {
+					t.Errorf("GetValue inside the transaction returned %q (%v)", value, err)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if head, err := test.store.GetHead(); err != nil || head.Hash != blocks[1].Hash {
+				t.Errorf("GetHead after the commit returned %v (%v)", head, err)
+			}
+			if value, err := test.store.GetValue("latest"); err != nil || string(value) != blocks[1].Hash {
+				t.Errorf("GetValue after the commit returned %q (%v)", value, err)
+			}
+		})
+	}
+}
+
+// A failed transaction discards all their writes, the ones before the failure included
+func TestTxnRollback(t *testing.T) {
+	failed := errors.New("the transaction failed")
+	for _, test := range testTransactors(t) {
+		t.Run(test.name, func(t *testing.T) {
+			blocks := testChain(t, 3, 1600000000)
+			if err := test.store.StoreBlock(blocks[0]); err != nil {
+				t.Fatal(err)
+			}
+			other := blocks[0]
+			other.AveragePrice++
+			if err := other.CreateHash(); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				name string
+				fail func(tx StoreTxn) error
+				err  error
+			}{
+				{"error of the function", func(tx StoreTxn) error { return failed }, failed},
+				{"conflict", func(tx StoreTxn) error { return tx.StoreBlock(other) }, nil},
+			}
+			for _, rollback := range tests {
+				err := RunTxn(test.store, func(tx StoreTxn) error {
+					if err := tx.StoreBlock(blocks[1]); err != nil {
+						return err
+					}
+					if err := tx.StoreValue("latest", []byte(blocks[1].Hash)); err != nil {
+						return err
+					}
+					return rollback.fail(tx)
+				})
+
+				var conflict *ConflictError
+				if rollback.err != nil && !errors.Is(err, rollback.err) || rollback.err == nil && !errors.As(err, &conflict) {
+					t.Errorf("the transaction with a %s returned %v", rollback.name, err)
+				}
+				if _, err = test.store.GetBlock(blocks[1].Hash); err == nil {
+					t.Errorf("the block of the transaction with a %s was stored", rollback.name)
+				}
+				if head, err := test.store.GetHead(); err != nil || head.Hash != blocks[0].Hash {
+					t.Errorf("the head after the transaction with a %s is %v (%v)", rollback.name, head, err)
+				}
+				if _, err = test.store.GetValue("latest"); !errors.Is(err, types.ErrNotFound) {
+					t.Errorf("the value of the transaction with a %s returned %v", rollback.name, err)
+				}
+			}
+		})
+	}
+}
+
+// The caches keep the blocks of the committed transactions only
+func TestTxnWrappers(t *testing.T) {
+	metrics, err := NewStoreMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewLRUCache(NewMeteredStore(NewKVStore(t.TempDir()), metrics), 0)
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	redis, err := NewRedisCache(NewKVStore(t.TempDir()), RedisCacheConfig{Addr: server.Addr(), Prefix: "test:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer redis.Close()
+
+	tests := []struct {
+		name   string
+		store  types.KVStore
+		cached func(hash string) bool
+	}{
+		{"lru", cache, func(hash string) bool { _, cached := cache.get(hash); return cached }},
+		{"redis", redis, func(hash string) bool { return server.Exists("test:block:" + hash) }},
+	}
+	for _, test := range tests {
+		blocks := testChain(t, 2, 1600000000)
+		if err = RunTxn(test.store, func(tx StoreTxn) error { return tx.StoreBlock(blocks[0]) }); err != nil {
+			t.Fatal(err)
+		}
+		if !test.cached(blocks[0].Hash) {
+			t.Errorf("%s: the block of the committed transaction is not cached", test.name)
+		}
+
+		err = RunTxn(test.store, func(tx StoreTxn) error {
+			if err := tx.StoreBlock(blocks[1]); err != nil {
+				return err
+			}
+			return errors.New("the transaction failed")
+		})
+		if err == nil {
+			t.Fatalf("%s: the failed transaction was committed", test.name)
+		}
+		if test.cached(blocks[1].Hash) {
+			t.Errorf("%s: the block of the failed transaction is cached", test.name)
+		}
+	}
+
+	if err = RunTxn(NewMemoryStore(), func(tx StoreTxn) error { return nil }); !errors.Is(err, ErrTxnUnsupported) {
+		t.Errorf("a transaction over a memory store returned %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// BreakKind classifies the problems found by VerifyChain
+type BreakKind string
+
+const (
+	// BreakMissingBlock is an index entry that points to a block that is not stored
+	BreakMissingBlock BreakKind = "missing-block"
+	// BreakHeightGap is a range of heights without blocks
+	BreakHeightGap BreakKind = "height-gap"
+	// BreakBrokenLink is a block whose PreviousHash is not the hash of the block at the previous height
+	BreakBrokenLink BreakKind = "broken-link"
+	// BreakInvalidHash is a block whose content doesn´t match their hash
+	BreakInvalidHash BreakKind = "invalid-hash"
+	// BreakInvalidEvidence is a block with a result that doesn´t match their hash, or evidence that doesn´t match
+	// the Merkle root
+	BreakInvalidEvidence BreakKind = "invalid-evidence"
+	// BreakIndex is an index entry that is missing or points to another block
+	BreakIndex BreakKind = "index"
+)
+
+// ErrVerifyUnsupported is returned by VerifyStoreChain for the backends that can´t walk their chain
+var ErrVerifyUnsupported = errors.New("database: the backend can´t verify their chain")
+
+// ChainVerifier is implemented by the stores that can verify the blocks of their chain
+type ChainVerifier interface {
+	// VerifyChain walks the blocks between the heights from and to, both included, and reports their breaks
+	VerifyChain(from uint64, to uint64) (*ChainReport, error)
+}
+
+// VerifyStoreChain verifies the chain of a store, or returns ErrVerifyUnsupported if their backend can´t walk it
+func VerifyStoreChain(store types.KVStore, from uint64, to uint64) (*ChainReport, error) {
+	if verifier, ok := store.(ChainVerifier); ok {
+		return verifier.VerifyChain(from, to)
+	}
+
+	return nil, ErrVerifyUnsupported
+}
+
+// ChainBreak is a problem found at a height of the chain
+type ChainBreak struct {
+	Height uint64    `json:"height"`
+	Hash   string    `json:"hash"`
+	Kind   BreakKind `json:"kind"`
+	Detail string    `json:"detail"`
+}
+
+// ChainReport is the result of a verification of a segment of the chain
+type ChainReport struct {
+	From    uint64       `json:"from"`
+	To      uint64       `json:"to"`
+	Checked int          `json:"checked"` // How many blocks were read
+	Breaks  []ChainBreak `json:"breaks"`
+}
+
+// OK returns true if no problem was found
+func (r *ChainReport) OK() bool {
+	return len(r.Breaks) == 0
+}
+
+func (r *ChainReport) add(height uint64, hash string, kind BreakKind, format string, args ...interface{}) {
+	r.Breaks = append(r.Breaks, ChainBreak{Height: height, Hash: hash, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Walk the height index between from and to, both included, checking each block and their link with the previous
+// one. The bodies of the pruned blocks were removed, so their hashes can´t be checked
+func orderedVerifyChain(r orderedReader, from uint64, to uint64) (*ChainReport, error) {
+	report := &ChainReport{From: from, To: to}
+
+	var prunedHeight uint64
+	if value, err := r.get(stringIndexKey(PrunedHeightKey, FixedKeyPrefix)); err == nil {
+		prunedHeight, _ = strconv.ParseUint(string(value), 10, 64)
+	}
+
+	// The block before the segment, to check the link of the first one
+	var previous *types.FullSignedBlock
+	if from > 0 {
+		block, err := orderedReadBlockByIndex(r, from-1, HeightKeyPrefix)
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return nil, err
+		}
+		previous = block
+	}
+
+	expected := from
+	last := uintIndexKey(to, HeightKeyPrefix)
+	err := r.scan([]byte{HeightKeyPrefix}, uintIndexKey(from, HeightKeyPrefix), false, func(key, hash []byte) (bool, error) {
+		if bytes.Compare(key, last) > 0 {
+			return false, nil // Out of the range
+		}
+		height := binary.BigEndian.Uint64(key[1:])
+
+		if height > expected {
+			report.add(expected, "", BreakHeightGap, "no blocks from the height %d to %d", expected, height-1)
+			previous = nil
+		}
+		expected = height + 1
+
+		block, err := orderedReadBlock(r, string(hash))
+		if errors.Is(err, types.ErrNotFound) {
+			report.add(height, string(hash), BreakMissingBlock, "the height index points to a block that is not stored")
+			previous = nil
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		report.Checked++
+
+		if block.Hash != string(hash) || block.Height != height {
+			report.add(height, string(hash), BreakIndex, "the height index points to the block %s at the height %d", block.Hash, block.Height)
+		}
+
+		if height >= prunedHeight {
+			valid, err := block.CheckHash()
+			if err != nil {
+				return false, err
+			}
+			if !valid {
+				report.add(height, block.Hash, BreakInvalidHash, "the content doesn´t match the hash")
+			}
+
+			err = block.VerifyEvidence()
+			var invalid *types.ValidationError
+			if errors.As(err, &invalid) {
+				report.add(height, block.Hash, BreakInvalidEvidence, "%s", invalid.Detail)
+			} else if err != nil {
+				return false, err
+			}
+		}
+
+		switch {
+		case height == 0 && block.PreviousHash != "":
+			report.add(height, block.Hash, BreakBrokenLink, "the genesis block links to %s", block.PreviousHash)
+		case previous != nil && block.PreviousHash != previous.Hash:
+			report.add(height, block.Hash, BreakBrokenLink, "links to %s, but the previous block is %s", block.PreviousHash, previous.Hash)
+		}
+
+		if err = verifyIndexes(r, block, report); err != nil {
+			return false, err
+		}
+
+		previous = block
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Check the other indexes of a block
+func verifyIndexes(r orderedReader, block *types.FullSignedBlock, report *ChainReport) error {
+	indexes := []struct {
+		name string
+		key  []byte
+	}{
+		{"timestamp", timestampHeightKey(block.Timestamp, block.Height)},
+		{"ticker", stringHeightKey(block.Ticker, block.Height, TickerKeyPrefix)},
+		{"address", stringHeightKey(block.Address, block.Height, AddressKeyPrefix)},
+	}
+	for _, index := range indexes {
+		hash, err := r.get(index.key)
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		if string(hash) != block.Hash {
+			report.add(block.Height, block.Hash, BreakIndex, "the %s index doesn´t point to the block", index.name)
+		}
+	}
+
+	return nil
+}
+
+// VerifyChain walks the blocks between the heights from and to, both included, and reports the broken links, the
+// invalid hashes and evidence, the gaps and the inconsistent indexes. The breaks are part of the report, the error is only for
+// the failures reading the store
+func (s Store) VerifyChain(from uint64, to uint64) (*ChainReport, error) {
+	// Open badger
+	stor, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.release(stor)
+
+	var report *ChainReport
+	err = stor.View(func(txn *badger.Txn) error {
+		report, err = orderedVerifyChain(s.reader(txn), from, to)
+		return err
+	})
+
+	return report, err
+}
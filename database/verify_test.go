@@ -0,0 +1,169 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/dgraph-io/badger/v2"
+)
+
+// The stores that can verify their chain
+type chainVerifier interface {
+	types.KVStore
+	VerifyChain(from uint64, to uint64) (*ChainReport, error)
+}
+
+func TestVerifyChainClean(t *testing.T) {
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "chain.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+	level, err := NewLevelDBStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer level.Close()
+
+	stores := []struct {
+		name  string
+		store chainVerifier
+	}{
+		{"badger", NewKVStore(t.TempDir()).(*Store)},
+		{"bolt", bolt},
+		{"leveldb", level},
+	}
+	for _, test := range stores {
+		if err := test.store.StoreBlocks(testChain(t, 6, 1600000000)); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := test.store.VerifyChain(0, 5)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !report.OK() || report.Checked != 6 {
+			t.Errorf("%s: the report of a whole chain checked %d blocks with the breaks %v", test.name, report.Checked, report.Breaks)
+		}
+
+		// A segment checks the link with the block before it
+		if report, err = test.store.VerifyChain(2, 3); err != nil || !report.OK() || report.Checked != 2 {
+			t.Errorf("%s: the report of the segment 2-3 is %v (%v)", test.name, report, err)
+		}
+	}
+}
+
+// Break a stored chain of six blocks in each possible way and check the report
+func TestVerifyChainBreaks(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(txn *badger.Txn, blocks []types.FullSignedBlock) error
+		height uint64
+		kind   BreakKind
+	}{
+		{"changed content", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			changed := blocks[3]
+			changed.Memo = "changed"
+			return writeBlock(txn.Set, changed, false)
+		}, 3, BreakInvalidHash},
+		{"tampered evidence", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			tampered := blocks[3]
+			tampered.Evidence = []types.Result{{CrawlerName: "binance", Ticker: "BTCUSD", Hash: "aa"}}
+			if err := tampered.CreateHash(); err != nil {
+				return err
+			}
+			return writeBlock(txn.Set, tampered, false)
+		}, 3, BreakInvalidEvidence},
+		{"height gap", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Delete(uintIndexKey(2, HeightKeyPrefix))
+		}, 2, BreakHeightGap},
+		{"missing block", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Delete(stringIndexKey(blocks[4].Hash, HashKeyPrefix))
+		}, 4, BreakMissingBlock},
+		{"broken link", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			relinked := blocks[5]
+			relinked.PreviousHash = blocks[3].Hash
+			if err := relinked.CreateHash(); err != nil {
+				return err
+			}
+			return writeBlock(txn.Set, relinked, false)
+		}, 5, BreakBrokenLink},
+		{"genesis with a link", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			genesis := blocks[0]
+			genesis.PreviousHash = blocks[5].Hash
+			if err := genesis.CreateHash(); err != nil {
+				return err
+			}
+			return writeBlock(txn.Set, genesis, false)
+		}, 0, BreakBrokenLink},
+		{"ticker index", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Set(stringHeightKey("BTCUSD", 1, TickerKeyPrefix), []byte(blocks[2].Hash))
+		}, 1, BreakIndex},
+		{"timestamp index", func(txn *badger.Txn, blocks []types.FullSignedBlock) error {
+			return txn.Delete(timestampHeightKey(blocks[2].Timestamp, 2))
+		}, 2, BreakIndex},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewKVStore(t.TempDir()).(*Store)
+			blocks := testChain(t, 6, 1600000000)
+			if err := store.StoreBlocks(blocks); err != nil {
+				t.Fatal(err)
+			}
+
+			stor, err := store.open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = stor.Update(func(txn *badger.Txn) error { return test.tamper(txn, blocks) })
+			stor.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := store.VerifyChain(0, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, broken := range report.Breaks {
+				found = found || (broken.Height == test.height && broken.Kind == test.kind)
+			}
+			if !found {
+				t.Errorf("the report doesn´t hold a %s break at the height %d: %v", test.kind, test.height, report.Breaks)
+			}
+		})
+	}
+}
+
+// The blocks below the pruned height lost their evidence, so their hashes are not checked
+func TestVerifyChainPruned(t *testing.T) {
+	store := NewKVStore(t.TempDir()).(*Store)
+	blocks := testChain(t, 4, 1600000000)
+	changed := blocks[1]
+	changed.Memo = "changed"
+	if err := store.StoreBlocks(append(blocks, changed)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		prunedHeight string
+		ok           bool
+	}{
+		{"0", false},
+		{"2", true},
+	}
+	for _, test := range tests {
+		if err := store.StoreValue(PrunedHeightKey, []byte(test.prunedHeight)); err != nil {
+			t.Fatal(err)
+		}
+		report, err := store.VerifyChain(0, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.OK() != test.ok {
+			t.Errorf("with the pruned height %s the report has the breaks %v", test.prunedHeight, report.Breaks)
+		}
+	}
+}
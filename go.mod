@@ -3,6 +3,21 @@ module github.com/aquarelle-tech/darkmatter
 go 1.13
 
 require (
+	github.com/alicebob/miniredis/v2 v2.14.1
 	github.com/dgraph-io/badger v1.6.0
+	github.com/dgraph-io/badger/v2 v2.2007.4
+	github.com/fxamacker/cbor/v2 v2.2.0
+	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/golang/protobuf v1.4.3
 	github.com/gorilla/websocket v1.4.1
+	github.com/klauspost/compress v1.12.3
+	github.com/lib/pq v1.8.0
+	github.com/mattn/go-sqlite3 v1.14.5
+	github.com/minio/minio-go/v7 v7.0.6
+	github.com/prometheus/client_golang v1.8.0
+	github.com/syndtr/goleveldb v1.0.0
+	github.com/vmihailenco/msgpack/v5 v5.0.0
+	go.etcd.io/bbolt v1.3.5
+	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899
+	google.golang.org/protobuf v1.25.0
 )
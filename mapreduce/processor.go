@@ -6,11 +6,14 @@
 package mapreduce
 
 import (
+	"fmt"
+	"log"
 	"sync"
 	"time"
 
 	"github.com/aquarelle-tech/darkmatter/database"
 	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
@@ -19,28 +22,79 @@ const (
 
 	// BlockchainFileLocation is the directory where to store the database for the node
 	BlockchainFileLocation = "./chain/stor"
-	MainBlockChainName     = "main"
+	// SigningKeyFileLocation is the file with the private key of the identity of the node, to sign the new blocks
+	SigningKeyFileLocation = "./chain/node.key"
+	// CollectorKeyFileLocation is the file with the private key of a crawler, with their name, to sign their results
+	CollectorKeyFileLocation = "./chain/collector-%s.key"
+	MainBlockChainName       = "main"
 )
 
-// PublicBlockDatabase is the main instance to manage the database
-var PublicBlockDatabase *database.BlockChain = database.NewBlockChain(MainBlockChainName, BlockchainFileLocation)
+// NewPublicBlockDatabase opens the main database of the node. The database is held open until it is closed on
+// shutdown, the recent blocks are cached in memory, and the calls to the store are measured and published with the
+// default Prometheus handler, so it must be created once
+func NewPublicBlockDatabase() (*database.BlockChain, error) {
+	metrics, err := database.NewStoreMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
+	kvstore, err := database.Open(BlockchainFileLocation, database.StoreConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	store := database.NewMeteredStore(kvstore, metrics)
+	if err = database.NewDiskUsageGauge(prometheus.DefaultRegisterer, store); err != nil {
+		kvstore.Close()
+		return nil, err
+	}
+	identity, err := types.LoadNodeIdentity(SigningKeyFileLocation)
+	if err != nil {
+		kvstore.Close()
+		return nil, err
+	}
+	log.Println("The address of the node is", identity.Address())
+
+	chain := database.NewBlockChainWithStore(MainBlockChainName, database.NewLRUCache(store, database.DefaultLRUCacheSize))
+	chain.SetIdentity(identity)
+	return chain, nil
+}
+
+// LoadCollectorIdentity reads the key pair of the crawler with the name, or creates it. Each crawler has their
+// own key, apart from the one of the node that signs the blocks
+func LoadCollectorIdentity(name string) (*types.NodeIdentity, error) {
+	return types.LoadNodeIdentity(fmt.Sprintf(CollectorKeyFileLocation, name))
+}
 
 type Processor struct {
 	// Channels to build the worker pool
 	DataJobs chan types.GetDataJob
 	Results  chan types.Result
 
+	Chain           *database.BlockChain // Where the new blocks are stored
 	Directory       []types.PriceEvidenceCrawler
 	QuotedCurrency  string
 	PublicationChan chan types.FullSignedBlock
+
+	collectors []string // The addresses of the keys of the crawlers in the directory
 }
 
-func NewMapReduceProcessor(directory []types.PriceEvidenceCrawler, quotedCurrency string, publicationChan chan types.FullSignedBlock) Processor {
+func NewMapReduceProcessor(chain *database.BlockChain, directory []types.PriceEvidenceCrawler, quotedCurrency string, publicationChan chan types.FullSignedBlock) Processor {
+	// Only the results signed by the crawlers of the directory are accepted
+	collectors := make([]string, 0, len(directory))
+	for _, crawler := range directory {
+		if pub := crawler.GetPublicKey(); pub != nil {
+			collectors = append(collectors, types.AddressOf(pub))
+		}
+	}
+
 	// Channels to build the worker pool
 	return Processor{
+		Chain:           chain,
 		Directory:       directory,
 		QuotedCurrency:  quotedCurrency,
 		PublicationChan: publicationChan,
+		collectors:      collectors,
 	}
 }
 
@@ -48,21 +102,25 @@ func NewMapReduceProcessor(directory []types.PriceEvidenceCrawler, quotedCurrenc
 func (p Processor) mapJob(wg *sync.WaitGroup) {
 
 	// The process should also be called async
-	internalChan := make(chan types.QuotePriceInfo)
+	internalChan := make(chan types.Result)
 
 	for job := range p.DataJobs {
-		// Get the data
+		// Get the data, hashed and signed by the crawler
 		go job.DataCrawler.Crawl(job.Quote, internalChan)
 
-		data := <-internalChan
-		result := types.Result{
-			Data:        data,
-			Ticker:      job.DataCrawler.GetTicker(),
-			HasError:    false,
-			Timestamp:   time.Now().Unix(),
-			CrawlerName: job.DataCrawler.GetName(),
+		result := <-internalChan
+
+		// The results not signed by a known crawler can´t be attributed, so they are not evidence
+		if err := result.VerifyCollector(p.collectors); err != nil {
+			log.Println("The result of", result.CrawlerName, "is dropped", err)
+			continue
+		}
+
+		// The results over the limits would make the block invalid
+		if err := result.CheckSize(); err != nil {
+			log.Println("The result is dropped", err)
+			continue
 		}
-		result.CreateHash()
 
 		// Send the result to the queue
 		p.Results <- result
@@ -112,20 +170,38 @@ func (p Processor) reduceJobs(poolSize int) {
 		sources = append(sources, result)
 	}
 
+	if len(sources) == 0 {
+		log.Println("No results to create a new block")
+		return
+	}
+
 	// Get the first
 	totalVolume = sources[0].Data.Volume
 	totalPrice = sources[0].Data.HighPrice
 	//====================================================================================================================
 
+	// The aggregate price of all the sources crawled in the period
+	candle, err := types.NewPriceCandle(ticker, DELAY_BETWEEN_CRAWLS, sources)
+	if err != nil {
+		log.Println("No prices to create a new block", err)
+		return
+	}
+
 	// Create a message to send to service´s listeners
-	newMsg := PublicBlockDatabase.NewFullSignedBlock(
-		ticker,
+	newMsg, err := p.Chain.NewCandleBlock(
+		candle,
 		totalPrice,  // Average price
 		totalVolume, // High price
 		sources,
 		"", // TODO: Add the memo info, if any
 	)
 
+	// Only the stored blocks are published. They were validated against their parent before being stored
+	if err != nil {
+		log.Println("The new block is not published", newMsg.Hash, err)
+		return
+	}
+
 	p.PublicationChan <- newMsg
 }
 
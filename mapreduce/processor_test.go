@@ -0,0 +1,85 @@
+package mapreduce
+
+import (
+	"crypto/ed25519"
+	"sync"
+	"testing"
+
+	"github.com/aquarelle-tech/darkmatter/types"
+)
+
+// A crawler that signs a fixed price with the key of the signer, and publishes the key of the identity
+type testCrawler struct {
+	name     string
+	identity *types.NodeIdentity
+	signer   *types.NodeIdentity // nil to send the result unsigned
+}
+
+func (c testCrawler) Crawl(quotedCurrency string, done chan types.Result) {
+	result := types.Result{CrawlerName: c.name, Ticker: "BTCUSD", Data: types.QuotePriceInfo{HighPrice: 10000}}
+	result.CreateHash()
+	if c.signer != nil {
+		result.Sign(c.signer.PrivateKey)
+	}
+	done <- result
+}
+
+func (c testCrawler) GetName() string {
+	return c.name
+}
+
+func (c testCrawler) GetTicker() string {
+	return "BTCUSD"
+}
+
+func (c testCrawler) GetPublicKey() ed25519.PublicKey {
+	return c.identity.PublicKey()
+}
+
+func testIdentity(t *testing.T) *types.NodeIdentity {
+	identity, err := types.NewNodeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+// Only the results signed by the crawlers of the directory reach the Reduce stage
+func TestMapJobVerifiesTheCollectors(t *testing.T) {
+	known, stranger := testIdentity(t), testIdentity(t)
+	directory := []types.PriceEvidenceCrawler{testCrawler{name: "known", identity: known, signer: known}}
+
+	tests := []struct {
+		name     string
+		crawler  testCrawler
+		accepted bool
+	}{
+		{"signed by the crawler", testCrawler{name: "known", identity: known, signer: known}, true},
+		{"signed by another key", testCrawler{name: "known", identity: known, signer: stranger}, false},
+		{"signed by a crawler out of the directory", testCrawler{name: "stranger", identity: stranger, signer: stranger}, false},
+		{"not signed", testCrawler{name: "known", identity: known}, false},
+	}
+	for _, test := range tests {
+		p := NewMapReduceProcessor(nil, directory, "USD", nil)
+		p.DataJobs = make(chan types.GetDataJob, 1)
+		p.Results = make(chan types.Result, 1)
+		p.DataJobs <- types.GetDataJob{Quote: "USD", DataCrawler: test.crawler}
+		close(p.DataJobs)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		p.mapJob(&wg)
+		close(p.Results)
+
+		var results []types.Result
+		for result := range p.Results {
+			results = append(results, result)
+		}
+		if accepted := len(results) == 1; accepted != test.accepted {
+			t.Errorf("the result %s was accepted: %t, expected %t", test.name, accepted, test.accepted)
+		}
+		if test.accepted && len(results) == 1 && results[0].PublicKey == "" {
+			t.Errorf("the result %s lost their signature", test.name)
+		}
+	}
+}
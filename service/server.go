@@ -4,33 +4,52 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/aquarelle-tech/darkmatter/database"
 	"github.com/aquarelle-tech/darkmatter/types"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var clients = make(map[*websocket.Conn]bool)           // connected clients
+var binaryClients = make(map[*websocket.Conn]bool)     // clients that receive CBOR binary frames
+var clientsLock sync.RWMutex                           // Guards the clients, registered and removed concurrently
 var broadcast = make(chan types.LiteIndexValueMessage) // Broadcast channel
-var upgrader = websocket.Upgrader{}
+
+// Any origin can listen to the prices. Set once, as the upgrader is shared by the handlers of all the connections
+var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
 
 type OracleServer struct {
+	// The chain of the published blocks, to count their confirmations
+	Chain *database.BlockChain
 	// Channel to se
 	Published chan types.FullSignedBlock
 	Broadcast chan types.LiteIndexValueMessage
-	Clients   map[*websocket.Conn]bool
+	// The connected clients, and the ones that asked for CBOR (with ?format=cbor) instead of JSON. They are
+	// registered by the handlers of the connections while the messages are broadcast, so both maps are only used
+	// with the clientsLock held
+	Clients       map[*websocket.Conn]bool
+	BinaryClients map[*websocket.Conn]bool
 }
 
-func NewOracleServer(published chan types.FullSignedBlock) OracleServer {
+func NewOracleServer(chain *database.BlockChain, published chan types.FullSignedBlock) OracleServer {
 	return OracleServer{
+		Chain:     chain,
 		Published: published,
 		Broadcast: broadcast,
 		Clients:   clients,
+
+		BinaryClients: binaryClients,
 	}
 }
 
@@ -40,20 +59,75 @@ func (o OracleServer) broadcastMessages() {
 		// Grab the next message from the broadcast channel
 		msg := <-o.Broadcast
 
-		// Send it out to every client that is currently connected
-		for client := range o.Clients {
-			err := client.WriteJSON(msg)
+		// The confirmations when the message is sent, not when the block was published
+		confirmations, err := o.Chain.GetConfirmations(msg.Hash)
+		if err != nil {
+			log.Printf("Can´t count the confirmations of the block %s: %v", msg.Hash, err)
+		}
+		msg.Confirmations = confirmations
+
+		// Send it out to every client that is currently connected. The lock is not held while writing, so a slow
+		// client doesn´t block the new connections
+		for client, binary := range o.connectedClients() {
+			var err error
+			if binary {
+				err = writeCBOR(client, msg)
+			} else {
+				err = client.WriteJSON(msg)
+			}
 
 			// If client is not longer listening or any other error, the client is removed from the list
 			if err != nil {
 				log.Printf("Error writing to a client: %v", err)
 				client.Close()
-				delete(o.Clients, client)
+				o.removeClient(client)
 			}
 		}
 	}
 }
 
+// Register a new listener, sent CBOR binary frames if binary
+func (o OracleServer) addClient(client *websocket.Conn, binary bool) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	o.Clients[client] = true
+	if binary {
+		o.BinaryClients[client] = true
+	}
+}
+
+func (o OracleServer) removeClient(client *websocket.Conn) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	delete(o.Clients, client)
+	delete(o.BinaryClients, client)
+}
+
+// A copy of the connected clients, with true for the ones that receive CBOR
+func (o OracleServer) connectedClients() map[*websocket.Conn]bool {
+	clientsLock.RLock()
+	defer clientsLock.RUnlock()
+
+	connected := make(map[*websocket.Conn]bool, len(o.Clients))
+	for client := range o.Clients {
+		connected[client] = o.BinaryClients[client]
+	}
+
+	return connected
+}
+
+// Send a message in a binary frame, serialized in CBOR
+func writeCBOR(client *websocket.Conn, msg types.LiteIndexValueMessage) error {
+	data, err := types.MarshalCBOR(msg)
+	if err != nil {
+		return err
+	}
+
+	return client.WriteMessage(websocket.BinaryMessage, data)
+}
+
 func setupResponse(w *http.ResponseWriter, req *http.Request) {
 	(*w).Header().Set("Access-Control-Allow-Origin", "*")
 	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
@@ -69,7 +143,6 @@ func (o OracleServer) handlePriceListeners(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Try to upgrade the connection. If it fails, the log, but not break the execution
-	upgrader.CheckOrigin = func(r *http.Request) bool { return true }
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Fatal(err)
@@ -79,22 +152,15 @@ func (o OracleServer) handlePriceListeners(w http.ResponseWriter, r *http.Reques
 	defer ws.Close()
 
 	// Register a new listener
-	o.Clients[ws] = true
+	o.addClient(ws, r.URL.Query().Get("format") == "cbor")
 
 	// An infinite loop to get the published messages from the data processors ad send to the broadcast queue
 	for {
 		msg := <-o.Published // Get a message from the public queue
 		log.Printf("MESSAGE: Volume=%f, HighPrice=%f", msg.AverageVolume, msg.AveragePrice)
 
-		liteMessage := types.LiteIndexValueMessage{
-			Hash:          msg.Hash,
-			Height:        msg.Height,
-			PriceIndex:    msg.AveragePrice,
-			Quoted:        msg.Ticker,
-			NodeAddress:   msg.Address,
-			Timestamp:     msg.Timestamp,
-			Confirmations: len(msg.Evidence),
-		}
+		// The Reduce stage takes the price of the first source, for now
+		liteMessage := types.NewLiteIndexValueMessage(msg, types.AggregationFirstSource)
 
 		// Send the newly received message to the broadcast channel
 		o.Broadcast <- liteMessage
@@ -106,12 +172,18 @@ func serveChain(w http.ResponseWriter, r *http.Request) {
 
 	path := filepath.Join("public", filepath.Clean(r.URL.Path))
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	if _, err := os.Stat(path); err != nil {
 		println("El fichero no existe")
 
 		//log.Fatal((err))
 
-		http.Error(w, "The requested block doesn´t exists.", http.StatusInternalServerError)
+		// A missing file is a missing block, any other error is a failure of the node
+		message := http.StatusText(http.StatusInternalServerError)
+		if os.IsNotExist(err) {
+			err = types.ErrNotFound
+			message = "The requested block doesn´t exists."
+		}
+		http.Error(w, message, ErrorStatus(err))
 
 	} else {
 		println("Intentando leer el fichero")
@@ -127,6 +199,65 @@ func serveChain(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// The confirmations of a block, served as /confirmations/<hash>
+type confirmationsResponse struct {
+	Hash          string `json:"hash"`
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// Serve the number of blocks from a block to the head of the chain, both included
+func (o OracleServer) serveConfirmations(w http.ResponseWriter, r *http.Request) {
+	setupResponse(&w, r)
+
+	hash := strings.TrimPrefix(r.URL.Path, "/confirmations/")
+	confirmations, err := o.Chain.GetConfirmations(hash)
+	if err != nil {
+		http.Error(w, err.Error(), ErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(confirmationsResponse{Hash: hash, Confirmations: confirmations})
+}
+
+// Serve the JSON Schema of a message of the API, as /schema/block (or block.json). /schema/ lists the names of
+// the messages
+func serveSchema(w http.ResponseWriter, r *http.Request) {
+	setupResponse(&w, r)
+	w.Header().Set("Content-Type", "application/schema+json")
+
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/schema/"), ".json")
+	if name == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.SchemaNames())
+		return
+	}
+
+	schema, err := types.Schema(name)
+	if err != nil {
+		http.Error(w, err.Error(), ErrorStatus(err))
+		return
+	}
+	w.Write(schema)
+}
+
+// ErrorStatus returns the HTTP status of an error of the chain, so the clients can tell a missing or invalid block
+// from a failure of the node
+func ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrInvalidBlock):
+		return http.StatusBadRequest
+	case errors.Is(err, types.ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, database.ErrReadOnly), errors.Is(err, database.ErrQuotaExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Prepare and start the main routines
 func (o OracleServer) Initialize() {
 	// To send back a html page by default
@@ -136,6 +267,15 @@ func (o OracleServer) Initialize() {
 	// The main route to get the websocket path
 	http.HandleFunc("/price", o.handlePriceListeners)
 
+	// The JSON Schemas of the blocks, the results and the lite messages, to validate them and generate clients
+	http.HandleFunc("/schema/", serveSchema)
+
+	// How deep a block is in the chain, to decide when to trust their price
+	http.HandleFunc("/confirmations/", o.serveConfirmations)
+
+	// The metrics for Prometheus
+	http.Handle("/metrics", promhttp.Handler())
+
 	// Launch subrouting to handle messages
 	go o.broadcastMessages()
 }
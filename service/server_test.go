@@ -0,0 +1,150 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquarelle-tech/darkmatter/database"
+	"github.com/aquarelle-tech/darkmatter/types"
+	"github.com/gorilla/websocket"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"not found", fmt.Errorf("reading the block: %w", types.ErrNotFound), http.StatusNotFound},
+		{"invalid block", &types.ValidationError{Rule: types.ErrBrokenLink}, http.StatusBadRequest},
+		{"conflict", &database.ConflictError{Height: 1, Hash: "aa", StoredHash: "bb"}, http.StatusConflict},
+		{"read-only store", database.ErrReadOnly, http.StatusServiceUnavailable},
+		{"quota exceeded", database.ErrQuotaExceeded, http.StatusServiceUnavailable},
+		{"other failure", errors.New("disk failure"), http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		if status := ErrorStatus(test.err); status != test.status {
+			t.Errorf("the status of a %s error is %d, expected %d", test.name, status, test.status)
+		}
+	}
+}
+
+func TestServeSchema(t *testing.T) {
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/schema/", http.StatusOK, "application/json"},
+		{"/schema/block", http.StatusOK, "application/schema+json"},
+		{"/schema/result.json", http.StatusOK, "application/schema+json"},
+		{"/schema/unknown", http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		serveSchema(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if recorder.Code != test.status {
+			t.Errorf("%s returned the status %d, expected %d", test.path, recorder.Code, test.status)
+			continue
+		}
+		if test.status == http.StatusOK {
+			if contentType := recorder.Header().Get("Content-Type"); contentType != test.contentType {
+				t.Errorf("%s returned the content type %s, expected %s", test.path, contentType, test.contentType)
+			}
+			if !json.Valid(recorder.Body.Bytes()) {
+				t.Errorf("%s returned invalid JSON: %s", test.path, recorder.Body)
+			}
+		}
+	}
+}
+
+// The listeners connect while the messages are broadcast, and each one receives them in their format. The
+// registry of the clients is shared by the handlers and the broadcast, so the test is meant for -race
+func TestBroadcastConcurrentClients(t *testing.T) {
+	chain := database.NewBlockChainWithStore("test", database.NewMemoryStore())
+	o := OracleServer{
+		Chain:         chain,
+		Published:     make(chan types.FullSignedBlock),
+		Broadcast:     make(chan types.LiteIndexValueMessage),
+		Clients:       make(map[*websocket.Conn]bool),
+		BinaryClients: make(map[*websocket.Conn]bool),
+	}
+	go o.broadcastMessages()
+
+	server := httptest.NewServer(http.HandlerFunc(o.handlePriceListeners))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	// The blocks are published until every client received one
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			block := chain.NewFullSignedBlock("BTCUSD", 10000, 1.5, nil, "")
+			select {
+			case o.Published <- block:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	tests := []struct {
+		name      string
+		query     string
+		frame     int
+		unmarshal func(data []byte, v interface{}) error
+	}{
+		{"JSON", "", websocket.TextMessage, json.Unmarshal},
+		{"CBOR", "?format=cbor", websocket.BinaryMessage, types.UnmarshalCBOR},
+	}
+	const clientsPerFormat = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(tests)*clientsPerFormat)
+	for _, test := range tests {
+		for i := 0; i < clientsPerFormat; i++ {
+			wg.Add(1)
+			go func(name string, query string, frame int, unmarshal func(data []byte, v interface{}) error) {
+				defer wg.Done()
+
+				ws, _, err := websocket.DefaultDialer.Dial(url+query, nil)
+				if err != nil {
+					errs <- fmt.Errorf("the %s client can´t connect: %v", name, err)
+					return
+				}
+				defer ws.Close()
+				ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+				var msg types.LiteIndexValueMessage
+				kind, data, err := ws.ReadMessage()
+				if err == nil && kind != frame {
+					err = fmt.Errorf("received a frame of the type %d, expected %d", kind, frame)
+				}
+				if err == nil {
+					err = unmarshal(data, &msg)
+				}
+				if err == nil && (msg.Hash == "" || msg.Confirmations == 0) {
+					err = fmt.Errorf("received the message %+v", msg)
+				}
+				if err != nil {
+					errs <- fmt.Errorf("the %s client: %v", name, err)
+				}
+			}(test.name, test.query, test.frame, test.unmarshal)
+		}
+	}
+	wg.Wait()
+
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
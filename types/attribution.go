@@ -0,0 +1,113 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnsignedResult is returned when verifying a result without signature
+	ErrUnsignedResult = errors.New("types: the result is not signed")
+	// ErrInvalidResultSignature is returned when the signature of a result is not the one of the key, or the
+	// content of the result changed after it was signed
+	ErrInvalidResultSignature = errors.New("types: the signature of the result is invalid")
+	// ErrUnknownCollector is returned when a result is signed by a key that is not one of the known collectors
+	ErrUnknownCollector = errors.New("types: the result is not signed by a known collector")
+)
+
+// The result without the signature and the public key, as it is hashed
+func (result Result) unsigned() Result {
+	result.Signature, result.PublicKey = "", ""
+	return result
+}
+
+// Sign signs the hash of the result with the private key of the node or the crawler that collected it, so the
+// evidence of a block can be attributed to them and can´t be forged by the producer of the block. The hash must be
+// created before, and not again after signing it
+func (result *Result) Sign(priv ed25519.PrivateKey) error {
+	if result.Hash == "" {
+		return errors.New("types: the result must be hashed before signing it")
+	}
+
+	result.PublicKey = hex.EncodeToString(priv.Public().(ed25519.PublicKey))
+	result.Signature = hex.EncodeToString(ed25519.Sign(priv, []byte(result.Hash)))
+
+	return nil
+}
+
+// VerifySignature checks that the result was signed with the private key of pub, and that their content matches
+// the signed hash
+func (result Result) VerifySignature(pub ed25519.PublicKey) error {
+	if result.Signature == "" {
+		return ErrUnsignedResult
+	}
+
+	signature, err := hex.DecodeString(result.Signature)
+	if err != nil || result.PublicKey != hex.EncodeToString(pub) {
+		return ErrInvalidResultSignature
+	}
+	if !ed25519.Verify(pub, []byte(result.Hash), signature) {
+		return ErrInvalidResultSignature
+	}
+
+	if err := result.Verify(); err != nil {
+		if errors.Is(err, ErrInvalidEvidence) {
+			return ErrInvalidResultSignature
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Signer verifies the signature of the result with their own public key, and returns the address of the key that
+// signed it. It only proves who signed the result: the address must be checked against the known collectors
+func (result Result) Signer() (string, error) {
+	pub, err := hex.DecodeString(result.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		if result.Signature == "" {
+			return "", ErrUnsignedResult
+		}
+		return "", ErrInvalidResultSignature
+	}
+
+	if err := result.VerifySignature(pub); err != nil {
+		return "", err
+	}
+
+	return AddressOf(pub), nil
+}
+
+// VerifyCollector checks that the result is signed by one of the addresses of the collectors. Returns
+// ErrUnsignedResult or ErrInvalidResultSignature if the signature can´t be verified, and ErrUnknownCollector if
+// the signer is not one of the collectors
+func (result Result) VerifyCollector(collectors []string) error {
+	signer, err := result.Signer()
+	if err != nil {
+		return err
+	}
+
+	for _, address := range collectors {
+		if address == signer {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnknownCollector, signer)
+}
+
+// VerifyEvidenceSignatures checks that every result of the evidence is signed by one of the addresses of the
+// collectors. Returns a *ValidationError with the ErrInvalidEvidence rule if a result is not signed, the signature
+// is invalid, or the signer is not one of them
+func (block FullSignedBlock) VerifyEvidenceSignatures(collectors []string) error {
+	for i, result := range block.Evidence {
+		if err := result.VerifyCollector(collectors); err != nil {
+			return &ValidationError{Hash: block.Hash, Height: block.Height, Rule: ErrInvalidEvidence,
+				Detail: fmt.Sprintf("the result %d of %s: %v", i, result.CrawlerName, err)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestResultSignature(t *testing.T) {
+	collector, other := testIdentity(t), testIdentity(t)
+
+	tests := []struct {
+		name   string
+		change func(result *Result)
+		key    ed25519.PublicKey
+		err    error
+	}{
+		{"signed", func(result *Result) {}, collector.PublicKey(), nil},
+		{"another key", func(result *Result) {}, other.PublicKey(), ErrInvalidResultSignature},
+		{"unsigned", func(result *Result) { result.Signature = "" }, collector.PublicKey(), ErrUnsignedResult},
+		{"changed content", func(result *Result) { result.Data.HighPrice++ }, collector.PublicKey(), ErrInvalidResultSignature},
+		{"hashed again", func(result *Result) { result.Data.HighPrice++; result.CreateHash() }, collector.PublicKey(), ErrInvalidResultSignature},
+		{"signature not in hex", func(result *Result) { result.Signature = "zz" }, collector.PublicKey(), ErrInvalidResultSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := testResults(1)[0]
+			if err := result.Sign(collector.PrivateKey); err != nil {
+				t.Fatal(err)
+			}
+			test.change(&result)
+
+			if err := result.VerifySignature(test.key); !errors.Is(err, test.err) {
+				t.Errorf("VerifySignature returned %v, expected %v", err, test.err)
+			}
+		})
+	}
+}
+
+// The signature is not part of the hash, so signing keeps the hash and the Merkle root of the evidence
+func TestResultSignKeepsHash(t *testing.T) {
+	results := testResults(2)
+	root := MerkleRoot(results)
+	for i := range results {
+		if err := results[i].Sign(testIdentity(t).PrivateKey); err != nil {
+			t.Fatal(err)
+		}
+		if err := results[i].Verify(); err != nil {
+			t.Errorf("the signed result doesn´t match their hash: %v", err)
+		}
+	}
+
+	if MerkleRoot(results) != root {
+		t.Error("signing the results changed the Merkle root")
+	}
+
+	unhashed := testResults(1)[0]
+	unhashed.Hash = ""
+	if err := unhashed.Sign(testIdentity(t).PrivateKey); err == nil {
+		t.Error("a result without hash was signed")
+	}
+}
+
+func TestResultSigner(t *testing.T) {
+	collector := testIdentity(t)
+	result := testResults(1)[0]
+	if err := result.Sign(collector.PrivateKey); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := result.Signer()
+	if err != nil || signer != collector.Address() {
+		t.Errorf("the signer is %s (%v), expected %s", signer, err, collector.Address())
+	}
+
+	unsigned := testResults(1)[0]
+	if _, err = unsigned.Signer(); !errors.Is(err, ErrUnsignedResult) {
+		t.Errorf("the signer of an unsigned result returned %v", err)
+	}
+
+	// The public key replaced by another one, with the signature of the first
+	forged := result
+	forged.PublicKey = hex.EncodeToString(testIdentity(t).PublicKey())
+	if _, err = forged.Signer(); !errors.Is(err, ErrInvalidResultSignature) {
+		t.Errorf("the signer of a result with another key returned %v", err)
+	}
+}
+
+func TestVerifyEvidenceSignatures(t *testing.T) {
+	collector, other := testIdentity(t), testIdentity(t)
+
+	signed := func(keys ...*NodeIdentity) FullSignedBlock {
+		block := FullSignedBlock{Hash: "dd00", Evidence: testResults(len(keys))}
+		for i, key := range keys {
+			if key != nil {
+				if err := block.Evidence[i].Sign(key.PrivateKey); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return block
+	}
+
+	tests := []struct {
+		name  string
+		block FullSignedBlock
+		valid bool
+	}{
+		{"signed by the collector", signed(collector, collector), true},
+		{"signed by the known collectors", signed(collector, other), true},
+		{"no evidence", signed(), true},
+		{"a result not signed", signed(collector, nil), false},
+	}
+	for _, test := range tests {
+		err := test.block.VerifyEvidenceSignatures([]string{collector.Address(), other.Address()})
+		if test.valid && err != nil {
+			t.Errorf("the evidence %s is not valid: %v", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidEvidence) {
+			t.Errorf("the evidence with %s returned %v, expected ErrInvalidEvidence", test.name, err)
+		}
+	}
+
+	stranger := signed(collector, testIdentity(t))
+	if err := stranger.VerifyEvidenceSignatures([]string{collector.Address()}); !errors.Is(err, ErrInvalidEvidence) {
+		t.Errorf("the evidence signed by an unknown collector returned %v", err)
+	}
+}
+
+func TestResultVerifyCollector(t *testing.T) {
+	collector, other := testIdentity(t), testIdentity(t)
+	signed := func(key *NodeIdentity) Result {
+		result := testResults(1)[0]
+		if err := result.Sign(key.PrivateKey); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	tampered := signed(collector)
+	tampered.Data.HighPrice++
+
+	tests := []struct {
+		name   string
+		result Result
+		err    error
+	}{
+		{"signed by the collector", signed(collector), nil},
+		{"signed by another collector", signed(other), ErrUnknownCollector},
+		{"not signed", testResults(1)[0], ErrUnsignedResult},
+		{"changed after signing it", tampered, ErrInvalidResultSignature},
+	}
+	for _, test := range tests {
+		if err := test.result.VerifyCollector([]string{collector.Address()}); !errors.Is(err, test.err) {
+			t.Errorf("the result %s returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
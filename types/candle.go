@@ -0,0 +1,87 @@
+package types
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNoPrices is returned when a candle is built from results without prices, all of them with errors
+var ErrNoPrices = errors.New("types: there are no prices to build the candle")
+
+// PriceCandle is the aggregate price of a ticker in a period, with the open, high, low and close (OHLCV) of the
+// prices of the sources and their volume, as produced by the Reduce stage
+type PriceCandle struct {
+	Ticker string `json:"ticker"`
+	Start  uint64 `json:"start"`  // The Unix time when the period starts
+	Period uint64 `json:"period"` // The seconds of the period
+
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume float64 `json:"volume"`
+}
+
+// NewPriceCandle builds the candle of a ticker from the results collected in a period that starts with the first
+// of them. The price of a source is their high price, and the open and the close are the prices of the first and
+// the last results collected. The volume is the sum of the volumes. The results with errors are skipped
+func NewPriceCandle(ticker string, period time.Duration, results []Result) (PriceCandle, error) {
+	var sources []Result
+	for _, result := range results {
+		result, err := result.Decompressed()
+		if err != nil {
+			return PriceCandle{}, err
+		}
+		if !result.HasError {
+			sources = append(sources, result)
+		}
+	}
+	if len(sources) == 0 {
+		return PriceCandle{}, ErrNoPrices
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Timestamp < sources[j].Timestamp })
+
+	candle := PriceCandle{
+		Ticker: ticker,
+		Start:  uint64(sources[0].Timestamp),
+		Period: uint64(period / time.Second),
+		Open:   sources[0].Data.HighPrice,
+		High:   sources[0].Data.HighPrice,
+		Low:    sources[0].Data.HighPrice,
+		Close:  sources[len(sources)-1].Data.HighPrice,
+	}
+	for _, source := range sources {
+		if price := source.Data.HighPrice; price > candle.High {
+			candle.High = price
+		} else if price < candle.Low {
+			candle.Low = price
+		}
+		candle.Volume += source.Data.Volume
+	}
+
+	return candle, nil
+}
+
+// End returns the time when the period of the candle ends
+func (candle PriceCandle) End() time.Time {
+	return time.Unix(int64(candle.Start+candle.Period), 0)
+}
+
+// Check the candle of a block of a ticker. Returns the detail of the problem, or an empty string
+func (candle PriceCandle) problem(ticker string) string {
+	switch {
+	case candle.Ticker != ticker:
+		return "the candle is of the ticker " + candle.Ticker
+	case candle.Low > candle.High:
+		return "the low price is over the high price"
+	case candle.Open < candle.Low || candle.Open > candle.High:
+		return "the open price is out of the low and high prices"
+	case candle.Close < candle.Low || candle.Close > candle.High:
+		return "the close price is out of the low and high prices"
+	case candle.Volume < 0:
+		return "the volume is negative"
+	}
+
+	return ""
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// A result of a source with a price and a volume, crawled at a second of the period
+func testPriceResult(second int64, price float64, volume float64, hasError bool) Result {
+	return Result{
+		CrawlerName: "crawler",
+		Ticker:      "BTCUSD",
+		Timestamp:   1600000000 + second,
+		HasError:    hasError,
+		Data:        QuotePriceInfo{HighPrice: price, Volume: volume},
+	}
+}
+
+func TestNewPriceCandle(t *testing.T) {
+	compressed := testPriceResult(3, 8, 1, false)
+	if err := compressed.Compress(EncodingZstd); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		results []Result
+		candle  PriceCandle
+		err     error
+	}{
+		{"one source", []Result{testPriceResult(0, 10, 2, false)},
+			PriceCandle{Start: 1600000000, Open: 10, High: 10, Low: 10, Close: 10, Volume: 2}, nil},
+		{"sources out of order", []Result{
+			testPriceResult(2, 12, 1, false),
+			testPriceResult(0, 10, 1, false),
+			testPriceResult(1, 15, 1, false),
+			testPriceResult(3, 9, 1, false),
+		}, PriceCandle{Start: 1600000000, Open: 10, High: 15, Low: 9, Close: 9, Volume: 4}, nil},
+		{"sources with errors", []Result{
+			testPriceResult(0, 100, 5, true),
+			testPriceResult(1, 10, 1, false),
+			testPriceResult(2, 11, 1, false),
+		}, PriceCandle{Start: 1600000001, Open: 10, High: 11, Low: 10, Close: 11, Volume: 2}, nil},
+		{"compressed source", []Result{testPriceResult(0, 10, 1, false), compressed},
+			PriceCandle{Start: 1600000000, Open: 10, High: 10, Low: 8, Close: 8, Volume: 2}, nil},
+		{"only errors", []Result{testPriceResult(0, 10, 1, true)}, PriceCandle{}, ErrNoPrices},
+		{"no sources", nil, PriceCandle{}, ErrNoPrices},
+	}
+	for _, test := range tests {
+		candle, err := NewPriceCandle("BTCUSD", time.Minute, test.results)
+		if !errors.Is(err, test.err) {
+			t.Errorf("the candle of the %s returned %v, expected %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		test.candle.Ticker, test.candle.Period = "BTCUSD", 60
+		if candle != test.candle {
+			t.Errorf("the candle of the %s is %+v, expected %+v", test.name, candle, test.candle)
+		}
+	}
+
+	candle := PriceCandle{Start: 1600000000, Period: 60}
+	if !candle.End().Equal(time.Unix(1600000060, 0)) {
+		t.Errorf("the candle ends at %v", candle.End())
+	}
+}
+
+func TestValidateCandle(t *testing.T) {
+	valid := PriceCandle{Ticker: "BTCUSD", Start: 1600000000, Period: 60, Open: 10, High: 15, Low: 9, Close: 12, Volume: 4}
+	withCandle := func(change func(candle *PriceCandle)) FullSignedBlock {
+		candle := valid
+		change(&candle)
+		return rehashed(t, testBlock(t), func(block *FullSignedBlock) { block.Candle = &candle })
+	}
+
+	tests := []struct {
+		name  string
+		block FullSignedBlock
+		rule  error
+	}{
+		{"valid candle", withCandle(func(candle *PriceCandle) {}), nil},
+		{"no candle", testBlock(t), nil},
+		{"candle of another ticker", withCandle(func(candle *PriceCandle) { candle.Ticker = "ETHUSD" }), ErrInvalidCandle},
+		{"low over high", withCandle(func(candle *PriceCandle) { candle.Low = 16 }), ErrInvalidCandle},
+		{"open out of the range", withCandle(func(candle *PriceCandle) { candle.Open = 20 }), ErrInvalidCandle},
+		{"close out of the range", withCandle(func(candle *PriceCandle) { candle.Close = 5 }), ErrInvalidCandle},
+		{"negative volume", withCandle(func(candle *PriceCandle) { candle.Volume = -1 }), ErrInvalidCandle},
+	}
+	for _, test := range tests {
+		if err := test.block.Validate(nil); !errors.Is(err, test.rule) {
+			t.Errorf("validating the block with %s returned %v, expected %v", test.name, err, test.rule)
+		}
+	}
+}
+
+// The candle is part of the content of the block: cloned, compared and serialized with it
+func TestCandleBlock(t *testing.T) {
+	candle := PriceCandle{Ticker: "BTCUSD", Start: 1600000000, Period: 60, Open: 10, High: 15, Low: 9, Close: 12, Volume: 4}
+	block := rehashed(t, testBlock(t), func(block *FullSignedBlock) { block.Candle = &candle })
+
+	clone := block.Clone()
+	clone.Candle.Close = 11
+	if block.Candle.Close != 12 {
+		t.Error("changing the candle of the clone changed the block")
+	}
+	if block.Equal(clone) || block.Equal(testBlock(t)) || !block.Equal(block.Clone()) {
+		t.Error("the candles are not compared by Equal")
+	}
+
+	data, err := block.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := UnmarshalBlockProto(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(block) {
+		t.Errorf("the block with a candle changed to %v in protobuf", decoded)
+	}
+
+	joined, err := block.Header().Join(block.Body())
+	if err != nil || !joined.Equal(block) {
+		t.Errorf("the block with a candle joined to %v (%v)", joined, err)
+	}
+}
@@ -0,0 +1,132 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CanonicalJSON serializes a value to a canonical form of JSON, used only to hash and sign. The value is
+// serialized with the JSON tags, and then written again with the keys of the objects sorted, without spaces,
+// without escaping other characters than the quotes, the backslash and the control ones, and with the numbers in
+// a fixed format: the integers as they are, and the rest in the shortest form that reads back the same float64.
+// So the same value gives the same bytes with any version of Go. The strings are valid UTF-8, as json.Marshal
+// replaces the invalid bytes
+func CanonicalJSON(v interface{}) ([]byte, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err = decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buffer canonicalBuffer
+	if err = buffer.write(value); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+type canonicalBuffer struct {
+	bytes.Buffer
+}
+
+func (b *canonicalBuffer) write(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(value))
+	case string:
+		b.writeString(value)
+	case json.Number:
+		return b.writeNumber(value)
+	case []interface{}:
+		b.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := b.write(item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // By their bytes
+
+		b.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.writeString(key)
+			b.WriteByte(':')
+			if err := b.write(value[key]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return fmt.Errorf("types: unexpected %T in the canonical form", value)
+	}
+
+	return nil
+}
+
+// The integers are kept as they are, so the uint64 don´t lose precision. The rest are floats
+func (b *canonicalBuffer) writeNumber(number json.Number) error {
+	if _, err := strconv.ParseInt(number.String(), 10, 64); err == nil {
+		b.WriteString(number.String())
+		return nil
+	}
+	if _, err := strconv.ParseUint(number.String(), 10, 64); err == nil {
+		b.WriteString(number.String())
+		return nil
+	}
+
+	f, err := number.Float64()
+	if err != nil {
+		return err
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		b.WriteString(strconv.FormatInt(int64(f), 10)) // An integer written as a float, e.g. 1e3
+		return nil
+	}
+
+	b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	return nil
+}
+
+func (b *canonicalBuffer) writeString(s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 0x20:
+			fmt.Fprintf(b, "\\u%04x", r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('"')
+}
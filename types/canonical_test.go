@@ -0,0 +1,109 @@
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"sorted keys", map[string]int{"b": 1, "a": 2, "B": 3}, `{"B":3,"a":2,"b":1}`},
+		{"nested", map[string]interface{}{"z": []interface{}{map[string]bool{"y": true, "x": false}}, "a": nil}, `{"a":null,"z":[{"x":false,"y":true}]}`},
+		{"struct with tags", struct {
+			Second string `json:"second"`
+			First  int    `json:"first"`
+			Empty  string `json:"empty,omitempty"`
+		}{"2", 1, ""}, `{"first":1,"second":"2"}`},
+		{"html not escaped", "<a href=\"x\">&</a>", `"<a href=\"x\">&</a>"`},
+		{"control characters", "a\nb\t\x01", `"a\u000ab\u0009\u0001"`},
+		{"backslash", `a\b`, `"a\\b"`},
+		{"unicode kept", "ñ€😀", `"ñ€😀"`},
+		{"invalid utf-8 replaced", "a\xffb", "\"a�b\""},
+		{"integer", 42, `42`},
+		{"negative integer", -42, `-42`},
+		{"greatest uint64", uint64(math.MaxUint64), `18446744073709551615`},
+		{"float with an integer value", 1000.0, `1000`},
+		{"float", 0.1, `0.1`},
+		{"small float", 1.5e-7, `1.5e-07`},
+		{"big float", 1.5e300, `1.5e+300`},
+		{"float over 2^53, in the digits of json.Marshal", float64(1 << 60), `1152921504606847000`},
+		{"empty array", []int{}, `[]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			content, err := CanonicalJSON(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != test.expected {
+				t.Errorf("the canonical form is %s, expected %s", content, test.expected)
+			}
+		})
+	}
+}
+
+// The canonical form doesn´t depend on the order of the fields, so a map and a struct with the same content give
+// the same bytes
+func TestCanonicalJSONOrder(t *testing.T) {
+	block := testBlock(t)
+	content, err := json.Marshal(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(content, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	fromStruct, err := CanonicalJSON(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromMap, err := CanonicalJSON(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fromStruct) != string(fromMap) {
+		t.Errorf("the canonical form of the struct %s is not the one of the map %s", fromStruct, fromMap)
+	}
+}
+
+// The legacy blocks can be hashed over their JSON or over their canonical form. The versioned blocks only over the
+// canonical form
+func TestLegacyHashFallback(t *testing.T) {
+	hashed := func(version uint32, hasher func(obj interface{}) (string, error)) FullSignedBlock {
+		block := testBlock(t)
+		block.Version = version
+		hash, err := block.hashWith(hasher)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.Hash = hash
+		return block
+	}
+
+	blocks := []struct {
+		name  string
+		block FullSignedBlock
+		valid bool
+	}{
+		{"legacy block over the JSON", hashed(BlockVersionLegacy, legacyHash), true},
+		{"legacy block over the canonical form", hashed(BlockVersionLegacy, calculateHash), true},
+		{"versioned block over the canonical form", hashed(BlockVersionCanonical, calculateHash), true},
+		{"versioned block over the JSON", hashed(BlockVersionCanonical, legacyHash), false},
+	}
+	for _, test := range blocks {
+		valid, err := test.block.CheckHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if valid != test.valid {
+			t.Errorf("CheckHash of the %s returned %t, expected %t", test.name, valid, test.valid)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// The core deterministic encoding of RFC 8949: the same value always gives the same bytes, with the keys sorted
+// and the floats as short as they can be without losing precision
+var cborEncoding, _ = cbor.CoreDetEncOptions().EncMode()
+var cborDecoding, _ = cbor.DecOptions{}.DecMode()
+
+// MarshalCBOR serializes a block, a result or a lite message in CBOR, a compact and self-describing alternative to
+// JSON. The keys of the maps are the names of the json tags, so the decoded values look the same than in JSON
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	return cborEncoding.Marshal(v)
+}
+
+// UnmarshalCBOR deserializes a value serialized by MarshalCBOR
+func UnmarshalCBOR(data []byte, v interface{}) error {
+	return cborDecoding.Unmarshal(data, v)
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	block := testBlock(t)
+	if err := block.Sign(testKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	lite := LiteIndexValueMessage{Hash: "hash", Height: 3, PriceIndex: 10000.5, Quoted: "BTCUSD", Confirmations: 2}
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		decoded interface{}
+	}{
+		{"block", block, &FullSignedBlock{}},
+		{"result", block.Evidence[0], &Result{}},
+		{"lite message", lite, &LiteIndexValueMessage{}},
+	}
+	for _, test := range tests {
+		data, err := MarshalCBOR(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = UnmarshalCBOR(data, test.decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded := reflect.ValueOf(test.decoded).Elem().Interface(); !reflect.DeepEqual(decoded, test.value) {
+			t.Errorf("the %s changed to %v", test.name, decoded)
+		}
+	}
+}
+
+// The keys are the names of the json tags, in the deterministic order, so the same value always gives the same bytes
+func TestCBORDeterministic(t *testing.T) {
+	lite := LiteIndexValueMessage{Hash: "hash", Height: 3}
+	first, err := MarshalCBOR(lite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err = UnmarshalCBOR(first, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["hash"] != "hash" || fields["priceIndex"] == nil {
+		t.Errorf("the keys of the message are %v", fields)
+	}
+
+	again, err := MarshalCBOR(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, again) {
+		t.Errorf("the map of the message was serialized in other bytes: %x, expected %x", again, first)
+	}
+}
+
+func TestUnmarshalCBORInvalid(t *testing.T) {
+	var block FullSignedBlock
+	if err := UnmarshalCBOR([]byte{0xff}, &block); err == nil {
+		t.Error("an invalid value was deserialized")
+	}
+}
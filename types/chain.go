@@ -0,0 +1,95 @@
+package types
+
+import "fmt"
+
+// Chain is a contiguous segment of a chain held in memory, from their first block to the tip. Append only accepts
+// the valid blocks that follow the tip, so the segment is always a valid chain. It is not safe for concurrent use
+type Chain struct {
+	parent *FullSignedBlock // The block before the segment, nil if it starts at the genesis block or is not known
+	blocks []FullSignedBlock
+}
+
+// NewChain creates an empty segment that starts after the parent block, or at the genesis block if the parent is
+// nil. A segment without parent that starts at another height can´t check the link of their first block
+func NewChain(parent *FullSignedBlock) *Chain {
+	return &Chain{parent: parent}
+}
+
+// Append validates the block against the tip, see FullSignedBlock.Validate, and adds it as the new tip. An invalid
+// block is not added
+func (c *Chain) Append(block FullSignedBlock) error {
+	if err := block.Validate(c.Tip()); err != nil {
+		return err
+	}
+
+	c.blocks = append(c.blocks, block)
+	return nil
+}
+
+// Tip returns the last block of the segment, or the parent if the segment is empty
+func (c *Chain) Tip() *FullSignedBlock {
+	if len(c.blocks) == 0 {
+		return c.parent
+	}
+
+	return &c.blocks[len(c.blocks)-1]
+}
+
+// Len returns the number of blocks of the segment
+func (c *Chain) Len() int {
+	return len(c.blocks)
+}
+
+// Block returns the block of the segment at the height, or ErrNotFound
+func (c *Chain) Block(height uint64) (*FullSignedBlock, error) {
+	if len(c.blocks) == 0 || height < c.blocks[0].Height || height > c.blocks[len(c.blocks)-1].Height {
+		return nil, fmt.Errorf("types: the height %d is not in the chain: %w", height, ErrNotFound)
+	}
+
+	block := c.blocks[height-c.blocks[0].Height]
+	return &block, nil
+}
+
+// Blocks returns a copy of the blocks of the segment, from the first one to the tip
+func (c *Chain) Blocks() []FullSignedBlock {
+	return append([]FullSignedBlock(nil), c.blocks...)
+}
+
+// VerifyRange validates again the blocks between the heights from and to, both included, each one against their
+// parent. The rules that depend on the clock, as the timestamps in the future, can change since they were appended
+func (c *Chain) VerifyRange(from uint64, to uint64) error {
+	if len(c.blocks) == 0 {
+		return nil
+	}
+
+	first, last := c.blocks[0].Height, c.blocks[len(c.blocks)-1].Height
+	if from > to || to < first || from > last {
+		return nil // Out of the segment
+	}
+	if from < first {
+		from = first
+	}
+	if to > last {
+		to = last
+	}
+
+	parent := c.parent
+	if from > first {
+		parent = &c.blocks[from-first-1]
+	}
+
+	return VerifyBlocks(parent, c.blocks[from-first:to-first+1])
+}
+
+// VerifyBlocks validates a contiguous list of blocks, each one against the previous one, and the first against the
+// parent, nil if it is not known. It returns the error of the first invalid block
+func VerifyBlocks(parent *FullSignedBlock, blocks []FullSignedBlock) error {
+	for i := range blocks {
+		if err := blocks[i].Validate(parent); err != nil {
+			return err
+		}
+		parent = &blocks[i]
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+// A valid chain of blocks from the genesis block, one per second
+func testChainBlocks(t *testing.T, length int) []FullSignedBlock {
+	genesis, err := NewGenesisBlock(GenesisConfig{Chain: "test", Timestamp: 1600000000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := []FullSignedBlock{genesis}
+	for len(blocks) < length {
+		parent := blocks[len(blocks)-1]
+		blocks = append(blocks, rehashed(t, testBlock(t), func(block *FullSignedBlock) {
+			block.Height, block.Timestamp, block.PreviousHash = parent.Height+1, parent.Timestamp+1, parent.Hash
+		}))
+	}
+
+	return blocks
+}
+
+func TestChainAppend(t *testing.T) {
+	blocks := testChainBlocks(t, 4)
+	chain := NewChain(nil)
+	for _, block := range blocks[:3] {
+		if err := chain.Append(block); err != nil {
+			t.Fatalf("the block at the height %d wasn´t appended: %v", block.Height, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		block FullSignedBlock
+		rule  error
+	}{
+		{"block at the same height", blocks[2], ErrInvalidHeight},
+		{"block after a gap", rehashed(t, blocks[3], func(block *FullSignedBlock) { block.Height = 4 }), ErrInvalidHeight},
+		{"block of another parent", rehashed(t, blocks[3], func(block *FullSignedBlock) { block.PreviousHash = blocks[1].Hash }), ErrBrokenLink},
+		{"changed block", changed(blocks[3], func(block *FullSignedBlock) { block.Memo = "changed" }), ErrInvalidHash},
+	}
+	for _, test := range tests {
+		if err := chain.Append(test.block); !errors.Is(err, test.rule) {
+			t.Errorf("appending the %s returned %v, expected %v", test.name, err, test.rule)
+		}
+	}
+	if chain.Len() != 3 || chain.Tip().Hash != blocks[2].Hash {
+		t.Errorf("after the invalid blocks the chain has %d blocks and the tip %s", chain.Len(), chain.Tip().Hash)
+	}
+	if err := chain.Append(blocks[3]); err != nil {
+		t.Errorf("the next block wasn´t appended: %v", err)
+	}
+
+	heights := []struct {
+		height uint64
+		err    error
+	}{
+		{0, nil},
+		{3, nil},
+		{4, ErrNotFound},
+	}
+	for _, test := range heights {
+		block, err := chain.Block(test.height)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Block(%d) returned %v, expected %v", test.height, err, test.err)
+		}
+		if err == nil && block.Hash != blocks[test.height].Hash {
+			t.Errorf("Block(%d) returned the block %s", test.height, block.Hash)
+		}
+	}
+
+	copied := chain.Blocks()
+	copied[0].Memo = "changed"
+	if block, _ := chain.Block(0); block.Memo == "changed" {
+		t.Error("changing the returned blocks changed the chain")
+	}
+}
+
+// A segment starts after their parent, and checks the link of their first block with it
+func TestChainWithParent(t *testing.T) {
+	blocks := testChainBlocks(t, 4)
+	chain := NewChain(&blocks[1])
+	if chain.Tip().Hash != blocks[1].Hash {
+		t.Errorf("the tip of an empty segment is %s, expected the parent", chain.Tip().Hash)
+	}
+	if err := chain.Append(blocks[3]); !errors.Is(err, ErrInvalidHeight) {
+		t.Errorf("appending a block after a gap returned %v", err)
+	}
+	if err := chain.Append(blocks[2]); err != nil {
+		t.Errorf("appending the block after the parent failed: %v", err)
+	}
+	if _, err := chain.Block(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the parent is returned as a block of the segment: %v", err)
+	}
+}
+
+func TestChainVerifyRange(t *testing.T) {
+	blocks := testChainBlocks(t, 5)
+	chain := NewChain(nil)
+	for _, block := range blocks {
+		if err := chain.Append(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Break the block at the height 3 in place, as the clock rules could
+	chain.blocks[3].Memo = "changed"
+
+	tests := []struct {
+		name string
+		from uint64
+		to   uint64
+		err  error
+	}{
+		{"all", 0, 4, ErrInvalidHash},
+		{"before the broken block", 0, 2, nil},
+		{"the broken block", 3, 3, ErrInvalidHash},
+		{"after the broken block", 4, 10, nil},
+		{"reversed", 4, 0, nil},
+		{"out of the segment", 10, 20, nil},
+	}
+	for _, test := range tests {
+		if err := chain.VerifyRange(test.from, test.to); !errors.Is(err, test.err) {
+			t.Errorf("VerifyRange %s returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestVerifyBlocks(t *testing.T) {
+	blocks := testChainBlocks(t, 4)
+	tests := []struct {
+		name   string
+		parent *FullSignedBlock
+		blocks []FullSignedBlock
+		rule   error
+	}{
+		{"whole chain", nil, blocks, nil},
+		{"segment after the parent", &blocks[0], blocks[1:], nil},
+		{"segment after another parent", &blocks[1], blocks[1:], ErrInvalidHeight},
+		{"blocks with a gap", nil, []FullSignedBlock{blocks[0], blocks[2]}, ErrInvalidHeight},
+		{"no blocks", nil, nil, nil},
+	}
+	for _, test := range tests {
+		if err := VerifyBlocks(test.parent, test.blocks); !errors.Is(err, test.rule) {
+			t.Errorf("VerifyBlocks of the %s returned %v, expected %v", test.name, err, test.rule)
+		}
+	}
+}
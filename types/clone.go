@@ -0,0 +1,69 @@
+package types
+
+import "bytes"
+
+// Clone returns a deep copy of the result, that shares nothing with it
+func (result Result) Clone() Result {
+	if result.Payload != nil {
+		result.Payload = append([]byte(nil), result.Payload...)
+	}
+
+	return result
+}
+
+// Equal returns true if both results have the same content. An empty payload is the same than no payload
+func (result Result) Equal(other Result) bool {
+	return result.CrawlerName == other.CrawlerName &&
+		result.Data == other.Data &&
+		result.HasError == other.HasError &&
+		result.Timestamp == other.Timestamp &&
+		result.Ticker == other.Ticker &&
+		result.Hash == other.Hash &&
+		result.Encoding == other.Encoding &&
+		bytes.Equal(result.Payload, other.Payload) &&
+		result.Signature == other.Signature &&
+		result.PublicKey == other.PublicKey
+}
+
+// Clone returns a deep copy of the block, that shares nothing with it, so it can be sent to many consumers that
+// could change it
+func (block FullSignedBlock) Clone() FullSignedBlock {
+	if block.Evidence != nil {
+		evidence := make([]Result, len(block.Evidence))
+		for i, result := range block.Evidence {
+			evidence[i] = result.Clone()
+		}
+		block.Evidence = evidence
+	}
+	if block.Candle != nil {
+		candle := *block.Candle
+		block.Candle = &candle
+	}
+
+	return block
+}
+
+// Equal returns true if both blocks have the same content. Empty evidence is the same than no evidence, as after
+// the round trips through the stores and the serializations
+func (block FullSignedBlock) Equal(other FullSignedBlock) bool {
+	if len(block.Evidence) != len(other.Evidence) {
+		return false
+	}
+	for i := range block.Evidence {
+		if !block.Evidence[i].Equal(other.Evidence[i]) {
+			return false
+		}
+	}
+
+	if (block.Candle == nil) != (other.Candle == nil) || (block.Candle != nil && *block.Candle != *other.Candle) {
+		return false
+	}
+
+	return block.Header() == other.Header() &&
+		block.AveragePrice == other.AveragePrice &&
+		block.AverageVolume == other.AverageVolume &&
+		block.Ticker == other.Ticker &&
+		block.Address == other.Address &&
+		block.PreviousAddress == other.PreviousAddress &&
+		block.Memo == other.Memo
+}
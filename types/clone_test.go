@@ -0,0 +1,66 @@
+package types
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	block := testBlock(t)
+	if err := block.Evidence[1].Compress(EncodingGzip); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := block.Clone()
+	if !clone.Equal(block) {
+		t.Fatalf("the clone %v is not equal to the block", clone)
+	}
+	clone.Evidence[0].CrawlerName = "changed"
+	clone.Evidence[1].Payload[0]++
+	if block.Evidence[0].CrawlerName == "changed" || clone.Evidence[1].Equal(block.Evidence[1]) {
+		t.Error("changing the clone changed the block")
+	}
+
+	if (FullSignedBlock{}).Clone().Evidence != nil {
+		t.Error("the clone of a block without evidence has evidence")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	block := testBlock(t)
+	if err := block.Sign(testKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	withEvidence := func(evidence []Result) FullSignedBlock {
+		return changed(block, func(block *FullSignedBlock) { block.Evidence = evidence })
+	}
+	withResult := func(change func(result *Result)) FullSignedBlock {
+		evidence := block.Clone().Evidence
+		change(&evidence[0])
+		return withEvidence(evidence)
+	}
+
+	tests := []struct {
+		name  string
+		other FullSignedBlock
+		equal bool
+	}{
+		{"same block", block.Clone(), true},
+		{"other price", changed(block, func(block *FullSignedBlock) { block.AveragePrice++ }), false},
+		{"other memo", changed(block, func(block *FullSignedBlock) { block.Memo = "changed" }), false},
+		{"other previous address", changed(block, func(block *FullSignedBlock) { block.PreviousAddress = "other" }), false},
+		{"other signature", changed(block, func(block *FullSignedBlock) { block.Signature = "" }), false},
+		{"other version", changed(block, func(block *FullSignedBlock) { block.Version = BlockVersionLegacy }), false},
+		{"less evidence", withEvidence(block.Evidence[1:]), false},
+		{"other data of a result", withResult(func(result *Result) { result.Data.Volume++ }), false},
+		{"other error of a result", withResult(func(result *Result) { result.HasError = true }), false},
+		{"empty payload and no payload", withResult(func(result *Result) { result.Payload = []byte{} }), true},
+	}
+	for _, test := range tests {
+		if equal := block.Equal(test.other); equal != test.equal {
+			t.Errorf("the block with %s is equal: %t, expected %t", test.name, equal, test.equal)
+		}
+	}
+
+	empty := FullSignedBlock{Height: 1}
+	if !empty.Equal(FullSignedBlock{Height: 1, Evidence: []Result{}}) {
+		t.Error("a block with empty evidence is not equal to a block without evidence")
+	}
+}
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The encodings of the compressed data of the results
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// Both are safe to use from many goroutines at the same time
+var zstdEncoder, _ = zstd.NewWriter(nil)
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// Compress replaces the data of the result with their JSON compressed with the encoding, in Payload. The hash is
+// kept, as it is the hash of the uncompressed result, so it must be created before. A result already compressed
+// doesn´t change
+func (result *Result) Compress(encoding string) error {
+	if result.Encoding != "" {
+		return nil
+	}
+
+	content, err := json.Marshal(result.Data)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	switch encoding {
+	case EncodingGzip:
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer) // Without name nor time, so the same data gives the same bytes
+		if _, err = writer.Write(content); err == nil {
+			err = writer.Close()
+		}
+		if err != nil {
+			return err
+		}
+		payload = buffer.Bytes()
+	case EncodingZstd:
+		payload = zstdEncoder.EncodeAll(content, nil)
+	default:
+		return fmt.Errorf("types: unknown encoding %q", encoding)
+	}
+
+	result.Data = QuotePriceInfo{}
+	result.Encoding = encoding
+	result.Payload = payload
+	return nil
+}
+
+// Decompressed returns the result with their data decompressed, or the same result if it is not compressed
+func (result Result) Decompressed() (Result, error) {
+	var content []byte
+	var err error
+	switch result.Encoding {
+	case "":
+		return result, nil
+	case EncodingGzip:
+		var reader *gzip.Reader
+		if reader, err = gzip.NewReader(bytes.NewReader(result.Payload)); err == nil {
+			content, err = ioutil.ReadAll(reader)
+		}
+	case EncodingZstd:
+		content, err = zstdDecoder.DecodeAll(result.Payload, nil)
+	default:
+		return result, fmt.Errorf("types: unknown encoding %q of the result of %s", result.Encoding, result.CrawlerName)
+	}
+	if err != nil {
+		return result, fmt.Errorf("types: the data of the result of %s can´t be decompressed: %v", result.CrawlerName, err)
+	}
+
+	var data QuotePriceInfo
+	if err = json.Unmarshal(content, &data); err != nil {
+		return result, err
+	}
+
+	result.Data = data
+	result.Encoding, result.Payload = "", nil
+	return result, nil
+}
+
+// CompressEvidence compresses the data of all the results of the evidence with the encoding. It must be called
+// before the hash of the block is created, as the block is hashed with the compressed evidence. The results of the
+// evidence are copied, so the results of the caller don´t change
+func (block *FullSignedBlock) CompressEvidence(encoding string) error {
+	if len(block.Evidence) == 0 {
+		return nil
+	}
+
+	evidence := make([]Result, len(block.Evidence))
+	for i, result := range block.Evidence {
+		if err := result.Compress(encoding); err != nil {
+			return err
+		}
+		evidence[i] = result
+	}
+
+	block.Evidence = evidence
+	return nil
+}
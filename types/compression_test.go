@@ -0,0 +1,105 @@
+package types
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompressResult(t *testing.T) {
+	result := testResults(1)[0]
+	result.Data.DataURL = strings.Repeat("https://example.com/ticker?pair=BTCUSD&", 20)
+	if err := result.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []string{EncodingGzip, EncodingZstd} {
+		compressed := result
+		if err := compressed.Compress(encoding); err != nil {
+			t.Fatal(err)
+		}
+		if compressed.Encoding != encoding || compressed.Data != (QuotePriceInfo{}) || compressed.Hash != result.Hash {
+			t.Errorf("the result compressed with %s is %v", encoding, compressed)
+		}
+		if len(compressed.Payload) >= len(result.Data.DataURL) {
+			t.Errorf("the payload of %s has %d bytes, for a data URL of %d", encoding, len(compressed.Payload), len(result.Data.DataURL))
+		}
+		if err := compressed.Verify(); err != nil {
+			t.Errorf("the result compressed with %s doesn´t match their hash: %v", encoding, err)
+		}
+
+		// Compressing again keeps the first compression
+		again := compressed
+		if err := again.Compress(EncodingGzip); err != nil || !reflect.DeepEqual(again, compressed) {
+			t.Errorf("compressing the result compressed with %s again changed it (%v)", encoding, err)
+		}
+
+		decompressed, err := compressed.Decompressed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decompressed, result) {
+			t.Errorf("the result decompressed from %s is %v, expected %v", encoding, decompressed, result)
+		}
+	}
+
+	unknown := result
+	if err := unknown.Compress("lz4"); err == nil || !reflect.DeepEqual(unknown, result) {
+		t.Errorf("the result was compressed with an unknown encoding (%v)", err)
+	}
+}
+
+func TestDecompressedInvalid(t *testing.T) {
+	result := testResults(1)[0]
+	if err := result.Compress(EncodingZstd); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(result *Result)
+	}{
+		{"unknown encoding", func(result *Result) { result.Encoding = "lz4" }},
+		{"corrupt payload", func(result *Result) { result.Payload = []byte("not zstd") }},
+		{"payload of another encoding", func(result *Result) { result.Encoding = EncodingGzip }},
+	}
+	for _, test := range tests {
+		broken := changedResult(result, test.change)
+		if _, err := broken.Decompressed(); err == nil {
+			t.Errorf("the result with a %s was decompressed", test.name)
+		}
+		if err := broken.Verify(); !errors.Is(err, ErrInvalidEvidence) {
+			t.Errorf("verifying the result with a %s returned %v, expected ErrInvalidEvidence", test.name, err)
+		}
+	}
+}
+
+// The blocks are hashed with the compressed evidence, and the results of the caller don´t change
+func TestCompressEvidence(t *testing.T) {
+	results := testResults(3)
+	block := FullSignedBlock{Version: CurrentBlockVersion, Height: 1, Timestamp: 1600000000, Evidence: results}
+	if err := block.CompressEvidence(EncodingGzip); err != nil {
+		t.Fatal(err)
+	}
+	block.CreateEvidenceRoot()
+	if err := block.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	if results[0].Encoding != "" {
+		t.Error("compressing the evidence changed the results of the caller")
+	}
+	for i, result := range block.Evidence {
+		if result.Encoding != EncodingGzip {
+			t.Errorf("the result %d has the encoding %q", i, result.Encoding)
+		}
+	}
+	if err := block.Validate(nil); err != nil {
+		t.Errorf("the block with compressed evidence is invalid: %v", err)
+	}
+
+	if err := block.CompressEvidence("lz4"); err != nil {
+		t.Errorf("compressing the evidence again failed: %v", err)
+	}
+}
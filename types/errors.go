@@ -0,0 +1,15 @@
+package types
+
+import "errors"
+
+// The errors shared by the packages, so the callers can tell them apart with errors.Is, whatever the backend or the
+// layer that returned them
+var (
+	// ErrNotFound is returned when a block or a value is not stored
+	ErrNotFound = errors.New("types: not found")
+	// ErrInvalidBlock is wrapped by the errors of the blocks that break a rule of the chain, see ValidationError
+	ErrInvalidBlock = errors.New("types: invalid block")
+	// ErrDuplicate is wrapped by the errors of the blocks that can´t be stored, because another block is already
+	// stored at their height or with their hash
+	ErrDuplicate = errors.New("types: duplicate block")
+)
@@ -0,0 +1,110 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// GenesisMemo starts the memo of the genesis blocks, followed by the parameters of the chain
+	GenesisMemo = "DarkMatter genesis"
+
+	// DefaultGenesisTimestamp is the timestamp of the genesis blocks whose config has none (2019-11-13 00:00 UTC)
+	DefaultGenesisTimestamp uint64 = 1573603200
+)
+
+// GenesisConfig holds the parameters of a chain. They are embedded in the memo of the genesis block, so the chains
+// with the same parameters have the same genesis block, and the ones with different parameters can´t be mixed
+type GenesisConfig struct {
+	Chain     string            `json:"chain"`
+	Timestamp uint64            `json:"timestamp"`
+	Params    map[string]string `json:"params,omitempty"` // Any other parameter of the chain
+}
+
+// The memo of the genesis block of a config, with the parameters in their canonical form
+func (config GenesisConfig) memo() (string, error) {
+	params, err := CanonicalJSON(config)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s", GenesisMemo, params), nil
+}
+
+// NewGenesisBlock builds the genesis block of the chain of the config. Everything in the block comes from the
+// config, so every node builds the same block: it is not signed, and has no evidence nor producing node
+func NewGenesisBlock(config GenesisConfig) (FullSignedBlock, error) {
+	if config.Timestamp == 0 {
+		config.Timestamp = DefaultGenesisTimestamp
+	}
+
+	memo, err := config.memo()
+	if err != nil {
+		return FullSignedBlock{}, err
+	}
+
+	block := FullSignedBlock{
+		Version:   CurrentBlockVersion,
+		Height:    0,
+		Timestamp: config.Timestamp,
+		Memo:      memo,
+	}
+	err = block.CreateHash()
+
+	return block, err
+}
+
+// GenesisParams returns the parameters of the chain embedded in a genesis block
+func (block FullSignedBlock) GenesisParams() (*GenesisConfig, error) {
+	if block.Height != 0 || !strings.HasPrefix(block.Memo, GenesisMemo+" ") {
+		return nil, fmt.Errorf("types: the block %s is not a genesis block", block.Hash)
+	}
+
+	var config GenesisConfig
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(block.Memo, GenesisMemo+" ")), &config); err != nil {
+		return nil, fmt.Errorf("types: the parameters of the genesis block %s are invalid: %v", block.Hash, err)
+	}
+
+	return &config, nil
+}
+
+// Check the rules of the genesis blocks of the versions that have them: no previous hash, the memo with the
+// parameters of the chain, and nothing that a node could add, so it is the same block for all the nodes
+func (block FullSignedBlock) validateGenesis() error {
+	if block.Version < BlockVersionCanonical {
+		return nil // The legacy chains started with a price block
+	}
+
+	config, err := block.GenesisParams()
+	if err != nil {
+		return err
+	}
+	if config.Timestamp != block.Timestamp {
+		return fmt.Errorf("the timestamp is not the one of the parameters, %d", config.Timestamp)
+	}
+	if block.PreviousHash != "" || len(block.Evidence) > 0 || block.Address != "" || block.Signature != "" {
+		return errors.New("the genesis block has content from a node")
+	}
+
+	return nil
+}
+
+// ValidateGenesis checks that the block is a valid genesis block, and the one of the chain of the config
+func (block FullSignedBlock) ValidateGenesis(config GenesisConfig) error {
+	if err := block.Validate(nil); err != nil {
+		return err
+	}
+
+	expected, err := NewGenesisBlock(config)
+	if err != nil {
+		return err
+	}
+	if block.Hash != expected.Hash {
+		return &ValidationError{Hash: block.Hash, Height: block.Height, Rule: ErrInvalidGenesis,
+			Detail: fmt.Sprintf("the genesis block of the chain %s is %s", config.Chain, expected.Hash)}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The genesis block only depends on the parameters of the chain
+func TestNewGenesisBlock(t *testing.T) {
+	config := GenesisConfig{Chain: "BTCUSD", Params: map[string]string{"sources": "binance,kraken"}}
+	genesis, err := NewGenesisBlock(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if genesis.Height != 0 || genesis.Timestamp != DefaultGenesisTimestamp || genesis.Version != CurrentBlockVersion {
+		t.Errorf("the genesis block is %v", genesis)
+	}
+	if err = genesis.Validate(nil); err != nil {
+		t.Errorf("the genesis block is invalid: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		config GenesisConfig
+		same   bool
+	}{
+		{"same parameters", GenesisConfig{Chain: "BTCUSD", Params: map[string]string{"sources": "binance,kraken"}}, true},
+		{"default timestamp set", GenesisConfig{Chain: "BTCUSD", Timestamp: DefaultGenesisTimestamp, Params: config.Params}, true},
+		{"another chain", GenesisConfig{Chain: "ETHUSD", Params: config.Params}, false},
+		{"another timestamp", GenesisConfig{Chain: "BTCUSD", Timestamp: 1600000000, Params: config.Params}, false},
+		{"another parameter", GenesisConfig{Chain: "BTCUSD"}, false},
+	}
+	for _, test := range tests {
+		block, err := NewGenesisBlock(test.config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if same := block.Hash == genesis.Hash; same != test.same {
+			t.Errorf("the genesis block with %s has the same hash: %t, expected %t", test.name, same, test.same)
+		}
+	}
+
+	params, err := genesis.GenesisParams()
+	config.Timestamp = DefaultGenesisTimestamp
+	if err != nil || !reflect.DeepEqual(*params, config) {
+		t.Errorf("the parameters of the genesis block are %v (%v), expected %v", params, err, config)
+	}
+	if _, err = testBlock(t).GenesisParams(); err == nil {
+		t.Error("a price block has parameters of a genesis block")
+	}
+}
+
+func TestValidateGenesis(t *testing.T) {
+	config := GenesisConfig{Chain: "BTCUSD"}
+	genesis, err := NewGenesisBlock(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := genesis
+	if err = signed.Sign(testKey(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		block  FullSignedBlock
+		config GenesisConfig
+		rule   error
+	}{
+		{"genesis block of the chain", genesis, config, nil},
+		{"genesis block of another chain", genesis, GenesisConfig{Chain: "ETHUSD"}, ErrInvalidGenesis},
+		{"signed genesis block", signed, config, ErrInvalidGenesis},
+		{"produced by a node", rehashed(t, genesis, func(block *FullSignedBlock) { block.Address = "node" }), config, ErrInvalidGenesis},
+		{"price block", testBlock(t), config, ErrInvalidGenesis},
+	}
+	for _, test := range tests {
+		if err := test.block.ValidateGenesis(test.config); !errors.Is(err, test.rule) {
+			t.Errorf("validating the %s returned %v, expected %v", test.name, err, test.rule)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// The names of the built-in hash providers
+const (
+	HashSHA256  = "sha256"
+	HashBLAKE2b = "blake2b" // BLAKE2b-256
+	HashSHA3    = "sha3"    // SHA3-256
+)
+
+// HashProvider is the hash function of the chain, used for the hashes of the blocks and the results, and for the
+// Merkle trees of the evidence. The sums must be 32 bytes, so the hashes keep their format
+type HashProvider interface {
+	Name() string
+	Sum(data []byte) []byte
+}
+
+type hashFunc struct {
+	name string
+	sum  func(data []byte) []byte
+}
+
+func (h hashFunc) Name() string {
+	return h.name
+}
+
+func (h hashFunc) Sum(data []byte) []byte {
+	return h.sum(data)
+}
+
+var hashProviders = map[string]HashProvider{
+	HashSHA256: hashFunc{HashSHA256, func(data []byte) []byte {
+		sum := sha256.Sum256(data)
+		return sum[:]
+	}},
+	HashBLAKE2b: hashFunc{HashBLAKE2b, func(data []byte) []byte {
+		sum := blake2b.Sum256(data)
+		return sum[:]
+	}},
+	HashSHA3: hashFunc{HashSHA3, func(data []byte) []byte {
+		sum := sha3.Sum256(data)
+		return sum[:]
+	}},
+}
+
+// The provider of the chain, SHA-256 unless it is changed with SetHashProvider
+var hashProvider = hashProviders[HashSHA256]
+
+// HashProviderByName returns one of the built-in hash providers
+func HashProviderByName(name string) (HashProvider, error) {
+	provider, exists := hashProviders[name]
+	if !exists {
+		return nil, fmt.Errorf("types: unknown hash provider %q", name)
+	}
+
+	return provider, nil
+}
+
+// SetHashProvider changes the hash function of all the chain. The blocks hashed with another provider don´t match
+// their hashes, so it must be set before using the blocks, and be the same in all the nodes of the chain
+func SetHashProvider(provider HashProvider) {
+	hashProvider = provider
+}
+
+// GetHashProvider returns the hash provider of the chain
+func GetHashProvider() HashProvider {
+	return hashProvider
+}
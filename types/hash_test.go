@@ -0,0 +1,66 @@
+package types
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// The sums of the providers, checked with their test vectors of the empty input
+func TestHashProviders(t *testing.T) {
+	tests := []struct {
+		name  string
+		empty string
+	}{
+		{HashSHA256, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{HashBLAKE2b, "0e5751c026e543b2e8ab2eb06099daa1d1e5df47778f7787faab45cdf12fe3a8"},
+		{HashSHA3, "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+	}
+	for _, test := range tests {
+		provider, err := HashProviderByName(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if provider.Name() != test.name {
+			t.Errorf("the provider %s is named %s", test.name, provider.Name())
+		}
+		if sum := hex.EncodeToString(provider.Sum(nil)); sum != test.empty {
+			t.Errorf("the %s sum of the empty input is %s, expected %s", test.name, sum, test.empty)
+		}
+	}
+
+	if _, err := HashProviderByName("md5"); err == nil {
+		t.Error("an unknown hash provider was returned")
+	}
+}
+
+// The blocks hashed with a provider only match their hash with the same provider
+func TestSetHashProvider(t *testing.T) {
+	blake2b, err := HashProviderByName(HashBLAKE2b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha256 := GetHashProvider()
+	defer SetHashProvider(sha256)
+
+	block := testBlock(t)
+	SetHashProvider(blake2b)
+	if GetHashProvider().Name() != HashBLAKE2b {
+		t.Errorf("the provider of the chain is %s", GetHashProvider().Name())
+	}
+	if valid, err := block.CheckHash(); err != nil || valid {
+		t.Errorf("the block hashed with sha256 matches their hash with blake2b: %t (%v)", valid, err)
+	}
+
+	hashed := testBlock(t)
+	if hashed.Hash == block.Hash || hashed.EvidenceRoot == block.EvidenceRoot {
+		t.Error("the block hashed with blake2b has the hash or the root of sha256")
+	}
+	if err = hashed.Validate(nil); err != nil {
+		t.Errorf("the block hashed with blake2b is invalid: %v", err)
+	}
+
+	SetHashProvider(sha256)
+	if valid, err := hashed.CheckHash(); err != nil || valid {
+		t.Errorf("the block hashed with blake2b matches their hash with sha256: %t (%v)", valid, err)
+	}
+}
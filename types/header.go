@@ -0,0 +1,164 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// BlockHeader is a view of the part of a block that identifies it and links it to the chain: the hash, the link with
+// the parent, the Merkle root of the evidence, the proof of work and the signature. The light clients and the sync
+// transfer only the headers, and get the bodies of the blocks they need.
+// FullSignedBlock doesn´t embed the header and the body: the legacy hashes are taken over the JSON of the block, in
+// the order of their fields, and the embedded structs would change it. So the block stays the stored and
+// transferred type, and the header and the body are copies of their fields
+type BlockHeader struct {
+	Version      uint32 `json:"version,omitempty"`
+	Hash         string `json:"hash"`
+	Height       uint64 `json:"height"`
+	Timestamp    uint64 `json:"timestamp"`
+	PreviousHash string `json:"previousHash"`
+	EvidenceRoot string `json:"evidenceRoot,omitempty"`
+	Nonce        uint64 `json:"nonce,omitempty"`
+	Difficulty   uint32 `json:"difficulty,omitempty"`
+	Signature    string `json:"signature,omitempty"`
+	PublicKey    string `json:"publicKey,omitempty"`
+}
+
+// BlockBody is a view of the content of a block: the price, the producing node and the evidence
+type BlockBody struct {
+	AveragePrice    float64      `json:"avgPrice"`
+	AverageVolume   float64      `json:"avgVolumen"`
+	Ticker          string       `json:"ticker"`
+	Address         string       `json:"address"`
+	PreviousAddress string       `json:"previousAddress"`
+	Memo            string       `json:"memo"`
+	Evidence        []Result     `json:"evidence"`
+	Candle          *PriceCandle `json:"candle,omitempty"`
+}
+
+// Header returns a copy of the fields of the header of the block
+func (block FullSignedBlock) Header() BlockHeader {
+	return BlockHeader{
+		Version:      block.Version,
+		Hash:         block.Hash,
+		Height:       block.Height,
+		Timestamp:    block.Timestamp,
+		PreviousHash: block.PreviousHash,
+		EvidenceRoot: block.EvidenceRoot,
+		Nonce:        block.Nonce,
+		Difficulty:   block.Difficulty,
+		Signature:    block.Signature,
+		PublicKey:    block.PublicKey,
+	}
+}
+
+// Body returns a copy of the fields of the body of the block. The evidence and the candle are shared
+func (block FullSignedBlock) Body() BlockBody {
+	return BlockBody{
+		AveragePrice:    block.AveragePrice,
+		AverageVolume:   block.AverageVolume,
+		Ticker:          block.Ticker,
+		Address:         block.Address,
+		PreviousAddress: block.PreviousAddress,
+		Memo:            block.Memo,
+		Evidence:        block.Evidence,
+		Candle:          block.Candle,
+	}
+}
+
+// Join builds the block of the header with a body, and checks that the body is the one of the header: the content
+// must match the hash, and the evidence the Merkle root
+func (header BlockHeader) Join(body BlockBody) (FullSignedBlock, error) {
+	block := FullSignedBlock{
+		Version:         header.Version,
+		Hash:            header.Hash,
+		Height:          header.Height,
+		Timestamp:       header.Timestamp,
+		AveragePrice:    body.AveragePrice,
+		AverageVolume:   body.AverageVolume,
+		Ticker:          body.Ticker,
+		PreviousHash:    header.PreviousHash,
+		Address:         body.Address,
+		PreviousAddress: body.PreviousAddress,
+		Memo:            body.Memo,
+		Evidence:        body.Evidence,
+		Candle:          body.Candle,
+		EvidenceRoot:    header.EvidenceRoot,
+		Nonce:           header.Nonce,
+		Difficulty:      header.Difficulty,
+		Signature:       header.Signature,
+		PublicKey:       header.PublicKey,
+	}
+
+	valid, err := block.CheckHash()
+	if err != nil {
+		return block, err
+	}
+	if !valid {
+		return block, &ValidationError{Hash: header.Hash, Height: header.Height, Rule: ErrInvalidHash,
+			Detail: "the body is not the one of the header"}
+	}
+	if header.EvidenceRoot != "" && header.EvidenceRoot != MerkleRoot(body.Evidence) {
+		return block, &ValidationError{Hash: header.Hash, Height: header.Height, Rule: ErrInvalidEvidence,
+			Detail: "the evidence doesn´t match the Merkle root"}
+	}
+
+	return block, nil
+}
+
+// Validate checks what can be checked of a block without their body: the version, the format of the hash, the
+// proof of work, the timestamp, and the link with the parent header, nil when it is not known
+func (header BlockHeader) Validate(parent *BlockHeader) error {
+	invalid := func(rule error, format string, args ...interface{}) error {
+		return &ValidationError{Hash: header.Hash, Height: header.Height, Rule: rule, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	if _, err := blockFormat(header.Version); err != nil {
+		return invalid(ErrUnknownVersion, "the version %d", header.Version)
+	}
+	if !blockHashFormat.MatchString(header.Hash) {
+		return invalid(ErrHashFormat, "%q", header.Hash)
+	}
+	if header.Height > 0 {
+		if detail := workProblem(header.Hash, header.Difficulty); detail != "" {
+			return invalid(ErrInsufficientWork, "%s", detail)
+		}
+	}
+
+	var parentTimestamp uint64
+	if parent != nil {
+		parentTimestamp = parent.Timestamp
+	}
+	if detail := timestampProblem(header.Timestamp, parentTimestamp, time.Now()); detail != "" {
+		return invalid(ErrInvalidTimestamp, "%s", detail)
+	}
+
+	switch {
+	case parent != nil && header.Height != parent.Height+1:
+		return invalid(ErrInvalidHeight, "the parent is at the height %d", parent.Height)
+	case parent != nil && header.PreviousHash != parent.Hash:
+		return invalid(ErrBrokenLink, "links to %s, but the parent is %s", header.PreviousHash, parent.Hash)
+	}
+
+	return nil
+}
+
+// VerifySignature checks that the hash of the header was signed with the private key of pub. Without the body it
+// can´t check that the content matches the hash, FullSignedBlock.VerifySignature does
+func (header BlockHeader) VerifySignature(pub ed25519.PublicKey) error {
+	if header.Signature == "" {
+		return ErrUnsignedBlock
+	}
+
+	signature, err := hex.DecodeString(header.Signature)
+	if err != nil || header.PublicKey != hex.EncodeToString(pub) {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(pub, []byte(header.Hash), signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
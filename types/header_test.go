@@ -0,0 +1,108 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The header and the body join back into the block
+func TestHeaderJoin(t *testing.T) {
+	block := testBlock(t)
+	if err := block.Sign(testKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	joined, err := block.Header().Join(block.Body())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(joined, block) {
+		t.Errorf("the joined block %v is not the block %v", joined, block)
+	}
+
+	other := rehashed(t, block, func(block *FullSignedBlock) { block.AveragePrice++ }).Body()
+	tampered := block.Body()
+	tampered.Evidence = append([]Result(nil), tampered.Evidence...)
+	tampered.Evidence[0] = block.Evidence[1]
+	unrooted := block.Header()
+	unrooted.EvidenceRoot = MerkleRoot(tampered.Evidence)
+
+	tests := []struct {
+		name   string
+		header BlockHeader
+		body   BlockBody
+		rule   error
+	}{
+		{"body of another block", block.Header(), other, ErrInvalidHash},
+		{"other evidence", block.Header(), tampered, ErrInvalidHash},
+		{"root of other evidence", unrooted, block.Body(), ErrInvalidHash},
+	}
+	for _, test := range tests {
+		if _, err := test.header.Join(test.body); !errors.Is(err, test.rule) {
+			t.Errorf("joining the %s returned %v, expected %v", test.name, err, test.rule)
+		}
+	}
+}
+
+func TestHeaderValidate(t *testing.T) {
+	parent := testBlock(t).Header()
+	header := rehashed(t, testBlock(t), func(block *FullSignedBlock) {
+		block.Height, block.PreviousHash = 2, parent.Hash
+	}).Header()
+	changed := func(change func(header *BlockHeader)) BlockHeader {
+		copied := header
+		change(&copied)
+		return copied
+	}
+
+	tests := []struct {
+		name   string
+		header BlockHeader
+		parent *BlockHeader
+		rule   error
+	}{
+		{"valid header", header, &parent, nil},
+		{"unknown parent", header, nil, nil},
+		{"unknown version", changed(func(header *BlockHeader) { header.Version = 99 }), &parent, ErrUnknownVersion},
+		{"hash without the prefix", changed(func(header *BlockHeader) { header.Hash = header.Hash[1:] }), &parent, ErrHashFormat},
+		{"no timestamp", changed(func(header *BlockHeader) { header.Timestamp = 0 }), &parent, ErrInvalidTimestamp},
+		{"height after a gap", changed(func(header *BlockHeader) { header.Height = 3 }), &parent, ErrInvalidHeight},
+		{"other parent", changed(func(header *BlockHeader) { header.PreviousHash = "dd00" }), &parent, ErrBrokenLink},
+	}
+	for _, test := range tests {
+		if err := test.header.Validate(test.parent); !errors.Is(err, test.rule) {
+			t.Errorf("validating the %s returned %v, expected %v", test.name, err, test.rule)
+		}
+	}
+}
+
+func TestHeaderSignature(t *testing.T) {
+	key := testKey(t)
+	block := testBlock(t)
+	if err := block.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	public := key.Public().(ed25519.PublicKey)
+
+	forged := block.Header()
+	forged.Hash = rehashed(t, block, func(block *FullSignedBlock) { block.AveragePrice++ }).Hash
+
+	tests := []struct {
+		name   string
+		header BlockHeader
+		err    error
+	}{
+		{"signed header", block.Header(), nil},
+		{"unsigned header", testBlock(t).Header(), ErrUnsignedBlock},
+		{"header with another hash", forged, ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		if err := test.header.VerifySignature(public); !errors.Is(err, test.err) {
+			t.Errorf("the signature of the %s verifies with %v, expected %v", test.name, err, test.err)
+		}
+	}
+	if err := block.Header().VerifySignature(testKey(t).Public().(ed25519.PublicKey)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("the signature verifies with another key: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+)
+
+// AddressPrefix is the human readable part of the bech32 addresses of the nodes
+const AddressPrefix = "dm"
+
+// ErrInvalidAddress is returned for an address that is not a bech32 address of a node
+var ErrInvalidAddress = errors.New("types: invalid node address")
+
+// NodeIdentity is the key pair of a node. The address of the node is derived from their public key, so the blocks
+// with an address can be verified against the key that signed them
+type NodeIdentity struct {
+	PrivateKey ed25519.PrivateKey
+}
+
+// NewNodeIdentity creates a node identity with a new random key pair
+func NewNodeIdentity() (*NodeIdentity, error) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NodeIdentity{PrivateKey: key}, nil
+}
+
+// LoadNodeIdentity reads the identity of the node from the file, where the private key is kept as the hex encoded
+// Ed25519 seed. A new identity is created and written to the file if it doesn´t exist
+func LoadNodeIdentity(fileLocation string) (*NodeIdentity, error) {
+	content, err := ioutil.ReadFile(fileLocation)
+	if os.IsNotExist(err) {
+		identity, err := NewNodeIdentity()
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Created a new identity for the node in", fileLocation)
+
+		return identity, identity.Save(fileLocation)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("types: the key in %s is not a valid Ed25519 seed", fileLocation)
+	}
+
+	return &NodeIdentity{PrivateKey: ed25519.NewKeyFromSeed(seed)}, nil
+}
+
+// Save writes the private key of the identity to the file, readable only by the owner
+func (identity NodeIdentity) Save(fileLocation string) error {
+	return ioutil.WriteFile(fileLocation, []byte(hex.EncodeToString(identity.PrivateKey.Seed())), 0600)
+}
+
+// PublicKey returns the public key of the node
+func (identity NodeIdentity) PublicKey() ed25519.PublicKey {
+	return identity.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+// Address returns the address of the node, derived from their public key
+func (identity NodeIdentity) Address() string {
+	return AddressOf(identity.PublicKey())
+}
+
+// AddressOf derives the address of a public key: the first 20 bytes of their sha256, bech32 encoded with the
+// AddressPrefix. The address doesn´t depend on the hash provider of the chain
+func AddressOf(pub ed25519.PublicKey) string {
+	hash := sha256.Sum256(pub)
+	return bech32Encode(AddressPrefix, convertBits(hash[:20], 8, 5, true))
+}
+
+// ValidateAddress checks that the address is a bech32 address of a node, with a valid checksum
+func ValidateAddress(address string) error {
+	prefix, data, err := bech32Decode(address)
+	if err != nil {
+		return err
+	}
+	if prefix != AddressPrefix || len(convertBits(data, 5, 8, false)) != 20 {
+		return ErrInvalidAddress
+	}
+
+	return nil
+}
+
+// VerifyAddress checks that the address of the block is the one of the public key that signed it
+func (block FullSignedBlock) VerifyAddress() error {
+	pub, err := hex.DecodeString(block.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return ErrUnsignedBlock
+	}
+	if block.Address != AddressOf(pub) {
+		return fmt.Errorf("%w: %s is not the address of the key of the block", ErrInvalidAddress, block.Address)
+	}
+
+	return nil
+}
+
+// The bech32 encoding of BIP-173
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+
+	return checksum
+}
+
+// The prefix expanded for the checksum, with the high bits of their characters, a zero, and the low bits
+func bech32Expand(prefix string) []byte {
+	expanded := make([]byte, 0, len(prefix)*2+1)
+	for i := 0; i < len(prefix); i++ {
+		expanded = append(expanded, prefix[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(prefix); i++ {
+		expanded = append(expanded, prefix[i]&31)
+	}
+
+	return expanded
+}
+
+func bech32Encode(prefix string, data []byte) string {
+	values := append(append(bech32Expand(prefix), data...), 0, 0, 0, 0, 0, 0)
+	polymod := bech32Polymod(values) ^ 1
+
+	var address strings.Builder
+	address.WriteString(prefix)
+	address.WriteByte('1')
+	for _, value := range data {
+		address.WriteByte(bech32Charset[value])
+	}
+	for i := 0; i < 6; i++ {
+		address.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+
+	return address.String()
+}
+
+func bech32Decode(address string) (string, []byte, error) {
+	if strings.ToLower(address) != address {
+		return "", nil, ErrInvalidAddress // The addresses are always written in lower case
+	}
+
+	separator := strings.LastIndexByte(address, '1')
+	if separator < 1 || separator+7 > len(address) {
+		return "", nil, ErrInvalidAddress
+	}
+
+	prefix := address[:separator]
+	data := make([]byte, 0, len(address)-separator-1)
+	for i := separator + 1; i < len(address); i++ {
+		value := strings.IndexByte(bech32Charset, address[i])
+		if value < 0 {
+			return "", nil, ErrInvalidAddress
+		}
+		data = append(data, byte(value))
+	}
+	if bech32Polymod(append(bech32Expand(prefix), data...)) != 1 {
+		return "", nil, ErrInvalidAddress
+	}
+
+	return prefix, data[:len(data)-6], nil
+}
+
+// Regroup the bits of the data, e.g. from bytes to the groups of 5 bits of bech32. Without padding, the bits left
+// over are dropped
+func convertBits(data []byte, from uint, to uint, pad bool) []byte {
+	var accumulator, bits uint
+	maximum := uint(1)<<to - 1
+
+	converted := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, value := range data {
+		accumulator = accumulator<<from | uint(value)
+		bits += from
+		for bits >= to {
+			bits -= to
+			converted = append(converted, byte((accumulator>>bits)&maximum))
+		}
+	}
+	if pad && bits > 0 {
+		converted = append(converted, byte((accumulator<<(to-bits))&maximum))
+	}
+
+	return converted
+}
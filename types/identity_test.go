@@ -0,0 +1,158 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The test vectors of BIP-173 in lower case, as the addresses are always written
+func TestBech32Decode(t *testing.T) {
+	tests := []struct {
+		address string
+		prefix  string
+		valid   bool
+	}{
+		{"a12uel5l", "a", true},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", "abcdef", true},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", "split", true},
+		{"?1ezyfcl", "?", true},
+		{"A12UEL5L", "", false},     // Upper case
+		{"a12uel5m", "", false},     // Wrong checksum
+		{"1nwldj5", "", false},      // Empty prefix
+		{"pzry9x0s0muk", "", false}, // No separator
+		{"x1b4n0q5v", "", false},    // Invalid character
+		{"li1dgmt3", "", false},     // Checksum too short
+	}
+	for _, test := range tests {
+		prefix, _, err := bech32Decode(test.address)
+		if test.valid && (err != nil || prefix != test.prefix) {
+			t.Errorf("%s decoded with the prefix %q (%v), expected %q", test.address, prefix, err, test.prefix)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%s decoded with %v, expected ErrInvalidAddress", test.address, err)
+		}
+	}
+}
+
+func TestBech32RoundTrip(t *testing.T) {
+	for _, data := range [][]byte{{}, {0}, bytes.Repeat([]byte{0xff}, 20), []byte("darkmatter")} {
+		groups := convertBits(data, 8, 5, true)
+		prefix, decoded, err := bech32Decode(bech32Encode(AddressPrefix, groups))
+		if err != nil || prefix != AddressPrefix {
+			t.Fatalf("the address of %x doesn´t decode: %v", data, err)
+		}
+		if back := convertBits(decoded, 5, 8, false); !bytes.Equal(back, data) {
+			t.Errorf("%x decoded as %x", data, back)
+		}
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	address := testIdentity(t).Address()
+	changed := address[:len(address)-1] + "q"
+	if changed == address {
+		changed = address[:len(address)-1] + "p"
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		valid   bool
+	}{
+		{"the address of a node", address, true},
+		{"a changed character", changed, false},
+		{"upper case", strings.ToUpper(address), false},
+		{"another prefix", bech32Encode("bc", convertBits(bytes.Repeat([]byte{1}, 20), 8, 5, true)), false},
+		{"a shorter hash", bech32Encode(AddressPrefix, convertBits(bytes.Repeat([]byte{1}, 10), 8, 5, true)), false},
+		{"empty", "", false},
+	}
+	for _, test := range tests {
+		err := ValidateAddress(test.address)
+		if test.valid && err != nil {
+			t.Errorf("%s is not valid: %v", test.name, err)
+		}
+		if !test.valid && !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%s returned %v, expected ErrInvalidAddress", test.name, err)
+		}
+	}
+
+	if !strings.HasPrefix(address, AddressPrefix+"1") {
+		t.Errorf("the address %s doesn´t start with the prefix", address)
+	}
+}
+
+func TestLoadNodeIdentity(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "node.key")
+
+	created, err := LoadNodeIdentity(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("the key file is readable by others: %v", info.Mode())
+	}
+
+	loaded, err := LoadNodeIdentity(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Address() != created.Address() {
+		t.Errorf("the identity loaded from the file is %s, expected %s", loaded.Address(), created.Address())
+	}
+
+	if err = ioutil.WriteFile(location, []byte("not a seed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadNodeIdentity(location); err == nil {
+		t.Error("a file without a seed was loaded")
+	}
+}
+
+func TestVerifyAddress(t *testing.T) {
+	identity, other := testIdentity(t), testIdentity(t)
+
+	tests := []struct {
+		name    string
+		address string
+		sign    bool
+		err     error
+	}{
+		{"the address of the key", identity.Address(), true, nil},
+		{"the address of another key", other.Address(), true, ErrInvalidAddress},
+		{"unsigned", identity.Address(), false, ErrUnsignedBlock},
+	}
+	for _, test := range tests {
+		block := testBlock(t)
+		block.Address = test.address
+		if err := block.CreateHash(); err != nil {
+			t.Fatal(err)
+		}
+		if test.sign {
+			if err := block.Sign(identity.PrivateKey); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := block.VerifyAddress(); !errors.Is(err, test.err) {
+			t.Errorf("VerifyAddress with %s returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
+
+func testIdentity(t *testing.T) *NodeIdentity {
+	identity, err := NewNodeIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return identity
+}
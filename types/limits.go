@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// BlockLimits are the maximum sizes of the blocks, checked by Validate, so a crawler that returns too much data
+// can´t bloat the blocks and break the websocket consumers. The sizes are of the JSON of the blocks and the results.
+// A zero limit is not checked
+type BlockLimits struct {
+	MaxBlockSize  int // The bytes of a block, with their evidence
+	MaxEvidence   int // The results of the evidence of a block
+	MaxResultSize int // The bytes of a result
+	MaxMemoSize   int // The bytes of the memo
+}
+
+// DefaultBlockLimits are the limits of the chain, unless they are changed with SetBlockLimits
+var DefaultBlockLimits = BlockLimits{
+	MaxBlockSize:  1 << 20,
+	MaxEvidence:   256,
+	MaxResultSize: 8 << 10,
+	MaxMemoSize:   4 << 10,
+}
+
+var blockLimits = DefaultBlockLimits
+
+// SetBlockLimits changes the limits of all the chain. The blocks over the limits are invalid, so they must be the
+// same in all the nodes of the chain
+func SetBlockLimits(limits BlockLimits) {
+	blockLimits = limits
+}
+
+// GetBlockLimits returns the limits of the chain
+func GetBlockLimits() BlockLimits {
+	return blockLimits
+}
+
+// CheckSize returns an error wrapping ErrBlockTooLarge if the result is over the size of the results, so it can be
+// dropped before it is part of a block
+func (result Result) CheckSize() error {
+	if blockLimits.MaxResultSize == 0 {
+		return nil
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if len(content) > blockLimits.MaxResultSize {
+		return fmt.Errorf("types: the result of %s has %d bytes, over the limit of %d: %w", result.CrawlerName,
+			len(content), blockLimits.MaxResultSize, ErrBlockTooLarge)
+	}
+
+	return nil
+}
+
+// CheckSize returns a ValidationError with the rule ErrBlockTooLarge if the block is over the limits of the chain, so
+// it can be rejected before it is linked to their parent or written
+func (block FullSignedBlock) CheckSize() error {
+	detail, err := block.checkLimits()
+	if err != nil {
+		return err
+	}
+	if detail != "" {
+		return &ValidationError{Hash: block.Hash, Height: block.Height, Rule: ErrBlockTooLarge, Detail: detail}
+	}
+
+	return nil
+}
+
+// Check the sizes of the block against the limits of the chain. Returns the detail of the broken limit, or an
+// empty string
+func (block FullSignedBlock) checkLimits() (string, error) {
+	limits := blockLimits
+
+	if limits.MaxMemoSize > 0 && len(block.Memo) > limits.MaxMemoSize {
+		return fmt.Sprintf("the memo has %d bytes, over the limit of %d", len(block.Memo), limits.MaxMemoSize), nil
+	}
+	if limits.MaxEvidence > 0 && len(block.Evidence) > limits.MaxEvidence {
+		return fmt.Sprintf("the evidence has %d results, over the limit of %d", len(block.Evidence), limits.MaxEvidence), nil
+	}
+	for i, result := range block.Evidence {
+		if err := result.CheckSize(); errors.Is(err, ErrBlockTooLarge) {
+			return fmt.Sprintf("the result %d is over the limit of %d bytes", i, limits.MaxResultSize), nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	if limits.MaxBlockSize > 0 {
+		content, err := json.Marshal(block)
+		if err != nil {
+			return "", err
+		}
+		if len(content) > limits.MaxBlockSize {
+			return fmt.Sprintf("the block has %d bytes, over the limit of %d", len(content), limits.MaxBlockSize), nil
+		}
+	}
+
+	return "", nil
+}
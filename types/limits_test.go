@@ -0,0 +1,68 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckSize(t *testing.T) {
+	defer SetBlockLimits(GetBlockLimits())
+	SetBlockLimits(BlockLimits{MaxResultSize: 300})
+
+	small := testResults(1)[0]
+	large := small
+	large.Data.DataURL = strings.Repeat("x", 300)
+
+	tests := []struct {
+		name   string
+		result Result
+		err    error
+	}{
+		{"small result", small, nil},
+		{"large result", large, ErrBlockTooLarge},
+	}
+	for _, test := range tests {
+		if err := test.result.CheckSize(); !errors.Is(err, test.err) {
+			t.Errorf("the size of the %s checks with %v, expected %v", test.name, err, test.err)
+		}
+	}
+
+	SetBlockLimits(BlockLimits{})
+	if err := large.CheckSize(); err != nil {
+		t.Errorf("the size of a result is checked without limit: %v", err)
+	}
+}
+
+// Validate rejects the blocks over each limit
+func TestValidateLimits(t *testing.T) {
+	defer SetBlockLimits(GetBlockLimits())
+
+	block := testBlock(t)
+	memo := rehashed(t, block, func(block *FullSignedBlock) { block.Memo = strings.Repeat("m", 100) })
+
+	tests := []struct {
+		name   string
+		limits BlockLimits
+		block  FullSignedBlock
+		err    error
+	}{
+		{"default limits", DefaultBlockLimits, memo, nil},
+		{"no limits", BlockLimits{}, memo, nil},
+		{"memo over the limit", BlockLimits{MaxMemoSize: 99}, memo, ErrBlockTooLarge},
+		{"memo at the limit", BlockLimits{MaxMemoSize: 100}, memo, nil},
+		{"evidence over the limit", BlockLimits{MaxEvidence: 2}, block, ErrBlockTooLarge},
+		{"result over the limit", BlockLimits{MaxResultSize: 50}, block, ErrBlockTooLarge},
+		{"block over the limit", BlockLimits{MaxBlockSize: 500}, block, ErrBlockTooLarge},
+	}
+	for _, test := range tests {
+		SetBlockLimits(test.limits)
+		err := test.block.Validate(nil)
+		if !errors.Is(err, test.err) {
+			t.Errorf("validating with the %s returned %v, expected %v", test.name, err, test.err)
+		}
+		if test.err != nil && !errors.Is(err, ErrInvalidBlock) {
+			t.Errorf("validating with the %s returned %v, expected an invalid block", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package types
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+)
+
+// The prefixes of the hashes of the leaves and of the inner nodes, so a leaf can´t be taken for a node
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// ErrProofIndex is returned when asking the proof of a result that is not in the evidence
+var ErrProofIndex = errors.New("types: the result is not in the evidence")
+
+// MerkleStep is a sibling on the path from a leaf to the root
+type MerkleStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"` // The sibling is at the left of the path
+}
+
+// MerkleProof proves that a result is part of the evidence of a block, with the siblings of the path from their
+// leaf to the root
+type MerkleProof struct {
+	Index int          `json:"index"`
+	Steps []MerkleStep `json:"steps"`
+}
+
+// The hash of the leaf of a result
+func merkleLeaf(result Result) []byte {
+	return hashProvider.Sum(append([]byte{merkleLeafPrefix}, result.Hash...))
+}
+
+// The hash of an inner node
+func merkleNode(left []byte, right []byte) []byte {
+	content := make([]byte, 0, 1+len(left)+len(right))
+	content = append(append(append(content, merkleNodePrefix), left...), right...)
+
+	return hashProvider.Sum(content)
+}
+
+// Build the levels of the tree, from the leaves to the root. The last node of an odd level is promoted to the next
+// one, instead of being paired with itself, so repeating the last result changes the root
+func merkleLevels(results []Result) [][][]byte {
+	level := make([][]byte, len(results))
+	for i, result := range results {
+		level[i] = merkleLeaf(result)
+	}
+
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, merkleNode(level[i], level[i+1]))
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return levels
+}
+
+// MerkleRoot returns the hex encoded Merkle root of the results, using their hashes as leaves. The results must be
+// hashed before. The root of no results is empty
+func MerkleRoot(results []Result) string {
+	if len(results) == 0 {
+		return ""
+	}
+
+	levels := merkleLevels(results)
+	return hex.EncodeToString(levels[len(levels)-1][0])
+}
+
+// CreateEvidenceRoot sets the Merkle root of the evidence. It must be called before CreateHash, so the root is part
+// of the hash
+func (block *FullSignedBlock) CreateEvidenceRoot() {
+	block.EvidenceRoot = MerkleRoot(block.Evidence)
+}
+
+// EvidenceProof returns the proof that the result at the index is part of the evidence of the block
+func (block FullSignedBlock) EvidenceProof(index int) (*MerkleProof, error) {
+	if index < 0 || index >= len(block.Evidence) {
+		return nil, ErrProofIndex
+	}
+
+	proof := &MerkleProof{Index: index}
+	levels := merkleLevels(block.Evidence)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) { // Else the node is promoted, and there is no step
+			proof.Steps = append(proof.Steps, MerkleStep{Hash: hex.EncodeToString(level[sibling]), Left: sibling < index})
+		}
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// VerifyEvidenceProof checks that the result is part of the evidence with the Merkle root, so a light client only
+// needs the root of the block, the result and the proof. The leaf is the hash of the result, so the hash is first
+// recomputed from their content: a result whose content was changed doesn´t verify, even with their hash
+func VerifyEvidenceProof(root string, result Result, proof MerkleProof) bool {
+	expected, err := hex.DecodeString(root)
+	if err != nil || root == "" {
+		return false
+	}
+	if result.Verify() != nil {
+		return false
+	}
+
+	hash := merkleLeaf(result)
+	for _, step := range proof.Steps {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		if step.Left {
+			hash = merkleNode(sibling, hash)
+		} else {
+			hash = merkleNode(hash, sibling)
+		}
+	}
+
+	return bytes.Equal(hash, expected)
+}
@@ -0,0 +1,118 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	results := testResults(5)
+	leaf := func(i int) []byte { return merkleLeaf(results[i]) }
+
+	tests := []struct {
+		name    string
+		results []Result
+		root    []byte
+	}{
+		{"one result", results[:1], leaf(0)},
+		{"two results", results[:2], merkleNode(leaf(0), leaf(1))},
+		{"three results, the last one promoted", results[:3], merkleNode(merkleNode(leaf(0), leaf(1)), leaf(2))},
+		{"five results", results, merkleNode(
+			merkleNode(merkleNode(leaf(0), leaf(1)), merkleNode(leaf(2), leaf(3))),
+			leaf(4),
+		)},
+	}
+	for _, test := range tests {
+		if root := MerkleRoot(test.results); root != hex.EncodeToString(test.root) {
+			t.Errorf("the root of %s is %s, expected %s", test.name, root, hex.EncodeToString(test.root))
+		}
+	}
+
+	if root := MerkleRoot(nil); root != "" {
+		t.Errorf("the root of no results is %q", root)
+	}
+}
+
+// Repeating the last result changes the root, as the last node is promoted instead of paired with itself
+func TestMerkleRootRepeatedResult(t *testing.T) {
+	results := testResults(3)
+	repeated := append(append([]Result(nil), results...), results[2])
+
+	if MerkleRoot(results) == MerkleRoot(repeated) {
+		t.Error("repeating the last result keeps the root")
+	}
+}
+
+func TestEvidenceProof(t *testing.T) {
+	for count := 1; count <= 7; count++ {
+		block := FullSignedBlock{Evidence: testResults(count)}
+		block.CreateEvidenceRoot()
+
+		for i, result := range block.Evidence {
+			proof, err := block.EvidenceProof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !VerifyEvidenceProof(block.EvidenceRoot, result, *proof) {
+				t.Errorf("the proof of the result %d of %d doesn´t verify", i, count)
+			}
+
+			// The proof is only valid for their result
+			other := testResults(count + 1)[count]
+			if VerifyEvidenceProof(block.EvidenceRoot, other, *proof) {
+				t.Errorf("the proof of the result %d of %d verifies another result", i, count)
+			}
+		}
+	}
+}
+
+func TestEvidenceProofInvalid(t *testing.T) {
+	block := FullSignedBlock{Evidence: testResults(4)}
+	block.CreateEvidenceRoot()
+	proof, err := block.EvidenceProof(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := *proof
+	tampered.Steps = append([]MerkleStep(nil), proof.Steps...)
+	tampered.Steps[0].Left = !tampered.Steps[0].Left
+
+	tests := []struct {
+		name  string
+		root  string
+		proof MerkleProof
+	}{
+		{"empty root", "", *proof},
+		{"root not in hex", "zz", *proof},
+		{"another root", MerkleRoot(testResults(3)), *proof},
+		{"sibling on the wrong side", block.EvidenceRoot, tampered},
+		{"no steps", block.EvidenceRoot, MerkleProof{Index: 1}},
+	}
+	for _, test := range tests {
+		if VerifyEvidenceProof(test.root, block.Evidence[1], test.proof) {
+			t.Errorf("the proof with %s verifies", test.name)
+		}
+	}
+
+	// A result changed after it was hashed keeps the leaf of their hash, but their content doesn´t match it
+	results := []struct {
+		name   string
+		result Result
+	}{
+		{"tampered data", changedResult(block.Evidence[1], func(result *Result) { result.Data.HighPrice++ })},
+		{"tampered crawler", changedResult(block.Evidence[1], func(result *Result) { result.CrawlerName = "other" })},
+	}
+	for _, test := range results {
+		if VerifyEvidenceProof(block.EvidenceRoot, test.result, *proof) {
+			t.Errorf("the proof of the result with %s verifies", test.name)
+		}
+	}
+
+	for _, index := range []int{-1, 4} {
+		if _, err := block.EvidenceProof(index); !errors.Is(err, ErrProofIndex) {
+			t.Errorf("the proof of the index %d returned %v, expected ErrProofIndex", index, err)
+		}
+	}
+}
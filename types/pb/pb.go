@@ -0,0 +1,7 @@
+// Package pb holds the protobuf messages of the core types. The conversions from and to the types are in the types
+// package
+package pb
+
+// The messages are generated with protoc v3.14.0 and protoc-gen-go v1.25.0, the version of google.golang.org/protobuf
+// in go.mod
+//go:generate protoc --go_out=paths=source_relative:. darkmatter.proto
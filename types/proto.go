@@ -0,0 +1,199 @@
+package types
+
+import (
+	"github.com/aquarelle-tech/darkmatter/types/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// ToProto converts the data of a crawler to their protobuf message
+func (info QuotePriceInfo) ToProto() *pb.QuotePriceInfo {
+	return &pb.QuotePriceInfo{
+		QuoteVolume: info.QuoteVolume,
+		Volume:      info.Volume,
+		HighPrice:   info.HighPrice,
+		OpenPrice:   info.OpenPrice,
+		Timestamp:   info.Timestamp,
+		DataUrl:     info.DataURL,
+	}
+}
+
+// QuotePriceInfoFromProto converts a protobuf message to the data of a crawler
+func QuotePriceInfoFromProto(msg *pb.QuotePriceInfo) QuotePriceInfo {
+	return QuotePriceInfo{
+		QuoteVolume: msg.GetQuoteVolume(),
+		Volume:      msg.GetVolume(),
+		HighPrice:   msg.GetHighPrice(),
+		OpenPrice:   msg.GetOpenPrice(),
+		Timestamp:   msg.GetTimestamp(),
+		DataURL:     msg.GetDataUrl(),
+	}
+}
+
+// ToProto converts a result to their protobuf message
+func (result Result) ToProto() *pb.Result {
+	return &pb.Result{
+		CrawlerName: result.CrawlerName,
+		Data:        result.Data.ToProto(),
+		HasError:    result.HasError,
+		Timestamp:   result.Timestamp,
+		Ticker:      result.Ticker,
+		Hash:        result.Hash,
+		Encoding:    result.Encoding,
+		Payload:     result.Payload,
+		Signature:   result.Signature,
+		PublicKey:   result.PublicKey,
+	}
+}
+
+// ResultFromProto converts a protobuf message to a result
+func ResultFromProto(msg *pb.Result) Result {
+	return Result{
+		CrawlerName: msg.GetCrawlerName(),
+		Data:        QuotePriceInfoFromProto(msg.GetData()),
+		HasError:    msg.GetHasError(),
+		Timestamp:   msg.GetTimestamp(),
+		Ticker:      msg.GetTicker(),
+		Hash:        msg.GetHash(),
+		Encoding:    msg.GetEncoding(),
+		Payload:     msg.GetPayload(),
+		Signature:   msg.GetSignature(),
+		PublicKey:   msg.GetPublicKey(),
+	}
+}
+
+// ToProto converts a candle to their protobuf message
+func (candle PriceCandle) ToProto() *pb.PriceCandle {
+	return &pb.PriceCandle{
+		Ticker: candle.Ticker,
+		Start:  candle.Start,
+		Period: candle.Period,
+		Open:   candle.Open,
+		High:   candle.High,
+		Low:    candle.Low,
+		Close:  candle.Close,
+		Volume: candle.Volume,
+	}
+}
+
+// PriceCandleFromProto converts a protobuf message to a candle
+func PriceCandleFromProto(msg *pb.PriceCandle) PriceCandle {
+	return PriceCandle{
+		Ticker: msg.GetTicker(),
+		Start:  msg.GetStart(),
+		Period: msg.GetPeriod(),
+		Open:   msg.GetOpen(),
+		High:   msg.GetHigh(),
+		Low:    msg.GetLow(),
+		Close:  msg.GetClose(),
+		Volume: msg.GetVolume(),
+	}
+}
+
+// ToProto converts a block to their protobuf message
+func (block FullSignedBlock) ToProto() *pb.FullSignedBlock {
+	msg := &pb.FullSignedBlock{
+		Version:         block.Version,
+		Hash:            block.Hash,
+		Height:          block.Height,
+		Timestamp:       block.Timestamp,
+		AveragePrice:    block.AveragePrice,
+		AverageVolume:   block.AverageVolume,
+		Ticker:          block.Ticker,
+		PreviousHash:    block.PreviousHash,
+		Address:         block.Address,
+		PreviousAddress: block.PreviousAddress,
+		Memo:            block.Memo,
+		Signature:       block.Signature,
+		PublicKey:       block.PublicKey,
+		EvidenceRoot:    block.EvidenceRoot,
+		Nonce:           block.Nonce,
+		Difficulty:      block.Difficulty,
+	}
+	for _, result := range block.Evidence {
+		msg.Evidence = append(msg.Evidence, result.ToProto())
+	}
+	if block.Candle != nil {
+		msg.Candle = block.Candle.ToProto()
+	}
+
+	return msg
+}
+
+// BlockFromProto converts a protobuf message to a block. A block without evidence keeps it nil, as in JSON
+func BlockFromProto(msg *pb.FullSignedBlock) FullSignedBlock {
+	block := FullSignedBlock{
+		Version:         msg.GetVersion(),
+		Hash:            msg.GetHash(),
+		Height:          msg.GetHeight(),
+		Timestamp:       msg.GetTimestamp(),
+		AveragePrice:    msg.GetAveragePrice(),
+		AverageVolume:   msg.GetAverageVolume(),
+		Ticker:          msg.GetTicker(),
+		PreviousHash:    msg.GetPreviousHash(),
+		Address:         msg.GetAddress(),
+		PreviousAddress: msg.GetPreviousAddress(),
+		Memo:            msg.GetMemo(),
+		Signature:       msg.GetSignature(),
+		PublicKey:       msg.GetPublicKey(),
+		EvidenceRoot:    msg.GetEvidenceRoot(),
+		Nonce:           msg.GetNonce(),
+		Difficulty:      msg.GetDifficulty(),
+	}
+	for _, result := range msg.GetEvidence() {
+		block.Evidence = append(block.Evidence, ResultFromProto(result))
+	}
+	if msg.GetCandle() != nil {
+		candle := PriceCandleFromProto(msg.GetCandle())
+		block.Candle = &candle
+	}
+
+	return block
+}
+
+// ToProto converts a lite message to their protobuf message
+func (msg LiteIndexValueMessage) ToProto() *pb.LiteIndexValueMessage {
+	return &pb.LiteIndexValueMessage{
+		Hash:              msg.Hash,
+		Height:            msg.Height,
+		PriceIndex:        msg.PriceIndex,
+		Quoted:            msg.Quoted,
+		NodeAddress:       msg.NodeAddress,
+		Timestamp:         msg.Timestamp,
+		Confirmations:     msg.Confirmations,
+		SourceCount:       int64(msg.SourceCount),
+		AggregationMethod: msg.AggregationMethod,
+		StdDeviation:      msg.StdDeviation,
+	}
+}
+
+// LiteIndexValueMessageFromProto converts a protobuf message to a lite message
+func LiteIndexValueMessageFromProto(msg *pb.LiteIndexValueMessage) LiteIndexValueMessage {
+	return LiteIndexValueMessage{
+		Hash:              msg.GetHash(),
+		Height:            msg.GetHeight(),
+		PriceIndex:        msg.GetPriceIndex(),
+		Quoted:            msg.GetQuoted(),
+		NodeAddress:       msg.GetNodeAddress(),
+		Timestamp:         msg.GetTimestamp(),
+		Confirmations:     msg.GetConfirmations(),
+		SourceCount:       int(msg.GetSourceCount()),
+		AggregationMethod: msg.GetAggregationMethod(),
+		StdDeviation:      msg.GetStdDeviation(),
+	}
+}
+
+// MarshalProto serializes a block in the protobuf wire format
+func (block FullSignedBlock) MarshalProto() ([]byte, error) {
+	return proto.Marshal(block.ToProto())
+}
+
+// UnmarshalBlockProto deserializes a block from the protobuf wire format
+func UnmarshalBlockProto(data []byte) (*FullSignedBlock, error) {
+	var msg pb.FullSignedBlock
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+
+	block := BlockFromProto(&msg)
+	return &block, nil
+}
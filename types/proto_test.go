@@ -0,0 +1,86 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"reflect"
+	"testing"
+)
+
+// The blocks come back unchanged from the wire format, so their hashes and signatures still verify
+func TestBlockProtoRoundTrip(t *testing.T) {
+	signed := testBlock(t)
+	key := testKey(t)
+	if err := signed.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	linked := testBlock(t)
+	linked.PreviousAddress = "previous"
+	linked.Memo = "a memo"
+
+	tests := []struct {
+		name  string
+		block FullSignedBlock
+	}{
+		{"signed block", signed},
+		{"block with a previous address and a memo", linked},
+		{"block without evidence", FullSignedBlock{Height: 7, Ticker: "ETHUSD"}},
+	}
+	for _, test := range tests {
+		data, err := test.block.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := UnmarshalBlockProto(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*block, test.block) {
+			t.Errorf("the %s changed to %v", test.name, *block)
+		}
+	}
+
+	block, err := UnmarshalBlockProto(mustMarshalProto(t, signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = block.VerifySignature(key.Public().(ed25519.PublicKey)); err != nil {
+		t.Errorf("the signature of the block read back doesn´t verify: %v", err)
+	}
+}
+
+func mustMarshalProto(t *testing.T, block FullSignedBlock) []byte {
+	data, err := block.MarshalProto()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestMessagesProtoRoundTrip(t *testing.T) {
+	result := testResults(1)[0]
+	result.Data.DataURL = "https://example.com"
+	result.HasError = true
+	if got := ResultFromProto(result.ToProto()); !reflect.DeepEqual(got, result) {
+		t.Errorf("the result changed to %v", got)
+	}
+
+	lite := LiteIndexValueMessage{
+		Hash:          "hash",
+		Height:        3,
+		PriceIndex:    10000.5,
+		Quoted:        "BTCUSD",
+		NodeAddress:   "node",
+		Timestamp:     1600000000,
+		Confirmations: 2,
+	}
+	if got := LiteIndexValueMessageFromProto(lite.ToProto()); got != lite {
+		t.Errorf("the lite message changed to %v", got)
+	}
+}
+
+func TestUnmarshalBlockProtoInvalid(t *testing.T) {
+	if _, err := UnmarshalBlockProto([]byte{0xff, 0xff, 0xff}); err == nil {
+		t.Error("an invalid message was deserialized")
+	}
+}
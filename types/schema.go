@@ -0,0 +1,131 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The messages of the API with a schema, by the names used to get them
+var schemaTypes = map[string]reflect.Type{
+	"block":  reflect.TypeOf(FullSignedBlock{}),
+	"header": reflect.TypeOf(BlockHeader{}),
+	"result": reflect.TypeOf(Result{}),
+	"lite":   reflect.TypeOf(LiteIndexValueMessage{}),
+}
+
+// SchemaNames returns the names of the messages with a schema, sorted
+func SchemaNames() []string {
+	names := make([]string, 0, len(schemaTypes))
+	for name := range schemaTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Schema returns the JSON Schema (draft-07) of a message of the API by their name, or ErrNotFound
+func Schema(name string) ([]byte, error) {
+	t, exists := schemaTypes[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: there is no schema for %q", ErrNotFound, name)
+	}
+
+	return JSONSchema(reflect.New(t).Elem().Interface())
+}
+
+// JSONSchema generates the JSON Schema (draft-07) of the JSON of a value, from the json tags of their fields. The
+// fields with omitempty are optional, the rest are required. The nested structs are in the definitions
+func JSONSchema(v interface{}) ([]byte, error) {
+	generator := schemaGenerator{definitions: map[string]interface{}{}}
+	t := reflect.TypeOf(v)
+
+	schema := generator.object(t)
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = t.Name()
+	if len(generator.definitions) > 0 {
+		schema["definitions"] = generator.definitions
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+// The schema of a type. The structs are added to the definitions, and referenced
+func (g schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"} // As encoding/json
+		}
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if _, exists := g.definitions[t.Name()]; !exists {
+			g.definitions[t.Name()] = nil // Reserved, for the recursive types
+			g.definitions[t.Name()] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+	default:
+		return map[string]interface{}{} // Any value
+	}
+}
+
+// The schema of the object of a struct, with their exported fields
+func (g schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // Not exported
+		}
+
+		name, options := field.Name, ""
+		if tag, exists := field.Tag.Lookup("json"); exists {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.SplitN(tag, ",", 2)
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			if len(parts) > 1 {
+				options = parts[1]
+			}
+		}
+
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// The parts of a schema checked by the tests
+type testSchema struct {
+	Title       string                     `json:"title"`
+	Properties  map[string]json.RawMessage `json:"properties"`
+	Required    []string                   `json:"required"`
+	Definitions map[string]testSchema      `json:"definitions"`
+}
+
+func readSchema(t *testing.T, name string) testSchema {
+	content, err := Schema(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema testSchema
+	if err = json.Unmarshal(content, &schema); err != nil {
+		t.Fatalf("the schema of %s is not JSON: %v", name, err)
+	}
+
+	return schema
+}
+
+// The properties of the schemas are the keys of the JSON of the messages
+func TestSchemaProperties(t *testing.T) {
+	block := testBlock(t)
+	if err := block.Sign(testKey(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		title string
+		value interface{}
+	}{
+		{"block", "FullSignedBlock", block},
+		{"header", "BlockHeader", block.Header()},
+		{"result", "Result", block.Evidence[0]},
+		{"lite", "LiteIndexValueMessage", NewLiteIndexValueMessage(block, AggregationFirstSource)},
+	}
+	for _, test := range tests {
+		schema := readSchema(t, test.name)
+		if schema.Title != test.title {
+			t.Errorf("the schema of %s has the title %s, expected %s", test.name, schema.Title, test.title)
+		}
+
+		content, err := json.Marshal(test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]interface{}
+		if err = json.Unmarshal(content, &fields); err != nil {
+			t.Fatal(err)
+		}
+		for key := range fields {
+			if _, exists := schema.Properties[key]; !exists {
+				t.Errorf("the schema of %s doesn´t have the property %s", test.name, key)
+			}
+		}
+		for _, key := range schema.Required {
+			if _, exists := fields[key]; !exists {
+				t.Errorf("the property %s of %s is required, but it is not in their JSON", key, test.name)
+			}
+		}
+	}
+}
+
+// The fields with omitempty are optional, and the nested structs are in the definitions
+func TestSchemaRequired(t *testing.T) {
+	schema := readSchema(t, "result")
+	required := append([]string(nil), schema.Required...)
+	sort.Strings(required)
+	if expected := []string{"data", "hasError", "hash", "name", "ticker", "timestamp"}; !reflect.DeepEqual(required, expected) {
+		t.Errorf("the required properties of a result are %v, expected %v", required, expected)
+	}
+	if _, exists := schema.Definitions["QuotePriceInfo"]; !exists {
+		t.Errorf("the definitions of a result are %v, expected QuotePriceInfo", schema.Definitions)
+	}
+
+	block := readSchema(t, "block")
+	for _, name := range []string{"Result", "QuotePriceInfo"} {
+		if _, exists := block.Definitions[name]; !exists {
+			t.Errorf("the schema of a block doesn´t define %s", name)
+		}
+	}
+}
+
+func TestSchemaNames(t *testing.T) {
+	if names := SchemaNames(); !reflect.DeepEqual(names, []string{"block", "header", "lite", "result"}) {
+		t.Errorf("the schemas are %v", names)
+	}
+	if _, err := Schema("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the schema of an unknown message returned %v, expected ErrNotFound", err)
+	}
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxFutureTimestamp is how far in the future the timestamp of a valid block can be, for the clocks of the nodes
+// that are not in sync
+const MaxFutureTimestamp = 2 * time.Minute
+
+// BlockTime converts a timestamp of a block, the seconds since the epoch, to a time
+func BlockTime(timestamp uint64) time.Time {
+	return time.Unix(int64(timestamp), 0)
+}
+
+// BlockTimestamp converts a time to the timestamp of a block, without the fraction of second
+func BlockTimestamp(t time.Time) uint64 {
+	return uint64(t.Unix())
+}
+
+// Time returns when the block was created
+func (block FullSignedBlock) Time() time.Time {
+	return BlockTime(block.Timestamp)
+}
+
+// NextTimestamp returns the timestamp of a new block created at now after the parent, nil for the first block. It
+// is never before the timestamp of the parent, so a clock that goes back doesn´t make the new block invalid
+func NextTimestamp(parent *FullSignedBlock, now time.Time) uint64 {
+	timestamp := BlockTimestamp(now)
+	if parent != nil && timestamp < parent.Timestamp {
+		return parent.Timestamp
+	}
+
+	return timestamp
+}
+
+// CheckTimestamp checks the timestamp of a block: it must be set, not before the timestamp of the parent (0 if it
+// is not known) and not further in the future of now than MaxFutureTimestamp. The error wraps ErrInvalidTimestamp
+func CheckTimestamp(timestamp uint64, parent uint64, now time.Time) error {
+	if detail := timestampProblem(timestamp, parent, now); detail != "" {
+		return fmt.Errorf("types: %s: %w", detail, ErrInvalidTimestamp)
+	}
+
+	return nil
+}
+
+// The problem of a timestamp for CheckTimestamp, or an empty string
+func timestampProblem(timestamp uint64, parent uint64, now time.Time) string {
+	switch {
+	case timestamp == 0:
+		return "the block has no timestamp"
+	case timestamp < parent:
+		return fmt.Sprintf("the timestamp %d is before the one of the parent, %d", timestamp, parent)
+	case BlockTime(timestamp).After(now.Add(MaxFutureTimestamp)):
+		return fmt.Sprintf("the timestamp %d is in the future", timestamp)
+	}
+
+	return ""
+}
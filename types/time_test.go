@@ -0,0 +1,75 @@
+package types
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBlockTime(t *testing.T) {
+	now := time.Date(2020, 9, 13, 12, 26, 40, 500000000, time.UTC)
+	if timestamp := BlockTimestamp(now); timestamp != 1600000000 {
+		t.Errorf("the timestamp of %v is %d, expected 1600000000", now, timestamp)
+	}
+	if block := (FullSignedBlock{Timestamp: 1600000000}); !block.Time().Equal(now.Truncate(time.Second)) {
+		t.Errorf("the time of the block is %v, expected %v", block.Time(), now.Truncate(time.Second))
+	}
+}
+
+func TestNextTimestamp(t *testing.T) {
+	now := BlockTime(1600000000)
+	tests := []struct {
+		name      string
+		parent    *FullSignedBlock
+		timestamp uint64
+	}{
+		{"first block", nil, 1600000000},
+		{"parent in the past", &FullSignedBlock{Timestamp: 1599999990}, 1600000000},
+		{"parent in the same second", &FullSignedBlock{Timestamp: 1600000000}, 1600000000},
+		{"clock gone back", &FullSignedBlock{Timestamp: 1600000005}, 1600000005},
+	}
+	for _, test := range tests {
+		if timestamp := NextTimestamp(test.parent, now); timestamp != test.timestamp {
+			t.Errorf("the next timestamp after the %s is %d, expected %d", test.name, timestamp, test.timestamp)
+		}
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	now := BlockTime(1600000000)
+	limit := BlockTimestamp(now.Add(MaxFutureTimestamp))
+
+	tests := []struct {
+		name      string
+		timestamp uint64
+		parent    uint64
+		err       error
+	}{
+		{"timestamp after the parent", 1600000000, 1599999999, nil},
+		{"same timestamp than the parent", 1600000000, 1600000000, nil},
+		{"unknown parent", 1600000000, 0, nil},
+		{"at the future limit", limit, 0, nil},
+		{"no timestamp", 0, 0, ErrInvalidTimestamp},
+		{"before the parent", 1599999999, 1600000000, ErrInvalidTimestamp},
+		{"over the future limit", limit + 1, 0, ErrInvalidTimestamp},
+	}
+	for _, test := range tests {
+		if err := CheckTimestamp(test.timestamp, test.parent, now); !errors.Is(err, test.err) {
+			t.Errorf("checking the %s returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
+
+// Validate applies the monotonic rule against the parent
+func TestValidateTimestampBeforeParent(t *testing.T) {
+	blocks := testChainBlocks(t, 2)
+	earlier := rehashed(t, blocks[1], func(block *FullSignedBlock) { block.Timestamp = blocks[0].Timestamp - 1 })
+
+	if err := earlier.Validate(&blocks[0]); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("validating a block before their parent returned %v", err)
+	}
+	parent := blocks[0].Header()
+	if err := earlier.Header().Validate(&parent); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("validating a header before their parent returned %v", err)
+	}
+}
@@ -2,10 +2,12 @@
 package types
 
 import (
-	"crypto/sha256"
+	"crypto/ed25519"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"time"
+	"math"
 
 	"log"
 )
@@ -18,14 +20,31 @@ const (
 	BlockHashPrefix = "dd"
 )
 
+var (
+	// ErrUnsignedBlock is returned when verifying a block without signature
+	ErrUnsignedBlock = errors.New("types: the block is not signed")
+	// ErrInvalidSignature is returned when the signature of a block is not the one of the key, or the content of the
+	// block changed after it was signed
+	ErrInvalidSignature = errors.New("types: the signature of the block is invalid")
+)
+
 // KVStore defines a KV pair storage manager definition
 type KVStore interface {
 	StoreValue(key string, value []byte) error
 	GetValue(key string) ([]byte, error)
 	StoreBlock(block FullSignedBlock) error
+	StoreBlocks(blocks []FullSignedBlock) error
 	GetBlock(hash string) (*FullSignedBlock, error)
 	FindBlockByTimestamp(timestamp uint64) (*FullSignedBlock, error)
 	FindBlockByHeight(Height uint64) (*FullSignedBlock, error)
+	GetLatestBlocks(timestamp uint64, offset int, limit int) ([]FullSignedBlock, error)
+	GetBlocksByHeightRange(from uint64, to uint64) ([]FullSignedBlock, error)
+	FindBlocksBetween(startTs uint64, endTs uint64, limit int) ([]FullSignedBlock, error)
+	GetHead() (*FullSignedBlock, error)
+	GetLatestHeight() (uint64, error)
+	GetBlocksByTicker(ticker string, limit int) ([]FullSignedBlock, error)
+	GetBlocksByAddress(address string, limit int) ([]FullSignedBlock, error)
+	FindBlocksByHashPrefix(prefix string, limit int) ([]FullSignedBlock, error)
 }
 
 // QuotePriceInfo is the model used to get the data
@@ -68,11 +87,61 @@ type LiteIndexValueMessage struct {
 	Quoted        string  `json:"quote"`
 	NodeAddress   string  `json:"nodeAddress"`
 	Timestamp     uint64  `json:"timestamp"`
-	Confirmations int     `json:"confirmations"`
+	Confirmations uint64  `json:"confirmations"` // The blocks from the block to the head, both included, when the message was sent
+
+	// How the price index was aggregated: the number of sources without errors, the method, and the standard
+	// deviation of the prices of the sources
+	SourceCount       int     `json:"sourceCount"`
+	AggregationMethod string  `json:"aggregationMethod"`
+	StdDeviation      float64 `json:"stdDeviation"`
+}
+
+// AggregationFirstSource is the aggregation method that takes the price of the first source
+const AggregationFirstSource = "first"
+
+// NewLiteIndexValueMessage creates the lite message of a block, with the price aggregated by method from the
+// evidence. The price of a source is their high price, as in the Reduce stage. The confirmations depend on the head
+// of the chain, so they are set when the message is sent
+func NewLiteIndexValueMessage(block FullSignedBlock, method string) LiteIndexValueMessage {
+	var prices []float64
+	for _, result := range block.Evidence {
+		result, err := result.Decompressed()
+		if err == nil && !result.HasError {
+			prices = append(prices, result.Data.HighPrice)
+		}
+	}
+
+	var deviation float64
+	if len(prices) > 0 {
+		var sum, squares float64
+		for _, price := range prices {
+			sum += price
+		}
+		mean := sum / float64(len(prices))
+		for _, price := range prices {
+			squares += (price - mean) * (price - mean)
+		}
+		deviation = math.Sqrt(squares / float64(len(prices)))
+	}
+
+	return LiteIndexValueMessage{
+		Hash:              block.Hash,
+		Height:            block.Height,
+		PriceIndex:        block.AveragePrice,
+		Quoted:            block.Ticker,
+		NodeAddress:       block.Address,
+		Timestamp:         block.Timestamp,
+		SourceCount:       len(prices),
+		AggregationMethod: method,
+		StdDeviation:      deviation,
+	}
 }
 
 // FullSignedBlock is the message to send to the connected clients through websocket
 type FullSignedBlock struct {
+	// The format of the block. Omitted for the legacy blocks, so their hashes don´t change
+	Version uint32 `json:"version,omitempty"`
+
 	Hash      string `json:"hash"`
 	Height    uint64 `json:"height"`
 	Timestamp uint64 `json:"timestamp"`
@@ -85,23 +154,104 @@ type FullSignedBlock struct {
 	PreviousAddress string   `json:"previousAddress"`
 	Memo            string   `json:"memo"`
 	Evidence        []Result `json:"evidence"`
+	// The Merkle root of the evidence, to prove that a result is part of it. Empty in the blocks created before it
+	EvidenceRoot string `json:"evidenceRoot,omitempty"`
+	// The aggregate price of the evidence, see NewPriceCandle. Omitted in the blocks created without it
+	Candle *PriceCandle `json:"candle,omitempty"`
+
+	// The proof of work: the hash starts with Difficulty zero bits, found by changing the Nonce. Omitted when the
+	// proof of work is off, see Mine
+	Nonce      uint64 `json:"nonce,omitempty"`
+	Difficulty uint32 `json:"difficulty,omitempty"`
+
+	// The Ed25519 signature of the hash, and the public key of the producer, hex encoded. They are not part of the
+	// hash, so the blocks signed before they existed keep their hashes
+	Signature string `json:"signature,omitempty"`
+	PublicKey string `json:"publicKey,omitempty"`
 }
 
-// CreateHash calculates the hash for a block
+// CreateHash calculates the hash for a block, in the way of their version. The signature and the public key are
+// not hashed
 func (block *FullSignedBlock) CreateHash() error {
+	format, err := blockFormat(block.Version)
+	if err != nil {
+		return err
+	}
+
+	hash, err := block.hashWith(format.Hashers[0])
+	block.Hash = hash
+
+	return err // No error
+}
 
+// Hash the block with a hash function, without the fields that are not hashed
+func (block FullSignedBlock) hashWith(hasher func(obj interface{}) (string, error)) (string, error) {
 	// create a hash the result
 	block.Hash = "" // To asure a clean hash
-	hash, err := calculateHash(block)
-	if err == nil {
-		block.Hash = hash
-	}
+	block.Signature, block.PublicKey = "", ""
+	hash, err := hasher(block)
 
 	// The hashes for the block has attached a prefix and the the number of seconds taken from the timestamp
-	seconds := time.Unix(int64(block.Timestamp), 0).Second()
-	block.Hash = fmt.Sprintf("%s%02d%s", BlockHashPrefix, seconds, block.Hash)
+	seconds := block.Time().Second()
+	return fmt.Sprintf("%s%02d%s", BlockHashPrefix, seconds, hash), err
+}
 
-	return err // No error
+// CheckHash returns true if the content of the block matches their hash, hashed in any of the ways of their version.
+// The previous address is set once the hash is created, so it is not part of the hash
+func (block FullSignedBlock) CheckHash() (bool, error) {
+	format, err := blockFormat(block.Version)
+	if err != nil {
+		return false, err
+	}
+
+	block.PreviousAddress = ""
+	for _, hasher := range format.Hashers {
+		hash, err := block.hashWith(hasher)
+		if err != nil {
+			return false, err
+		}
+		if hash == block.Hash {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Sign signs the hash of the block with the private key of the producer, so the hash must be created before. The
+// previous address is not part of the hash, so it can still be set after
+func (block *FullSignedBlock) Sign(priv ed25519.PrivateKey) error {
+	if block.Hash == "" {
+		return errors.New("types: the block must be hashed before signing it")
+	}
+
+	block.PublicKey = hex.EncodeToString(priv.Public().(ed25519.PublicKey))
+	block.Signature = hex.EncodeToString(ed25519.Sign(priv, []byte(block.Hash)))
+
+	return nil
+}
+
+// VerifySignature checks that the block was signed with the private key of pub, that their content matches the
+// signed hash, and that their address, if it has one, is the address of the key
+func (block FullSignedBlock) VerifySignature(pub ed25519.PublicKey) error {
+	if err := block.Header().VerifySignature(pub); err != nil {
+		return err
+	}
+	if block.Address != "" {
+		if err := block.VerifyAddress(); err != nil {
+			return err
+		}
+	}
+
+	valid, err := block.CheckHash()
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	return nil
 }
 
 // Implement the Stringer interface
@@ -128,13 +278,23 @@ type Result struct {
 	Timestamp   int64          `json:"timestamp"`
 	Ticker      string         `json:"ticker"`
 	Hash        string         `json:"hash"`
+
+	// The data compressed with the encoding, when the result is compressed, see Compress. Then Data is empty
+	Encoding string `json:"encoding,omitempty"`
+	Payload  []byte `json:"payload,omitempty"`
+
+	// The Ed25519 signature of the hash by the crawler that collected the result, and their public key, hex
+	// encoded, see Sign. They are not part of the hash
+	Signature string `json:"signature,omitempty"`
+	PublicKey string `json:"publicKey,omitempty"`
 }
 
-// CreateHash creates a double hash (sha256(sha256)) for all the content
+// CreateHash creates a double hash (sha256(sha256), or with the hash provider of the chain) for all the content
+// but the signature and the public key
 func (result *Result) CreateHash() error {
 	// create a hash the result
 	result.Hash = "" // To asure a clean hash
-	hash, err := calculateHash(result)
+	hash, err := calculateHash(result.unsigned())
 
 	if err == nil {
 		result.Hash = hash
@@ -143,16 +303,27 @@ func (result *Result) CreateHash() error {
 	return err // No error
 }
 
-// PriceEvidenceCrawler is the interface for clients
+// PriceEvidenceCrawler is the interface for clients. Each crawler has their own key pair: Crawl sends the result
+// hashed and signed with their private key, and GetPublicKey returns the public one, to verify the results
 type PriceEvidenceCrawler interface {
-	Crawl(quotedCurrency string, done chan QuotePriceInfo)
+	Crawl(quotedCurrency string, done chan Result)
 	GetName() string
 	GetTicker() string
+	GetPublicKey() ed25519.PublicKey
 }
 
-// Generate a hash using a double operation over the serialized content of object
+// Generate a hash using a double operation over the canonical form of the object
 func calculateHash(obj interface{}) (string, error) {
-	bytes, err := json.Marshal(obj)
+	return hashContent(obj, CanonicalJSON)
+}
+
+// The hash of the blocks and the results created before the canonical form, over their JSON
+func legacyHash(obj interface{}) (string, error) {
+	return hashContent(obj, json.Marshal)
+}
+
+func hashContent(obj interface{}, serialize func(v interface{}) ([]byte, error)) (string, error) {
+	bytes, err := serialize(obj)
 	if err != nil {
 		log.Println("Error serializing message", err)
 		return "", err
@@ -161,8 +332,8 @@ func calculateHash(obj interface{}) (string, error) {
 	rawContent := fmt.Sprintf("%s:%s", ServiceHash, bytes)
 
 	// Double hash for the content
-	doubleHash := fmt.Sprintf("%x", sha256.Sum256([]byte(rawContent)))
-	doubleHash = fmt.Sprintf("%x", sha256.Sum256([]byte(doubleHash)))
+	doubleHash := fmt.Sprintf("%x", hashProvider.Sum([]byte(rawContent)))
+	doubleHash = fmt.Sprintf("%x", hashProvider.Sum([]byte(doubleHash)))
 
 	return doubleHash, nil
 }
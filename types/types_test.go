@@ -0,0 +1,106 @@
+package types
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Results of the sources, hashed
+func testResults(count int) []Result {
+	results := make([]Result, count)
+	for i := range results {
+		results[i] = Result{
+			CrawlerName: fmt.Sprintf("crawler-%d", i),
+			Ticker:      "BTCUSD",
+			Timestamp:   1600000000,
+			Data:        QuotePriceInfo{HighPrice: 10000 + float64(i), Volume: 1.5},
+		}
+		results[i].CreateHash()
+	}
+
+	return results
+}
+
+// A hashed block of the current version, with evidence
+func testBlock(t *testing.T) FullSignedBlock {
+	block := FullSignedBlock{
+		Version:      CurrentBlockVersion,
+		Height:       1,
+		Timestamp:    1600000000,
+		AveragePrice: 10000,
+		Ticker:       "BTCUSD",
+		PreviousHash: "dd00",
+		Evidence:     testResults(3),
+	}
+	block.CreateEvidenceRoot()
+	if err := block.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+func testKey(t *testing.T) ed25519.PrivateKey {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return priv
+}
+
+func TestBlockSignature(t *testing.T) {
+	priv, other := testKey(t), testKey(t)
+
+	tests := []struct {
+		name   string
+		change func(block *FullSignedBlock)
+		key    ed25519.PublicKey
+		err    error
+	}{
+		{"signed", func(block *FullSignedBlock) {}, priv.Public().(ed25519.PublicKey), nil},
+		{"another key", func(block *FullSignedBlock) {}, other.Public().(ed25519.PublicKey), ErrInvalidSignature},
+		{"unsigned", func(block *FullSignedBlock) { block.Signature = "" }, priv.Public().(ed25519.PublicKey), ErrUnsignedBlock},
+		{"changed content", func(block *FullSignedBlock) { block.AveragePrice++ }, priv.Public().(ed25519.PublicKey), ErrInvalidSignature},
+		{"changed hash", func(block *FullSignedBlock) { block.Hash = "dd00" + block.Hash[4:] }, priv.Public().(ed25519.PublicKey), ErrInvalidSignature},
+		{"signature not in hex", func(block *FullSignedBlock) { block.Signature = "zz" }, priv.Public().(ed25519.PublicKey), ErrInvalidSignature},
+		{"previous address set after signing", func(block *FullSignedBlock) { block.PreviousAddress = "dm1" }, priv.Public().(ed25519.PublicKey), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			block := testBlock(t)
+			if err := block.Sign(priv); err != nil {
+				t.Fatal(err)
+			}
+			test.change(&block)
+
+			if err := block.VerifySignature(test.key); !errors.Is(err, test.err) {
+				t.Errorf("VerifySignature returned %v, expected %v", err, test.err)
+			}
+		})
+	}
+}
+
+// The signature and the public key are not hashed, so signing keeps the hash
+func TestSignKeepsHash(t *testing.T) {
+	block := testBlock(t)
+	hash := block.Hash
+	if err := block.Sign(testKey(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := block.CreateHash(); err != nil || block.Hash != hash {
+		t.Errorf("the signed block has the hash %s (%v), expected %s", block.Hash, err, hash)
+	}
+}
+
+func TestSignUnhashedBlock(t *testing.T) {
+	block := testBlock(t)
+	block.Hash = ""
+
+	if err := block.Sign(testKey(t)); err == nil {
+		t.Error("a block without hash was signed")
+	}
+}
@@ -0,0 +1,208 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// The rules of Validate. The errors returned by Validate wrap one of them or ErrUnknownVersion, so they can be
+// checked with errors.Is
+var (
+	// ErrInvalidHash is a block whose content doesn´t match their hash
+	ErrInvalidHash = errors.New("the content doesn´t match the hash")
+	// ErrHashFormat is a hash without the prefix of the blocks and the seconds of their timestamp
+	ErrHashFormat = errors.New("the hash has not the format of the blocks")
+	// ErrInvalidTimestamp is a block without timestamp, with a timestamp before the one of their parent, or too far
+	// in the future
+	ErrInvalidTimestamp = errors.New("the timestamp is invalid")
+	// ErrInvalidHeight is a block that is not at the height after their parent
+	ErrInvalidHeight = errors.New("the height doesn´t follow the parent")
+	// ErrBrokenLink is a block whose previous hash is not the hash of their parent
+	ErrBrokenLink = errors.New("the previous hash is not the parent")
+	// ErrInvalidGenesis is a block at the height 0 that breaks the rules of the genesis blocks, or the genesis
+	// block of another chain
+	ErrInvalidGenesis = errors.New("the genesis block is invalid")
+	// ErrBlockTooLarge is a block, or a result of their evidence, over the limits of the chain, see BlockLimits
+	ErrBlockTooLarge = errors.New("the block is over the size limits")
+	// ErrInsufficientWork is a block whose hash doesn´t have the leading zero bits of their difficulty, or with a
+	// difficulty under the minimum of the chain
+	ErrInsufficientWork = errors.New("the proof of work is insufficient")
+	// ErrInvalidEvidence is a result of the evidence whose content doesn´t match their hash or with an invalid
+	// signature, or evidence that doesn´t match the Merkle root
+	ErrInvalidEvidence = errors.New("the evidence is invalid")
+	// ErrInvalidCandle is a block with a candle of another ticker, or whose prices are not within their low and
+	// high prices
+	ErrInvalidCandle = errors.New("the candle is invalid")
+)
+
+// ValidationError is returned by Validate with the rule broken by the block
+type ValidationError struct {
+	Hash   string
+	Height uint64
+	Rule   error // One of the rules of Validate
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("types: the block %s at the height %d is invalid, %v: %s", e.Hash, e.Height, e.Rule, e.Detail)
+}
+
+// Unwrap returns the rule, for errors.Is
+func (e *ValidationError) Unwrap() error {
+	return e.Rule
+}
+
+// Is makes all the validation errors ErrInvalidBlock, whatever their rule
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidBlock
+}
+
+// The prefix, two digits with the seconds of the timestamp, and the double hash
+var blockHashFormat = regexp.MustCompile("^" + BlockHashPrefix + "[0-5][0-9][0-9a-f]{64}$")
+
+// checkHash returns true if the content of the result matches their hash, in the canonical form or in the JSON of
+// the results created before it
+func (result Result) checkHash() (bool, error) {
+	hash := result.Hash
+	result = result.unsigned()
+	result.Hash = ""
+	for _, hasher := range []func(obj interface{}) (string, error){calculateHash, legacyHash} {
+		expected, err := hasher(result)
+		if err != nil {
+			return false, err
+		}
+		if expected == hash {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Verify recomputes the double hash of the result, decompressed, and checks that it is their hash. The error wraps
+// ErrInvalidEvidence if it is not
+func (result Result) Verify() error {
+	result, err := result.Decompressed()
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, ErrInvalidEvidence)
+	}
+
+	valid, err := result.checkHash()
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("types: the result of %s doesn´t match their hash %s: %w", result.CrawlerName, result.Hash,
+			ErrInvalidEvidence)
+	}
+
+	return nil
+}
+
+// VerifyEvidence checks the evidence of the block: every result must match their hash, and their signature if it
+// has one, and all of them the Merkle root. The blocks of the versioned formats with evidence must have a root
+func (block FullSignedBlock) VerifyEvidence() error {
+	invalid := func(format string, args ...interface{}) error {
+		return &ValidationError{Hash: block.Hash, Height: block.Height, Rule: ErrInvalidEvidence, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	for i, result := range block.Evidence {
+		if err := result.Verify(); err != nil {
+			if errors.Is(err, ErrInvalidEvidence) {
+				return invalid("the result %d of %s doesn´t match their hash", i, result.CrawlerName)
+			}
+			return err
+		}
+		if result.Signature != "" {
+			if _, err := result.Signer(); err != nil {
+				return invalid("the result %d of %s has an invalid signature", i, result.CrawlerName)
+			}
+		}
+	}
+	if block.EvidenceRoot != "" && block.EvidenceRoot != MerkleRoot(block.Evidence) {
+		return invalid("the evidence doesn´t match the Merkle root")
+	}
+	if block.Version >= BlockVersionCanonical && block.EvidenceRoot == "" && len(block.Evidence) > 0 {
+		return invalid("the evidence has no Merkle root")
+	}
+
+	return nil
+}
+
+// Validate checks the structure of a block: the version, the size limits, the format of the hash and that it
+// matches the content, the proof of work (but for the genesis block), the timestamp, the hashes of the evidence and
+// their Merkle root, the candle, and the link with the parent, the block at the previous height.
+// The parent is nil for the genesis block, or when it is not known; then the link is not checked, unless the block
+// is at the height 0. The evidence of the pruned blocks was removed, so they can´t be validated
+func (block FullSignedBlock) Validate(parent *FullSignedBlock) error {
+	invalid := func(rule error, format string, args ...interface{}) error {
+		return &ValidationError{Hash: block.Hash, Height: block.Height, Rule: rule, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	if _, err := blockFormat(block.Version); err != nil {
+		return invalid(ErrUnknownVersion, "the version %d", block.Version)
+	}
+
+	if err := block.CheckSize(); err != nil {
+		return err
+	}
+
+	if !blockHashFormat.MatchString(block.Hash) {
+		return invalid(ErrHashFormat, "%q", block.Hash)
+	}
+	seconds := block.Time().Second()
+	if block.Hash[len(BlockHashPrefix):len(BlockHashPrefix)+2] != fmt.Sprintf("%02d", seconds) {
+		return invalid(ErrHashFormat, "the hash doesn´t have the seconds of the timestamp %d", block.Timestamp)
+	}
+
+	valid, err := block.CheckHash()
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return invalid(ErrInvalidHash, "the hash of the content is another one")
+	}
+
+	if block.Height > 0 {
+		if detail := workProblem(block.Hash, block.Difficulty); detail != "" {
+			return invalid(ErrInsufficientWork, "%s", detail)
+		}
+	}
+
+	var parentTimestamp uint64
+	if parent != nil {
+		parentTimestamp = parent.Timestamp
+	}
+	if detail := timestampProblem(block.Timestamp, parentTimestamp, time.Now()); detail != "" {
+		return invalid(ErrInvalidTimestamp, "%s", detail)
+	}
+
+	if err := block.VerifyEvidence(); err != nil {
+		return err
+	}
+
+	if block.Candle != nil {
+		if detail := block.Candle.problem(block.Ticker); detail != "" {
+			return invalid(ErrInvalidCandle, "%s", detail)
+		}
+	}
+
+	switch {
+	case parent != nil && block.Height != parent.Height+1:
+		return invalid(ErrInvalidHeight, "the parent is at the height %d", parent.Height)
+	case parent != nil && block.PreviousHash != parent.Hash:
+		return invalid(ErrBrokenLink, "links to %s, but the parent is %s", block.PreviousHash, parent.Hash)
+	case parent == nil && block.Height == 0 && block.PreviousHash != "":
+		return invalid(ErrBrokenLink, "the genesis block links to %s", block.PreviousHash)
+	}
+
+	if block.Height == 0 {
+		if err := block.validateGenesis(); err != nil {
+			return invalid(ErrInvalidGenesis, "%v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package types
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// A copy of the block with a change
+func changed(block FullSignedBlock, change func(block *FullSignedBlock)) FullSignedBlock {
+	change(&block)
+	return block
+}
+
+// A copy of the result with a change
+func changedResult(result Result, change func(result *Result)) Result {
+	change(&result)
+	return result
+}
+
+// Change a block and hash it again, so only the changed rule is broken
+func rehashed(t *testing.T, block FullSignedBlock, change func(block *FullSignedBlock)) FullSignedBlock {
+	block = changed(block, change)
+	if err := block.CreateHash(); err != nil {
+		t.Fatal(err)
+	}
+
+	return block
+}
+
+func TestValidate(t *testing.T) {
+	parent, err := NewGenesisBlock(GenesisConfig{Chain: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := rehashed(t, testBlock(t), func(block *FullSignedBlock) { block.PreviousHash = parent.Hash })
+
+	tests := []struct {
+		name   string
+		block  FullSignedBlock
+		parent *FullSignedBlock
+		rule   error
+	}{
+		{"valid block", block, &parent, nil},
+		{"valid genesis block", parent, nil, nil},
+		{"unknown parent", block, nil, nil},
+		{"hash without the prefix", changed(block, func(block *FullSignedBlock) { block.Hash = block.Hash[1:] }), &parent, ErrHashFormat},
+		{"hash with other seconds", changed(block, func(block *FullSignedBlock) { block.Timestamp++ }), &parent, ErrHashFormat},
+		{"changed content", changed(block, func(block *FullSignedBlock) { block.AveragePrice++ }), &parent, ErrInvalidHash},
+		{"no timestamp", rehashed(t, block, func(block *FullSignedBlock) { block.Timestamp = 0 }), &parent, ErrInvalidTimestamp},
+		{"timestamp in the future", rehashed(t, block, func(block *FullSignedBlock) {
+			block.Timestamp = uint64(time.Now().Add(2 * MaxFutureTimestamp).Unix())
+		}), &parent, ErrInvalidTimestamp},
+		{"tampered result", rehashed(t, block, func(block *FullSignedBlock) {
+			block.Evidence = append([]Result(nil), block.Evidence...)
+			block.Evidence[1].Data.HighPrice++
+		}), &parent, ErrInvalidEvidence},
+		{"evidence out of the root", rehashed(t, block, func(block *FullSignedBlock) {
+			block.Evidence = block.Evidence[:2]
+		}), &parent, ErrInvalidEvidence},
+		{"unknown version", changed(block, func(block *FullSignedBlock) { block.Version = 99 }), &parent, ErrUnknownVersion},
+		{"versioned evidence without root", rehashed(t, block, func(block *FullSignedBlock) { block.EvidenceRoot = "" }), &parent, ErrInvalidEvidence},
+		{"legacy evidence without root", rehashed(t, block, func(block *FullSignedBlock) {
+			block.Version, block.EvidenceRoot = BlockVersionLegacy, ""
+		}), &parent, nil},
+		{"height after a gap", rehashed(t, block, func(block *FullSignedBlock) { block.Height = 2 }), &parent, ErrInvalidHeight},
+		{"other parent", rehashed(t, block, func(block *FullSignedBlock) { block.PreviousHash = "dd00" }), &parent, ErrBrokenLink},
+		{"linked genesis block", rehashed(t, parent, func(block *FullSignedBlock) { block.PreviousHash = "dd00" }), nil, ErrBrokenLink},
+		{"genesis block with evidence", rehashed(t, parent, func(block *FullSignedBlock) { block.Evidence = testResults(1) }), nil, ErrInvalidEvidence},
+		{"genesis block without parameters", rehashed(t, parent, func(block *FullSignedBlock) { block.Memo = "" }), nil, ErrInvalidGenesis},
+		{"legacy chain starting with a price block", rehashed(t, block, func(block *FullSignedBlock) {
+			block.Version, block.Height, block.PreviousHash = BlockVersionLegacy, 0, ""
+		}), nil, nil},
+	}
+	for _, test := range tests {
+		err := test.block.Validate(test.parent)
+		if !errors.Is(err, test.rule) {
+			t.Errorf("validating the %s returned %v, expected %v", test.name, err, test.rule)
+		}
+		var invalid *ValidationError
+		if test.rule != nil && (!errors.As(err, &invalid) || invalid.Height != test.block.Height) {
+			t.Errorf("validating the %s returned %v, expected a ValidationError", test.name, err)
+		}
+	}
+}
+
+func TestResultVerify(t *testing.T) {
+	result := testResults(1)[0]
+	legacy := result
+	legacy.Hash = ""
+	hash, err := legacyHash(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy.Hash = hash
+
+	tests := []struct {
+		name   string
+		result Result
+		err    error
+	}{
+		{"result", result, nil},
+		{"result hashed over their JSON", legacy, nil},
+		{"tampered data", changedResult(result, func(result *Result) { result.Data.HighPrice++ }), ErrInvalidEvidence},
+		{"other crawler", changedResult(result, func(result *Result) { result.CrawlerName = "other" }), ErrInvalidEvidence},
+		{"no hash", changedResult(result, func(result *Result) { result.Hash = "" }), ErrInvalidEvidence},
+	}
+	for _, test := range tests {
+		if err := test.result.Verify(); !errors.Is(err, test.err) {
+			t.Errorf("verifying the %s returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestVerifyEvidence(t *testing.T) {
+	block := testBlock(t)
+	tests := []struct {
+		name  string
+		block FullSignedBlock
+		err   error
+	}{
+		{"evidence with their root", block, nil},
+		{"no evidence", changed(block, func(block *FullSignedBlock) { block.Evidence, block.EvidenceRoot = nil, "" }), nil},
+		{"legacy evidence without root", changed(block, func(block *FullSignedBlock) {
+			block.Version, block.EvidenceRoot = BlockVersionLegacy, ""
+		}), nil},
+		{"tampered result", changed(block, func(block *FullSignedBlock) {
+			block.Evidence = append([]Result(nil), block.Evidence...)
+			block.Evidence[2].Data.Volume++
+		}), ErrInvalidEvidence},
+		{"result removed", changed(block, func(block *FullSignedBlock) { block.Evidence = block.Evidence[1:] }), ErrInvalidEvidence},
+		{"versioned evidence without root", changed(block, func(block *FullSignedBlock) { block.EvidenceRoot = "" }), ErrInvalidEvidence},
+	}
+	for _, test := range tests {
+		err := test.block.VerifyEvidence()
+		if !errors.Is(err, test.err) {
+			t.Errorf("verifying the evidence of the %s returned %v, expected %v", test.name, err, test.err)
+		}
+		var invalid *ValidationError
+		if test.err != nil && !errors.As(err, &invalid) {
+			t.Errorf("verifying the evidence of the %s returned %v, expected a ValidationError", test.name, err)
+		}
+	}
+}
+
+// All the validation errors are ErrInvalidBlock, and keep their rule
+func TestValidationErrorIs(t *testing.T) {
+	var err error = &ValidationError{Hash: "hash", Height: 1, Rule: ErrBrokenLink}
+	tests := []struct {
+		target error
+		is     bool
+	}{
+		{ErrInvalidBlock, true},
+		{ErrBrokenLink, true},
+		{ErrInvalidHash, false},
+		{ErrNotFound, false},
+	}
+	for _, test := range tests {
+		if is := errors.Is(err, test.target); is != test.is {
+			t.Errorf("errors.Is(%v) returned %t, expected %t", test.target, is, test.is)
+		}
+	}
+}
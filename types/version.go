@@ -0,0 +1,111 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// BlockVersionLegacy is the version of the blocks created before the versions. Their hash can be over their
+	// JSON or over their canonical form, and they can have a Merkle root of the evidence or not
+	BlockVersionLegacy uint32 = 0
+	// BlockVersionCanonical blocks are hashed over their canonical form and always have a Merkle root of the
+	// evidence, if they have evidence
+	BlockVersionCanonical uint32 = 1
+
+	// CurrentBlockVersion is the version of the new blocks
+	CurrentBlockVersion = BlockVersionCanonical
+)
+
+// ErrUnknownVersion is returned for the blocks of a version without a registered format, e.g. created by a newer
+// node
+var ErrUnknownVersion = errors.New("types: unknown block version")
+
+// BlockFormat is how the blocks of a version are hashed, and how they are converted from the previous version and
+// back. The converted blocks keep their hash, so the hash must be checked before converting them
+type BlockFormat struct {
+	// Hashers are the hash functions the blocks of the version can be hashed with, the one of the new blocks first
+	Hashers []func(obj interface{}) (string, error)
+	// Upgrade converts a block of the previous version to this one
+	Upgrade func(block *FullSignedBlock) error
+	// Downgrade converts a block of this version to the previous one, for the consumers that only know it
+	Downgrade func(block *FullSignedBlock) error
+}
+
+// The formats of the known versions
+var blockFormats = map[uint32]BlockFormat{
+	BlockVersionLegacy: {
+		Hashers: []func(obj interface{}) (string, error){calculateHash, legacyHash},
+	},
+	BlockVersionCanonical: {
+		Hashers: []func(obj interface{}) (string, error){calculateHash},
+		Upgrade: func(block *FullSignedBlock) error {
+			if block.EvidenceRoot == "" {
+				block.CreateEvidenceRoot()
+			}
+			return nil
+		},
+		Downgrade: func(block *FullSignedBlock) error {
+			return nil // The legacy blocks can have a root
+		},
+	},
+}
+
+// RegisterBlockFormat adds the format of a new version. The version must follow the last registered one, so the
+// blocks can be converted through all the versions. The formats must be registered before using the blocks, in an
+// init function
+func RegisterBlockFormat(version uint32, format BlockFormat) {
+	if _, exists := blockFormats[version]; exists {
+		panic(fmt.Sprintf("types: the block version %d is already registered", version))
+	}
+	if _, exists := blockFormats[version-1]; !exists || version == 0 {
+		panic(fmt.Sprintf("types: the block version %d doesn´t follow a registered version", version))
+	}
+	if len(format.Hashers) == 0 || format.Upgrade == nil || format.Downgrade == nil {
+		panic(fmt.Sprintf("types: the format of the block version %d is incomplete", version))
+	}
+
+	blockFormats[version] = format
+}
+
+// Get the format of the version of a block
+func blockFormat(version uint32) (BlockFormat, error) {
+	format, exists := blockFormats[version]
+	if !exists {
+		return BlockFormat{}, fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+
+	return format, nil
+}
+
+// ConvertTo returns the block converted to another version, upgrading or downgrading it one version at a time. The
+// conversions can change the content, so the converted block doesn´t match their hash if the versions hash
+// different fields
+func (block FullSignedBlock) ConvertTo(version uint32) (FullSignedBlock, error) {
+	if _, err := blockFormat(version); err != nil {
+		return block, err
+	}
+
+	for block.Version < version {
+		format, err := blockFormat(block.Version + 1)
+		if err != nil {
+			return block, err
+		}
+		if err = format.Upgrade(&block); err != nil {
+			return block, err
+		}
+		block.Version++
+	}
+	for block.Version > version {
+		format, err := blockFormat(block.Version)
+		if err != nil {
+			return block, err
+		}
+		if err = format.Downgrade(&block); err != nil {
+			return block, err
+		}
+		block.Version--
+	}
+
+	return block, nil
+}
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConvertTo(t *testing.T) {
+	legacy := testBlock(t)
+	legacy.Version = BlockVersionLegacy
+	legacy.EvidenceRoot = ""
+
+	tests := []struct {
+		name    string
+		block   FullSignedBlock
+		version uint32
+		root    string
+		err     error
+	}{
+		{"upgrade creates the root", legacy, BlockVersionCanonical, MerkleRoot(legacy.Evidence), nil},
+		{"downgrade keeps the root", testBlock(t), BlockVersionLegacy, MerkleRoot(legacy.Evidence), nil},
+		{"same version", legacy, BlockVersionLegacy, "", nil},
+		{"unknown version", legacy, 99, "", ErrUnknownVersion},
+	}
+	for _, test := range tests {
+		converted, err := test.block.ConvertTo(test.version)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s returned %v, expected %v", test.name, err, test.err)
+			continue
+		}
+		if err == nil && (converted.Version != test.version || converted.EvidenceRoot != test.root) {
+			t.Errorf("%s returned the version %d with the root %q", test.name, converted.Version, converted.EvidenceRoot)
+		}
+	}
+
+}
+
+func TestUnknownVersion(t *testing.T) {
+	block := testBlock(t)
+	block.Version = 99
+	if err := block.CreateHash(); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("CreateHash returned %v, expected ErrUnknownVersion", err)
+	}
+	if _, err := block.CheckHash(); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("CheckHash returned %v, expected ErrUnknownVersion", err)
+	}
+}
+
+func TestRegisterBlockFormat(t *testing.T) {
+	noop := func(block *FullSignedBlock) error { return nil }
+	complete := BlockFormat{Hashers: []func(obj interface{}) (string, error){calculateHash}, Upgrade: noop, Downgrade: noop}
+
+	tests := []struct {
+		name    string
+		version uint32
+		format  BlockFormat
+	}{
+		{"registered version", BlockVersionCanonical, complete},
+		{"gap after the last version", CurrentBlockVersion + 2, complete},
+		{"incomplete format", CurrentBlockVersion + 1, BlockFormat{Upgrade: noop, Downgrade: noop}},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering the %s didn´t panic", test.name)
+				}
+			}()
+			RegisterBlockFormat(test.version, test.format)
+		}()
+	}
+
+	RegisterBlockFormat(CurrentBlockVersion+1, complete)
+	defer delete(blockFormats, CurrentBlockVersion+1)
+	block := testBlock(t)
+	if converted, err := block.ConvertTo(CurrentBlockVersion + 1); err != nil || converted.Version != CurrentBlockVersion+1 {
+		t.Errorf("the conversion to the registered version returned %d (%v)", converted.Version, err)
+	}
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/bits"
+)
+
+// MaxDifficulty is the highest difficulty a block can have, the bits of the double hash
+const MaxDifficulty = 256
+
+// The minimum difficulty of the blocks of the chain. 0 by default, so the proof of work is off
+var minDifficulty uint32
+
+// SetMinDifficulty sets the minimum difficulty of the blocks of all the chain, for the networks open to any node,
+// so producing many blocks has a cost. The blocks under the minimum are invalid, so it must be the same in all the
+// nodes of the chain. 0 turns the proof of work off
+func SetMinDifficulty(difficulty uint32) {
+	minDifficulty = difficulty
+}
+
+// GetMinDifficulty returns the minimum difficulty of the blocks of the chain
+func GetMinDifficulty() uint32 {
+	return minDifficulty
+}
+
+// HasWork returns true if the hash starts with the number of zero bits of the difficulty, after the prefix and the
+// seconds of the timestamp
+func HasWork(hash string, difficulty uint32) bool {
+	if difficulty == 0 {
+		return true
+	}
+	if difficulty > MaxDifficulty || len(hash) < len(BlockHashPrefix)+2 {
+		return false
+	}
+
+	digest, err := hex.DecodeString(hash[len(BlockHashPrefix)+2:])
+	if err != nil {
+		return false
+	}
+
+	var zeros uint32
+	for _, b := range digest {
+		zeros += uint32(bits.LeadingZeros8(b))
+		if b != 0 || zeros >= difficulty {
+			break
+		}
+	}
+
+	return zeros >= difficulty
+}
+
+// Mine sets the difficulty of the block and searches the nonce that gives a hash with that proof of work, creating
+// the hash with it. It must be called instead of CreateHash, once the content is complete, and before signing the
+// block. The expected number of hashes doubles with each bit of difficulty
+func (block *FullSignedBlock) Mine(difficulty uint32) error {
+	if difficulty > MaxDifficulty {
+		return errors.New("types: the difficulty is over the bits of the hash")
+	}
+
+	block.Difficulty = difficulty
+	for block.Nonce = 0; ; block.Nonce++ {
+		if err := block.CreateHash(); err != nil {
+			return err
+		}
+		if HasWork(block.Hash, difficulty) {
+			return nil
+		}
+	}
+}
+
+// Check the proof of work of a block against their difficulty and the minimum of the chain. Returns the detail of
+// the problem, or an empty string
+func workProblem(hash string, difficulty uint32) string {
+	switch {
+	case difficulty < minDifficulty:
+		return "the difficulty is under the minimum of the chain"
+	case !HasWork(hash, difficulty):
+		return "the hash doesn´t have the leading zero bits of the difficulty"
+	}
+
+	return ""
+}
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHasWork(t *testing.T) {
+	// A hash with the prefix, the seconds and the digest in hex
+	hash := func(digest string) string {
+		return BlockHashPrefix + "05" + digest + strings.Repeat("f", 64-len(digest))
+	}
+
+	tests := []struct {
+		name       string
+		hash       string
+		difficulty uint32
+		work       bool
+	}{
+		{"no difficulty", hash(""), 0, true},
+		{"a zero bit", hash("7"), 1, true},
+		{"not enough zero bits", hash("7"), 2, false},
+		{"a zero byte", hash("00"), 8, true},
+		{"a zero byte and a bit", hash("0040"), 9, true},
+		{"a zero byte and not enough bits", hash("0040"), 10, false},
+		{"three zero bytes", hash("000000"), 24, true},
+		{"all zeros", BlockHashPrefix + "05" + strings.Repeat("0", 64), MaxDifficulty, true},
+		{"over the bits of the hash", BlockHashPrefix + "05" + strings.Repeat("0", 64), MaxDifficulty + 1, false},
+		{"digest not in hex", hash("zz"), 1, false},
+		{"too short", BlockHashPrefix, 1, false},
+	}
+	for _, test := range tests {
+		if work := HasWork(test.hash, test.difficulty); work != test.work {
+			t.Errorf("HasWork with %s returned %t, expected %t", test.name, work, test.work)
+		}
+	}
+}
+
+func TestMine(t *testing.T) {
+	for _, difficulty := range []uint32{0, 1, 4, 8, 12} {
+		block := testBlock(t)
+		if err := block.Mine(difficulty); err != nil {
+			t.Fatal(err)
+		}
+
+		if block.Difficulty != difficulty || !HasWork(block.Hash, difficulty) {
+			t.Errorf("the block mined with the difficulty %d has the hash %s", difficulty, block.Hash)
+		}
+		if valid, err := block.CheckHash(); err != nil || !valid {
+			t.Errorf("the block mined with the difficulty %d doesn´t match their hash (%v)", difficulty, err)
+		}
+		if err := block.Header().Validate(nil); err != nil {
+			t.Errorf("the block mined with the difficulty %d is not valid: %v", difficulty, err)
+		}
+	}
+
+	block := testBlock(t)
+	if err := block.Mine(MaxDifficulty + 1); err == nil {
+		t.Error("a block was mined over the bits of the hash")
+	}
+}
+
+func TestMinDifficulty(t *testing.T) {
+	defer SetMinDifficulty(GetMinDifficulty())
+	SetMinDifficulty(8)
+
+	tests := []struct {
+		name       string
+		difficulty uint32
+		err        error
+	}{
+		{"the minimum", 8, nil},
+		{"over the minimum", 10, nil},
+		{"under the minimum", 4, ErrInsufficientWork},
+	}
+	for _, test := range tests {
+		block := testBlock(t)
+		if err := block.Mine(test.difficulty); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := block.Header().Validate(nil); !errors.Is(err, test.err) {
+			t.Errorf("Validate with %s returned %v, expected %v", test.name, err, test.err)
+		}
+	}
+
+	// A hash without the work of their difficulty
+	block := testBlock(t)
+	if err := block.Mine(8); err != nil {
+		t.Fatal(err)
+	}
+	block.Difficulty = 16
+	if err := block.Header().Validate(nil); !errors.Is(err, ErrInsufficientWork) {
+		t.Errorf("Validate of a block without the work of their difficulty returned %v", err)
+	}
+}